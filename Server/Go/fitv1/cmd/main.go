@@ -8,15 +8,17 @@ import (
 	"os/signal"
 	"syscall"
 	"time"
+	_ "time/tzdata" // zone data for user timezones on hosts without it
 
 	"github.com/AyushIIITU/virtualfit/config"
-	"github.com/AyushIIITU/virtualfit/internal/handlers"
+	"github.com/AyushIIITU/virtualfit/internal/analyzer"
+	"github.com/AyushIIITU/virtualfit/internal/auth"
+	"github.com/AyushIIITU/virtualfit/internal/jobs"
+	"github.com/AyushIIITU/virtualfit/internal/mailer"
 	"github.com/AyushIIITU/virtualfit/internal/middleware"
 	"github.com/AyushIIITU/virtualfit/internal/repository"
 	"github.com/AyushIIITU/virtualfit/internal/service"
-
 	// "github.com/AyushIIITU/virtualfit/internal/service"
-	"github.com/gin-gonic/gin"
 )
 
 func main() {
@@ -27,56 +29,113 @@ func main() {
 	}
 
 	// Initialize repository
-	repo := repository.NewMongoDB(cfg.MongoClient, cfg.DatabaseName)
+	var repo repository.Store
+	if cfg.Store == config.StoreMemory {
+		memory := repository.NewMemory()
+		if cfg.WorkoutSeedFile != "" {
+			if err := seedWorkouts(memory, cfg.WorkoutSeedFile); err != nil {
+				log.Fatalf("Failed to seed workouts: %v", err)
+			}
+		}
+		repo = memory
+	} else {
+		mongoRepo := repository.NewMongoDB(cfg.MongoClient, cfg.DatabaseName)
+		// Emails are looked up lower-case, so stored ones must be too
+		normalized, err := mongoRepo.NormalizeEmails(context.Background())
+		if err != nil {
+			log.Fatalf("Failed to normalize emails: %v", err)
+		}
+		if normalized > 0 {
+			log.Printf("Normalized %d stored email addresses", normalized)
+		}
+		if err := mongoRepo.EnsureIndexes(context.Background()); err != nil {
+			log.Fatalf("Failed to create indexes: %v", err)
+		}
+		migrated, orphaned, err := mongoRepo.MigrateExercises(context.Background())
+		if err != nil {
+			log.Fatalf("Failed to migrate exercises: %v", err)
+		}
+		if migrated > 0 {
+			log.Printf("Migrated %d legacy exercises to workout sessions", migrated)
+		}
+		for _, id := range orphaned {
+			log.Printf("Skipped legacy exercise %s: no user owns it", id.Hex())
+		}
+		repo = mongoRepo
+	}
 
-	// Initialize service
-	svc := service.NewService(repo)
+	// Initialize background job queue
+	queue := jobs.NewQueue(repo, jobs.Options{
+		Workers:           cfg.JobWorkers,
+		MaxAttempts:       cfg.JobMaxAttempts,
+		VisibilityTimeout: cfg.JobVisibilityTimeout,
+		BackoffBase:       cfg.JobBackoffBase,
+		BackoffMax:        cfg.JobBackoffMax,
+		PollInterval:      cfg.JobPollInterval,
+	})
+
+	// Initialize food analyzer
+	var foodAnalyzer analyzer.FoodAnalyzer
+	if cfg.AnalyzerMode == config.AnalyzerFake {
+		foodAnalyzer = analyzer.NewFakeAnalyzer()
+	} else {
+		foodAnalyzer = analyzer.NewHTTPAnalyzer(cfg.AnalyzerURL, cfg.AnalyzerTimeout, cfg.AnalyzerToken)
+	}
 
-	// Initialize handler
-	handler := handlers.NewHandler(svc)
+	// Initialize token signing
+	keys, err := auth.LoadKeySet(cfg.JWTKeyDir, cfg.JWTActiveKID)
+	if err != nil {
+		log.Fatalf("Failed to load signing keys: %v", err)
+	}
+	tokens := auth.NewTokenService(keys, cfg.JWTSecret, cfg.JWTIssuer, cfg.AccessTokenTTL)
+
+	// Initialize mail delivery
+	var mail mailer.Mailer
+	switch cfg.Mailer {
+	case config.MailerSMTP:
+		mail = mailer.NewSMTPMailer(cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUsername, cfg.SMTPPassword, cfg.MailFrom, cfg.SMTPTimeout)
+	case config.MailerFile:
+		mail = mailer.NewFileMailer(cfg.MailDir, cfg.MailFrom)
+	default:
+		mail = mailer.NewLogMailer(cfg.MailFrom)
+	}
 
-	// Initialize router
-	router := gin.Default()
-
-	// Add middleware
-	router.Use(middleware.LoggerMiddleware())
-	router.Use(middleware.CORSMiddleware())
-
-	// Public routes
-	public := router.Group("/api/v1")
-	{
-		public.POST("/register", handler.Register)
-		public.POST("/login", handler.Login)
-		// Workout routes
-		public.GET("/workout", handler.ListWorkoutAPI)
-		public.GET("/workout/search", handler.SearchWorkoutAPI)
-		public.GET("/workout/:id/:imageName", handler.GetWorkoutImage)
-		// Food image routes
-		public.GET("/food-images/:filename", handler.ServeFoodImage)
+	// Initialize service
+	svc := service.NewService(repo, queue, foodAnalyzer, tokens, mail, service.Options{
+		RefreshTokenTTL:      cfg.RefreshTokenTTL,
+		EmailVerificationTTL: cfg.EmailVerificationTTL,
+		PasswordResetTTL:     cfg.PasswordResetTTL,
+		PublicURL:            cfg.PublicURL,
+		PasswordResetURL:     cfg.PasswordResetURL,
+		AdminEmails:          cfg.AdminEmails,
+		MFAIssuer:            cfg.MFAIssuer,
+		MFAChallengeTTL:      cfg.MFAChallengeTTL,
+		AccountLockout: service.LockoutPolicy{
+			MaxFailures: cfg.LoginMaxFailures,
+			Lockout:     cfg.LoginLockout,
+		},
+		IPLockout: service.LockoutPolicy{
+			MaxFailures: cfg.LoginIPMaxFailures,
+			Lockout:     cfg.LoginLockout,
+		},
+		LoginFailureWindow: cfg.LoginFailureWindow,
+	})
+
+	// Start workers once every job type has been registered
+	if err := queue.Start(context.Background()); err != nil {
+		log.Fatalf("Failed to start job queue: %v", err)
 	}
 
-	// Protected routes
-	protected := router.Group("/api/v1")
-	protected.Use(middleware.AuthMiddleware(cfg))
-	{
-		// User routes
-		protected.PUT("/profile", handler.UpdateProfile)
-		protected.GET("/diet-plan-data", handler.GetDietPlanData)
-
-		// Exercise routes
-		protected.POST("/exercises", handler.CreateExercise)
-		protected.GET("/exercises/:id", handler.GetExercise)
-		protected.GET("/exercises", handler.ListExercises)
-
-		// Food Intake routes
-		protected.POST("/food-intake", handler.CreateFoodIntake)
-		protected.GET("/food-intake/:id", handler.GetFoodIntakeStatus)
-		protected.GET("/food-intake", handler.ListUserFoodIntake)
-
-		// Chat routes
-		protected.POST("/chat", handler.StoreSocketID)
-		protected.GET("/chat/:id", handler.GetAllSocketIDs)
-		protected.DELETE("/chat/:id", handler.DisconnectSocket)
+	// Rate limit buckets stay in this process unless instances must share them
+	var rateStore middleware.RateLimitStore = repo
+	if cfg.Store == config.StoreMongo && cfg.RateLimitStore == config.StoreMemory {
+		rateStore = repository.NewMemory()
+	}
+
+	// Initialize router
+	router, err := newRouter(cfg, svc, rateStore)
+	if err != nil {
+		log.Fatalf("Invalid TRUSTED_PROXIES: %v", err)
 	}
 
 	// Create server
@@ -107,5 +166,18 @@ func main() {
 		log.Fatal("Server forced to shutdown:", err)
 	}
 
+	// Let in-flight jobs finish; unfinished ones are picked up again on restart
+	queue.Stop()
+
 	log.Println("Server exiting")
 }
+
+// seedWorkouts loads the workout catalog from a JSON file into the in-memory store
+func seedWorkouts(memory *repository.Memory, path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return memory.LoadWorkouts(file)
+}
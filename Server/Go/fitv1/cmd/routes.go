@@ -0,0 +1,165 @@
+package main
+
+import (
+	"github.com/AyushIIITU/virtualfit/config"
+	"github.com/AyushIIITU/virtualfit/internal/handlers"
+	"github.com/AyushIIITU/virtualfit/internal/middleware"
+	"github.com/AyushIIITU/virtualfit/internal/models"
+	"github.com/AyushIIITU/virtualfit/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+// newRouter registers every route of the API on a new router
+func newRouter(cfg *config.Config, svc *service.Service, rateStore middleware.RateLimitStore) (*gin.Engine, error) {
+	// Initialize handler
+	handler := handlers.NewHandler(svc)
+
+	// Initialize router
+	router := gin.Default()
+	// Client IPs feed login lockouts and the audit log, so only trust
+	// X-Forwarded-For from known proxies
+	if err := router.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		return nil, err
+	}
+
+	// Add middleware
+	router.Use(middleware.LoggerMiddleware())
+	router.Use(middleware.CORSMiddleware())
+
+	// Public keys for services that verify our tokens
+	router.GET("/.well-known/jwks.json", handler.GetJWKS)
+
+	rateLimit := func(policy string) gin.HandlerFunc {
+		return middleware.RateLimit(rateStore, policy, cfg.RateLimits[policy])
+	}
+
+	// Public routes
+	public := router.Group("/api/v1")
+	{
+		accounts := public.Group("", rateLimit(config.RateLimitAuth))
+		accounts.POST("/register", handler.Register)
+		accounts.POST("/login", handler.Login)
+		accounts.POST("/auth/refresh", handler.RefreshToken)
+		accounts.POST("/auth/forgot-password", handler.ForgotPassword)
+		accounts.POST("/auth/reset-password", handler.ResetPassword)
+		accounts.GET("/auth/verify-email", handler.VerifyEmail)
+		accounts.GET("/auth/unlock", handler.UnlockAccount)
+		accounts.POST("/auth/mfa/verify", handler.VerifyMFA)
+		accounts.GET("/account/email/confirm", handler.ConfirmEmailChange)
+		// Workout routes
+		workouts := public.Group("", rateLimit(config.RateLimitCatalog))
+		workouts.GET("/workout", handler.ListWorkoutAPI)
+		workouts.GET("/workout/search", handler.SearchWorkoutAPI)
+		workouts.GET("/workout/:id/:imageName", handler.GetWorkoutImage)
+	}
+
+	// Protected routes
+	protected := router.Group("/api/v1")
+	protected.Use(middleware.AuthMiddleware(svc), rateLimit(config.RateLimitAPI))
+	{
+		// Routes open to unverified accounts
+		protected.POST("/auth/logout", handler.Logout)
+		protected.POST("/auth/logout-all", handler.LogoutAll)
+		protected.POST("/auth/verify-email/resend", handler.ResendVerificationEmail)
+		protected.POST("/auth/mfa/enroll", handler.BeginMFAEnrollment)
+		protected.POST("/auth/mfa/enroll/confirm", handler.ConfirmMFAEnrollment)
+		protected.POST("/auth/mfa/disable", handler.DisableMFA)
+		protected.POST("/auth/mfa/recovery-codes", handler.RegenerateRecoveryCodes)
+		protected.POST("/account/password", handler.ChangePassword)
+		// Lets a user correct a mistyped email address
+		protected.POST("/account/email", handler.RequestEmailChange)
+	}
+
+	// Routes restricted for accounts with an unverified email
+	verified := protected.Group("")
+	verified.Use(middleware.RequireVerifiedEmail(cfg.UnverifiedAccess))
+	{
+		// User routes
+		verified.GET("/diet-plan-data", handler.GetDietPlanData)
+		verified.PATCH("/profile", handler.UpdateProfile)
+		// Older clients send the same merge patch with PUT
+		verified.PUT("/profile", handler.UpdateProfile)
+		verified.GET("/targets/recommendation", handler.GetTargetRecommendation)
+
+		// Workout session routes
+		verified.POST("/sessions", handler.CreateWorkoutSession)
+		verified.GET("/sessions", handler.ListWorkoutSessions)
+		verified.GET("/sessions/:id", handler.GetWorkoutSession)
+		verified.PATCH("/sessions/:id", handler.UpdateWorkoutSession)
+		verified.DELETE("/sessions/:id", handler.DeleteWorkoutSession)
+		verified.POST("/sessions/:id/entries", handler.AddSessionEntry)
+		verified.PATCH("/sessions/:id/entries/:entryId", handler.UpdateSessionEntry)
+		verified.DELETE("/sessions/:id/entries/:entryId", handler.DeleteSessionEntry)
+		verified.POST("/sessions/:id/entries/:entryId/sets", handler.AddWorkoutSet)
+		verified.PATCH("/sessions/:id/entries/:entryId/sets/:setId", handler.UpdateWorkoutSet)
+		verified.DELETE("/sessions/:id/entries/:entryId/sets/:setId", handler.DeleteWorkoutSet)
+
+		// Personal record routes
+		verified.GET("/records", handler.GetPersonalRecords)
+		verified.GET("/records/:workoutId/history", handler.GetRecordHistory)
+
+		// Program routes
+		verified.POST("/programs/generate", handler.GenerateProgram)
+		verified.POST("/programs", handler.CreateProgram)
+		verified.GET("/programs", handler.ListPrograms)
+		verified.GET("/programs/next-session", handler.GetNextSession)
+		verified.GET("/programs/:id", handler.GetProgram)
+		verified.PATCH("/programs/:id", handler.UpdateProgram)
+		verified.DELETE("/programs/:id", handler.DeleteProgram)
+
+		// Training analytics routes
+		verified.GET("/analytics/training", handler.GetTrainingAnalytics)
+
+		// Food Intake routes
+		verified.POST("/food-intake", rateLimit(config.RateLimitUpload), handler.CreateFoodIntake)
+		verified.POST("/food-intake/manual", rateLimit(config.RateLimitUpload), handler.CreateManualFoodIntake)
+		verified.GET("/food-intake/:id", handler.GetFoodIntakeStatus)
+		verified.PATCH("/food-intake/:id", handler.UpdateFoodIntake)
+		verified.DELETE("/food-intake/:id", handler.DeleteFoodIntake)
+		verified.GET("/food-intake", handler.ListUserFoodIntake)
+		verified.GET("/food-images/:filename", handler.ServeFoodImage)
+
+		// Nutrition routes
+		verified.GET("/nutrition/summary", handler.GetNutritionSummary)
+
+		// Body measurement routes
+		verified.POST("/measurements", handler.CreateBodyMeasurement)
+		verified.GET("/measurements", handler.ListBodyMeasurements)
+		verified.GET("/measurements/trends", handler.GetMeasurementTrend)
+		verified.GET("/measurements/:id", handler.GetBodyMeasurement)
+		verified.PATCH("/measurements/:id", handler.UpdateBodyMeasurement)
+		verified.DELETE("/measurements/:id", handler.DeleteBodyMeasurement)
+
+		// Chat routes
+		verified.POST("/chat", handler.StoreSocketID)
+		verified.GET("/chat/:id", handler.GetAllSocketIDs)
+		verified.DELETE("/chat/:id", handler.DisconnectSocket)
+	}
+
+	// Workout catalog management
+	catalog := verified.Group("/catalog")
+	catalog.Use(middleware.RequireRole(models.RoleCoach, models.RoleAdmin))
+	{
+		catalog.POST("/workouts", handler.CreateWorkout)
+		catalog.PUT("/workouts/:id", handler.UpdateWorkout)
+		catalog.DELETE("/workouts/:id", handler.DeleteWorkout)
+	}
+
+	// Admin routes
+	admin := verified.Group("/admin")
+	admin.Use(middleware.RequireRole(models.RoleAdmin))
+	{
+		admin.GET("/users", handler.ListUsers)
+		admin.GET("/users/:id", handler.GetUser)
+		admin.POST("/users/:id/disable", handler.DisableUser)
+		admin.POST("/users/:id/enable", handler.EnableUser)
+		admin.POST("/users/:id/force-password-reset", handler.ForcePasswordReset)
+		admin.POST("/users/:id/reset-mfa", handler.ResetMFA)
+		admin.PUT("/users/:id/role", handler.SetUserRole)
+		admin.GET("/jobs", handler.ListJobs)
+		admin.POST("/jobs/:id/retry", handler.RetryJob)
+		admin.GET("/audit", handler.ListAudit)
+	}
+
+	return router, nil
+}
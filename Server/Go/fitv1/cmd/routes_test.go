@@ -0,0 +1,514 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/x509"
+	"encoding/base32"
+	"encoding/binary"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/AyushIIITU/virtualfit/config"
+	"github.com/AyushIIITU/virtualfit/internal/analyzer"
+	"github.com/AyushIIITU/virtualfit/internal/auth"
+	"github.com/AyushIIITU/virtualfit/internal/jobs"
+	"github.com/AyushIIITU/virtualfit/internal/mailer"
+	"github.com/AyushIIITU/virtualfit/internal/models"
+	"github.com/AyushIIITU/virtualfit/internal/repository"
+	"github.com/AyushIIITU/virtualfit/internal/service"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// png is enough of a PNG file for uploads to accept it
+const png = "\x89PNG\r\n\x1a\n0000000000"
+
+// publicRoutes need no token
+var publicRoutes = map[string]bool{
+	"GET /.well-known/jwks.json":         true,
+	"POST /api/v1/register":              true,
+	"POST /api/v1/login":                 true,
+	"POST /api/v1/auth/refresh":          true,
+	"POST /api/v1/auth/forgot-password":  true,
+	"POST /api/v1/auth/reset-password":   true,
+	"GET /api/v1/auth/verify-email":      true,
+	"GET /api/v1/auth/unlock":            true,
+	"POST /api/v1/auth/mfa/verify":       true,
+	"GET /api/v1/account/email/confirm":  true,
+	"GET /api/v1/workout":                true,
+	"GET /api/v1/workout/search":         true,
+	"GET /api/v1/workout/:id/:imageName": true,
+}
+
+// testAPI is the router of a server backed by an in-memory store
+type testAPI struct {
+	t      *testing.T
+	router *gin.Engine
+	store  *repository.Memory
+}
+
+func newTestAPI(t *testing.T) *testAPI {
+	t.Helper()
+	return newTestAPIWith(t, func(m *repository.Memory) repository.Store { return m })
+}
+
+// newTestAPIWith is newTestAPI with the service reading through the store
+// that wrap returns
+func newTestAPIWith(t *testing.T, wrap func(*repository.Memory) repository.Store) *testAPI {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	// Food images are saved under the working directory
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDir := filepath.Join(dir, "keys")
+	if err := os.Mkdir(keyDir, 0o700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(keyDir, "test.pem"), pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	keys, err := auth.LoadKeySet(keyDir, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	store := repository.NewMemory()
+	queue := jobs.NewQueue(store, jobs.Options{})
+	tokens := auth.NewTokenService(keys, "0123456789abcdef0123456789abcdef", "test", 15*time.Minute)
+	svc := service.NewService(wrap(store), queue, analyzer.NewFakeAnalyzer(), tokens, mailer.NewLogMailer("test@example.com"), service.Options{
+		RefreshTokenTTL:      time.Hour,
+		EmailVerificationTTL: time.Hour,
+		PasswordResetTTL:     time.Hour,
+		MFAIssuer:            "test",
+		MFAChallengeTTL:      time.Minute,
+		AccountLockout:       service.LockoutPolicy{MaxFailures: 100, Lockout: time.Minute},
+		IPLockout:            service.LockoutPolicy{MaxFailures: 100, Lockout: time.Minute},
+		LoginFailureWindow:   time.Minute,
+	})
+
+	limits := make(map[string]config.RateLimit)
+	for _, policy := range []string{config.RateLimitAuth, config.RateLimitUpload, config.RateLimitCatalog, config.RateLimitAPI} {
+		limits[policy] = config.RateLimit{Requests: 1000, Period: time.Minute}
+	}
+	router, err := newRouter(&config.Config{UnverifiedAccess: config.UnverifiedFull, RateLimits: limits}, svc, store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testAPI{t: t, router: router, store: store}
+}
+
+// do sends a request with a JSON or multipart body and returns the response
+func (a *testAPI) do(method, path, token, contentType, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if body != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	a.router.ServeHTTP(w, req)
+	return w
+}
+
+// decode sends a JSON request that must succeed and decodes the response
+func (a *testAPI) decode(method, path, token, body string, out any) {
+	a.t.Helper()
+	w := a.do(method, path, token, "application/json", body)
+	if w.Code >= 300 {
+		a.t.Fatalf("%s %s: %d %s", method, path, w.Code, w.Body)
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), out); err != nil {
+		a.t.Fatalf("%s %s: %v", method, path, err)
+	}
+}
+
+// login registers the email if needed and returns an access token
+func (a *testAPI) login(email string) string {
+	a.t.Helper()
+	a.do(http.MethodPost, "/api/v1/register", "", "application/json", `{"name": "Test", "email": "`+email+`", "password": "password1",
+		"dob": "1990-01-01T00:00:00Z", "gender": "Male", "height": 180, "weight": 80, "region": "IN", "goals": ["lose weight"],
+		"daily_calorie_intake": 2000, "daily_protein_intake": 100, "preferred_meal_frequency": 3,
+		"current_fitness_level": "Beginner", "days_per_week": 3}`)
+	var resp struct {
+		Token string `json:"token"`
+	}
+	a.decode(http.MethodPost, "/api/v1/login", "", `{"email": "`+email+`", "password": "password1"}`, &resp)
+	return resp.Token
+}
+
+// upload returns a multipart form holding a food image
+func upload(t *testing.T) (string, string) {
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, err := form.CreateFormFile("image", "meal.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	part.Write([]byte(png))
+	form.Close()
+	return form.FormDataContentType(), body.String()
+}
+
+func TestProtectedRoutes(t *testing.T) {
+	api := newTestAPI(t)
+	workout := &models.Workout{Name: "Barbell Squat", Equipment: "barbell", PrimaryMuscles: []string{"quadriceps"}}
+	api.store.SeedWorkouts(workout)
+	workoutID := workout.ID.Hex()
+
+	// User A owns one of everything; user B tries to reach it
+	tokenA, tokenB := api.login("a@example.com"), api.login("b@example.com")
+	var userA struct {
+		ID string `json:"id"`
+	}
+	api.decode(http.MethodGet, "/api/v1/diet-plan-data", tokenA, "", &userA)
+
+	var session models.WorkoutSession
+	api.decode(http.MethodPost, "/api/v1/sessions", tokenA, `{"name": "Legs", "entries": [{"workout_id": "`+workoutID+`", "sets": [{"reps": 5, "load": 100}]}]}`, &session)
+	sessionPath := "/api/v1/sessions/" + session.ID.Hex()
+	entryPath := sessionPath + "/entries/" + session.Entries[0].ID.Hex()
+	setPath := entryPath + "/sets/" + session.Entries[0].Sets[0].ID.Hex()
+
+	var program models.Program
+	api.decode(http.MethodPost, "/api/v1/programs", tokenA, `{"name": "Legs", "days": [{"name": "Day 1", "exercises": [{"workout_id": "`+workoutID+`", "sets": 3, "reps_min": 5, "reps_max": 8}]}]}`, &program)
+	programPath := "/api/v1/programs/" + program.ID.Hex()
+
+	var measurement models.BodyMeasurement
+	api.decode(http.MethodPost, "/api/v1/measurements", tokenA, `{"weight": 80}`, &measurement)
+	measurementPath := "/api/v1/measurements/" + measurement.ID.Hex()
+
+	var food models.FoodIntake
+	api.decode(http.MethodPost, "/api/v1/food-intake/manual", tokenA, `{"foodName": "Eggs", "calories": 150, "protein": 12, "carbs": 1, "fat": 10,
+		"date": "2024-04-25T08:00:00Z", "mealType": "breakfast", "imageBase64": "iVBORw0KGgowMDAwMDAwMDAw"}`, &food)
+	foodPath := "/api/v1/food-intake/" + food.ID.Hex()
+
+	var chat gin.H
+	api.decode(http.MethodPost, "/api/v1/chat", tokenA, `{"socket_id": "socket-a"}`, &chat)
+
+	// Nothing of A's may show up in B's responses
+	secrets := []string{session.ID.Hex(), program.ID.Hex(), measurement.ID.Hex(), food.ID.Hex(), "socket-a"}
+
+	uploadType, uploadBody := upload(t)
+	tests := []struct {
+		method, path, route string // route is the pattern, when path fills it in
+		body                string
+		other, owner        int // status for user B, then for user A
+	}{
+		// Account routes act on the caller
+		{method: "POST", path: "/api/v1/auth/verify-email/resend", other: 202, owner: 202},
+		{method: "POST", path: "/api/v1/auth/mfa/enroll", other: 200, owner: 200},
+		{method: "POST", path: "/api/v1/auth/mfa/enroll/confirm", body: `{"code": "000000"}`, other: 401, owner: 401},
+		{method: "POST", path: "/api/v1/auth/mfa/disable", body: `{"password": "password1", "code": "000000"}`, other: 409, owner: 409},
+		{method: "POST", path: "/api/v1/auth/mfa/recovery-codes", body: `{"password": "password1", "code": "000000"}`, other: 409, owner: 409},
+		{method: "POST", path: "/api/v1/account/password", body: `{"current_password": "wrong-password", "new_password": "password2"}`, other: 401, owner: 401},
+		{method: "POST", path: "/api/v1/account/email", body: `{"password": "wrong-password", "new_email": "c@example.com"}`, other: 401, owner: 401},
+		{method: "GET", path: "/api/v1/diet-plan-data", other: 200, owner: 200},
+		{method: "PATCH", path: "/api/v1/profile", body: `{"region": "IN"}`, other: 200, owner: 200},
+		{method: "PUT", path: "/api/v1/profile", body: `{"region": "IN"}`, other: 200, owner: 200},
+		{method: "GET", path: "/api/v1/targets/recommendation", other: 200, owner: 200},
+
+		// Workout sessions
+		{method: "POST", path: "/api/v1/sessions", body: `{"name": "Own"}`, other: 201, owner: 201},
+		{method: "GET", path: "/api/v1/sessions", other: 200, owner: 200},
+		{method: "GET", path: sessionPath, route: "/api/v1/sessions/:id", other: 404, owner: 200},
+		{method: "PATCH", path: sessionPath, route: "/api/v1/sessions/:id", body: `{"notes": "heavy"}`, other: 404, owner: 200},
+		{method: "POST", path: sessionPath + "/entries", route: "/api/v1/sessions/:id/entries", body: `{"workout_id": "` + workoutID + `"}`, other: 404, owner: 201},
+		{method: "PATCH", path: entryPath, route: "/api/v1/sessions/:id/entries/:entryId", body: `{"notes": "deep"}`, other: 404, owner: 200},
+		{method: "POST", path: entryPath + "/sets", route: "/api/v1/sessions/:id/entries/:entryId/sets", body: `{"reps": 5, "load": 105}`, other: 404, owner: 201},
+		{method: "PATCH", path: setPath, route: "/api/v1/sessions/:id/entries/:entryId/sets/:setId", body: `{"reps": 6}`, other: 404, owner: 200},
+		{method: "GET", path: "/api/v1/records", other: 200, owner: 200},
+		{method: "GET", path: "/api/v1/records/" + workoutID + "/history", route: "/api/v1/records/:workoutId/history", other: 200, owner: 200},
+		{method: "GET", path: "/api/v1/analytics/training", other: 200, owner: 200},
+
+		// Programs
+		{method: "POST", path: "/api/v1/programs/generate", body: `{}`, other: 201, owner: 201},
+		{method: "POST", path: "/api/v1/programs", body: `{"name": "Own", "days": [{"name": "Day 1"}]}`, other: 201, owner: 201},
+		{method: "GET", path: "/api/v1/programs", other: 200, owner: 200},
+		{method: "GET", path: "/api/v1/programs/next-session", other: 200, owner: 200},
+		{method: "GET", path: programPath, route: "/api/v1/programs/:id", other: 404, owner: 200},
+		{method: "PATCH", path: programPath, route: "/api/v1/programs/:id", body: `{"name": "Squats"}`, other: 404, owner: 200},
+
+		// Food intake
+		{method: "POST", path: "/api/v1/food-intake", body: uploadBody, other: 201, owner: 201},
+		{method: "POST", path: "/api/v1/food-intake/manual", body: `{"foodName": "Toast", "calories": 80, "date": "2024-04-25T08:00:00Z", "mealType": "breakfast"}`, other: 201, owner: 201},
+		{method: "GET", path: foodPath, route: "/api/v1/food-intake/:id", other: 404, owner: 200},
+		{method: "PATCH", path: foodPath, route: "/api/v1/food-intake/:id", body: `{"foodName": "Omelette"}`, other: 404, owner: 200},
+		{method: "GET", path: "/api/v1/food-intake", other: 200, owner: 200},
+		{method: "GET", path: food.ImageUrl, route: "/api/v1/food-images/:filename", other: 404, owner: 200},
+		{method: "GET", path: "/api/v1/nutrition/summary", other: 200, owner: 200},
+
+		// Body measurements
+		{method: "POST", path: "/api/v1/measurements", body: `{"weight": 81}`, other: 201, owner: 201},
+		{method: "GET", path: "/api/v1/measurements", other: 200, owner: 200},
+		{method: "GET", path: "/api/v1/measurements/trends", other: 200, owner: 200},
+		{method: "GET", path: measurementPath, route: "/api/v1/measurements/:id", other: 404, owner: 200},
+		{method: "PATCH", path: measurementPath, route: "/api/v1/measurements/:id", body: `{"waist": 85}`, other: 404, owner: 200},
+
+		// Chats
+		{method: "POST", path: "/api/v1/chat", body: `{"socket_id": "socket-b", "user_id": "` + userA.ID + `"}`, other: 404, owner: 201},
+		{method: "GET", path: "/api/v1/chat/" + userA.ID, route: "/api/v1/chat/:id", other: 404, owner: 200},
+
+		// Deletes come last so the routes above still find A's records
+		{method: "DELETE", path: setPath, route: "/api/v1/sessions/:id/entries/:entryId/sets/:setId", other: 404, owner: 200},
+		{method: "DELETE", path: entryPath, route: "/api/v1/sessions/:id/entries/:entryId", other: 404, owner: 200},
+		{method: "DELETE", path: sessionPath, route: "/api/v1/sessions/:id", other: 404, owner: 200},
+		{method: "DELETE", path: programPath, route: "/api/v1/programs/:id", other: 404, owner: 200},
+		{method: "DELETE", path: foodPath, route: "/api/v1/food-intake/:id", other: 404, owner: 200},
+		{method: "DELETE", path: measurementPath, route: "/api/v1/measurements/:id", other: 404, owner: 200},
+		{method: "DELETE", path: "/api/v1/chat/socket-a", route: "/api/v1/chat/:id", other: 404, owner: 200},
+
+		// Neither user has a role beyond user
+		{method: "POST", path: "/api/v1/catalog/workouts", other: 403, owner: 403},
+		{method: "PUT", path: "/api/v1/catalog/workouts/" + workoutID, route: "/api/v1/catalog/workouts/:id", other: 403, owner: 403},
+		{method: "DELETE", path: "/api/v1/catalog/workouts/" + workoutID, route: "/api/v1/catalog/workouts/:id", other: 403, owner: 403},
+		{method: "GET", path: "/api/v1/admin/users", other: 403, owner: 403},
+		{method: "GET", path: "/api/v1/admin/users/" + userA.ID, route: "/api/v1/admin/users/:id", other: 403, owner: 403},
+		{method: "POST", path: "/api/v1/admin/users/" + userA.ID + "/disable", route: "/api/v1/admin/users/:id/disable", other: 403, owner: 403},
+		{method: "POST", path: "/api/v1/admin/users/" + userA.ID + "/enable", route: "/api/v1/admin/users/:id/enable", other: 403, owner: 403},
+		{method: "POST", path: "/api/v1/admin/users/" + userA.ID + "/force-password-reset", route: "/api/v1/admin/users/:id/force-password-reset", other: 403, owner: 403},
+		{method: "POST", path: "/api/v1/admin/users/" + userA.ID + "/reset-mfa", route: "/api/v1/admin/users/:id/reset-mfa", other: 403, owner: 403},
+		{method: "PUT", path: "/api/v1/admin/users/" + userA.ID + "/role", route: "/api/v1/admin/users/:id/role", body: `{"role": "admin"}`, other: 403, owner: 403},
+		{method: "GET", path: "/api/v1/admin/jobs", other: 403, owner: 403},
+		{method: "POST", path: "/api/v1/admin/jobs/" + userA.ID + "/retry", route: "/api/v1/admin/jobs/:id/retry", other: 403, owner: 403},
+		{method: "GET", path: "/api/v1/admin/audit", other: 403, owner: 403},
+
+		// Signing out goes last as it revokes the tokens
+		{method: "POST", path: "/api/v1/auth/logout", other: 200, owner: 200},
+		{method: "POST", path: "/api/v1/auth/logout-all", other: 200, owner: 200},
+	}
+
+	covered := make(map[string]bool)
+	for _, tt := range tests {
+		route := tt.route
+		if route == "" {
+			route = tt.path
+		}
+		covered[tt.method+" "+route] = true
+
+		t.Run(tt.method+" "+route, func(t *testing.T) {
+			contentType := "application/json"
+			if tt.body == uploadBody {
+				contentType = uploadType
+			}
+			if w := api.do(tt.method, tt.path, "", contentType, tt.body); w.Code != http.StatusUnauthorized {
+				t.Errorf("without a token: %d, want 401", w.Code)
+			}
+
+			// The sign-out routes revoke the token they are called with
+			if strings.HasPrefix(route, "/api/v1/auth/logout") {
+				tokenA, tokenB = api.login("a@example.com"), api.login("b@example.com")
+			}
+
+			w := api.do(tt.method, tt.path, tokenB, contentType, tt.body)
+			if w.Code != tt.other {
+				t.Errorf("as another user: %d %s, want %d", w.Code, w.Body, tt.other)
+			}
+			for _, secret := range secrets {
+				if strings.Contains(w.Body.String(), secret) {
+					t.Errorf("another user sees %s: %s", secret, w.Body)
+				}
+			}
+
+			if w := api.do(tt.method, tt.path, tokenA, contentType, tt.body); w.Code != tt.owner {
+				t.Errorf("as the owner: %d %s, want %d", w.Code, w.Body, tt.owner)
+			}
+		})
+	}
+
+	for _, route := range api.router.Routes() {
+		key := route.Method + " " + route.Path
+		if !covered[key] && !publicRoutes[key] {
+			t.Errorf("%s is not covered", key)
+		}
+	}
+}
+
+func TestFoodImagesAreNotShared(t *testing.T) {
+	api := newTestAPI(t)
+	token := api.login("a@example.com")
+
+	// Both uploads are called meal.png by the client
+	type uploaded struct {
+		FoodID   string `json:"food_id"`
+		ImageURL string `json:"imageUrl"`
+	}
+	var first, second uploaded
+	for _, u := range []*uploaded{&first, &second} {
+		contentType, body := upload(t)
+		w := api.do(http.MethodPost, "/api/v1/food-intake", token, contentType, body)
+		if w.Code != http.StatusCreated {
+			t.Fatalf("upload: %d %s", w.Code, w.Body)
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), u); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if first.ImageURL == second.ImageURL || !strings.HasSuffix(first.ImageURL, ".png") {
+		t.Fatalf("uploads stored as %s and %s", first.ImageURL, second.ImageURL)
+	}
+
+	if w := api.do(http.MethodDelete, "/api/v1/food-intake/"+first.FoodID, token, "", ""); w.Code != http.StatusOK {
+		t.Fatalf("delete: %d %s", w.Code, w.Body)
+	}
+	if w := api.do(http.MethodGet, second.ImageURL, token, "", ""); w.Code != http.StatusOK || w.Body.String() != png {
+		t.Errorf("image of the other entry: %d", w.Code)
+	}
+	if _, err := os.Stat(filepath.Join("uploads/food_images", filepath.Base(first.ImageURL))); !os.IsNotExist(err) {
+		t.Errorf("image of the deleted entry is still there: %v", err)
+	}
+
+	// Uploads are typed by their content, not their name
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, _ := form.CreateFormFile("image", "meal.png")
+	part.Write([]byte("not an image at all"))
+	form.Close()
+	if w := api.do(http.MethodPost, "/api/v1/food-intake", token, form.FormDataContentType(), body.String()); w.Code != http.StatusBadRequest {
+		t.Errorf("upload of text: %d %s", w.Code, w.Body)
+	}
+}
+
+// slowSessionReads holds each session read back a little, so edits of one
+// session overlap between reading and saving it
+type slowSessionReads struct {
+	*repository.Memory
+}
+
+func (s slowSessionReads) GetWorkoutSessionByID(ctx context.Context, id bson.ObjectID) (*models.WorkoutSession, error) {
+	session, err := s.Memory.GetWorkoutSessionByID(ctx, id)
+	time.Sleep(10 * time.Millisecond)
+	return session, err
+}
+
+func TestConcurrentSetsAreKept(t *testing.T) {
+	api := newTestAPIWith(t, func(m *repository.Memory) repository.Store { return slowSessionReads{m} })
+	workout := &models.Workout{Name: "Barbell Squat", PrimaryMuscles: []string{"quadriceps"}}
+	api.store.SeedWorkouts(workout)
+	token := api.login("a@example.com")
+
+	var session models.WorkoutSession
+	api.decode(http.MethodPost, "/api/v1/sessions", token, `{"entries": [{"workout_id": "`+workout.ID.Hex()+`"}]}`, &session)
+	sessionPath := "/api/v1/sessions/" + session.ID.Hex()
+	setsPath := sessionPath + "/entries/" + session.Entries[0].ID.Hex() + "/sets"
+
+	// Every set the API accepts must be in the session; a request that
+	// keeps losing the race may be told to retry instead
+	const requests = 8
+	codes := make(chan int, requests)
+	var wg sync.WaitGroup
+	for i := 0; i < requests; i++ {
+		wg.Add(1)
+		go func(reps int) {
+			defer wg.Done()
+			codes <- api.do(http.MethodPost, setsPath, token, "application/json", fmt.Sprintf(`{"reps": %d, "load": 100}`, reps)).Code
+		}(i + 1)
+	}
+	wg.Wait()
+	close(codes)
+	accepted := 0
+	for code := range codes {
+		switch code {
+		case http.StatusCreated:
+			accepted++
+		case http.StatusConflict:
+		default:
+			t.Errorf("logging a set: %d", code)
+		}
+	}
+
+	api.decode(http.MethodGet, sessionPath, token, "", &session)
+	if got := len(session.Entries[0].Sets); got != accepted || accepted == 0 {
+		t.Errorf("%d sets stored, %d accepted", got, accepted)
+	}
+}
+
+// totpAt computes the code an authenticator app shows for secret, steps
+// time steps from now
+func totpAt(t *testing.T, secret string, now time.Time, steps int64) string {
+	t.Helper()
+	key, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(now.Unix()/30+steps))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+	offset := sum[len(sum)-1] & 0x0f
+	return fmt.Sprintf("%06d", (binary.BigEndian.Uint32(sum[offset:])&0x7fffffff)%1000000)
+}
+
+func TestMFACodesAreUsedOnce(t *testing.T) {
+	api := newTestAPI(t)
+	token := api.login("a@example.com")
+
+	// Keep the test within one time step
+	now := time.Now()
+	if left := 30 - now.Unix()%30; left < 3 {
+		time.Sleep(time.Duration(left) * time.Second)
+		now = time.Now()
+	}
+
+	var enrollment models.MFAEnrollment
+	api.decode(http.MethodPost, "/api/v1/auth/mfa/enroll", token, "", &enrollment)
+	var confirmed map[string]any
+	api.decode(http.MethodPost, "/api/v1/auth/mfa/enroll/confirm", token, `{"code": "`+totpAt(t, enrollment.Secret, now, -1)+`"}`, &confirmed)
+
+	verify := func(code string) int {
+		var challenge models.MFAChallenge
+		api.decode(http.MethodPost, "/api/v1/login", "", `{"email": "a@example.com", "password": "password1"}`, &challenge)
+		if !challenge.MFARequired {
+			t.Fatal("login did not ask for a second factor")
+		}
+		return api.do(http.MethodPost, "/api/v1/auth/mfa/verify", "", "application/json", `{"mfa_token": "`+challenge.MFAToken+`", "code": "`+code+`"}`).Code
+	}
+	tests := []struct {
+		name  string
+		steps int64
+		want  int
+	}{
+		{"current code", 0, http.StatusOK},
+		{"same code again", 0, http.StatusUnauthorized},
+		{"code of the step used to confirm", -1, http.StatusUnauthorized},
+		{"next code, within the skew", 1, http.StatusOK},
+		{"current code after the next one", 0, http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		if got := verify(totpAt(t, enrollment.Secret, now, tt.steps)); got != tt.want {
+			t.Errorf("%s: %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
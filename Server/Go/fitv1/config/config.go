@@ -2,8 +2,12 @@ package config
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/joho/godotenv"
@@ -11,12 +15,127 @@ import (
 	"go.mongodb.org/mongo-driver/v2/mongo/options"
 )
 
+// Storage backends selectable through the STORE environment variable
+const (
+	StoreMongo  = "mongo"
+	StoreMemory = "memory"
+)
+
+// Food analyzers selectable through the ANALYZER_MODE environment variable
+const (
+	AnalyzerHTTP = "http"
+	AnalyzerFake = "fake"
+)
+
+// Access policies for accounts with an unverified email, selected through
+// the UNVERIFIED_ACCESS environment variable
+const (
+	UnverifiedFull     = "full"
+	UnverifiedReadOnly = "read-only"
+	UnverifiedNone     = "none"
+)
+
+// Mail transports selectable through the MAILER environment variable
+const (
+	MailerLog  = "log"
+	MailerFile = "file"
+	MailerSMTP = "smtp"
+)
+
+// Rate limit policies, each applied to a group of routes
+const (
+	RateLimitAuth    = "auth"    // login, registration and other public auth endpoints
+	RateLimitUpload  = "upload"  // food image uploads
+	RateLimitCatalog = "catalog" // public workout catalog reads
+	RateLimitAPI     = "api"     // every authenticated request
+)
+
+// RateLimit allows Requests per Period, in bursts of up to Requests. Zero
+// Requests turns the limit off.
+type RateLimit struct {
+	Requests int
+	Period   time.Duration
+}
+
+// defaultRateLimits apply to policies missing from RATE_LIMITS
+var defaultRateLimits = map[string]RateLimit{
+	RateLimitAuth:    {Requests: 20, Period: time.Minute},
+	RateLimitUpload:  {Requests: 10, Period: time.Minute},
+	RateLimitCatalog: {Requests: 100, Period: time.Minute},
+	RateLimitAPI:     {Requests: 300, Period: time.Minute},
+}
+
 type Config struct {
-	MongoURI      string
-	DatabaseName  string
-	JWTSecret     string
-	ServerPort    string
-	MongoClient   *mongo.Client
+	MongoURI     string
+	DatabaseName string
+	JWTSecret    string
+	ServerPort   string
+	Store        string
+	// WorkoutSeedFile is a JSON array of catalog workouts loaded into the
+	// in-memory store on startup
+	WorkoutSeedFile string
+
+	// JWTKeyDir holds the PEM signing keys, named <kid>.pem
+	JWTKeyDir    string
+	JWTActiveKID string
+	JWTIssuer    string
+
+	// Token lifetimes
+	AccessTokenTTL  time.Duration
+	RefreshTokenTTL time.Duration
+
+	// Two-factor authentication
+	MFAIssuer       string // name shown in authenticator apps
+	MFAChallengeTTL time.Duration
+
+	// Failed login limits
+	LoginMaxFailures   int // per account
+	LoginIPMaxFailures int // per client IP
+	LoginLockout       time.Duration
+	LoginFailureWindow time.Duration
+	// TrustedProxies may set X-Forwarded-For; empty trusts none
+	TrustedProxies []string
+
+	// Request rate limits, by policy name
+	RateLimits map[string]RateLimit
+	// RateLimitStore keeps the buckets: StoreMemory per process, or StoreMongo
+	// shared by every instance
+	RateLimitStore string
+
+	// Account emails
+	PublicURL            string // base URL of this API, used in emailed links
+	PasswordResetURL     string // client page that completes a password reset
+	EmailVerificationTTL time.Duration
+	PasswordResetTTL     time.Duration
+	UnverifiedAccess     string
+	// AdminEmails are promoted to admin once verified, to bootstrap a deployment
+	AdminEmails []string
+
+	// Mail delivery
+	Mailer       string
+	MailFrom     string
+	MailDir      string
+	SMTPHost     string
+	SMTPPort     int
+	SMTPUsername string
+	SMTPPassword string
+	SMTPTimeout  time.Duration
+
+	// Background job worker pool
+	JobWorkers           int
+	JobMaxAttempts       int
+	JobVisibilityTimeout time.Duration
+	JobBackoffBase       time.Duration
+	JobBackoffMax        time.Duration
+	JobPollInterval      time.Duration
+
+	// Food image analysis service
+	AnalyzerMode    string
+	AnalyzerURL     string
+	AnalyzerTimeout time.Duration
+	AnalyzerToken   string
+
+	MongoClient *mongo.Client
 }
 
 func LoadConfig() (*Config, error) {
@@ -26,10 +145,86 @@ func LoadConfig() (*Config, error) {
 	}
 
 	config := &Config{
-		MongoURI:     getEnv("MONGO_URI", "mongodb://localhost:27017"),
-		DatabaseName: getEnv("DB_NAME", "fitv1"),
-		JWTSecret:    getEnv("JWT_SECRET", "your-secret-key"),
-		ServerPort:   getEnv("PORT", "8080"),
+		MongoURI:        getEnv("MONGO_URI", "mongodb://localhost:27017"),
+		DatabaseName:    getEnv("DB_NAME", "fitv1"),
+		JWTSecret:       getEnv("JWT_SECRET", ""),
+		ServerPort:      getEnv("PORT", "8080"),
+		Store:           getEnv("STORE", StoreMongo),
+		WorkoutSeedFile: getEnv("WORKOUT_SEED_FILE", ""),
+
+		JWTKeyDir:    getEnv("JWT_KEY_DIR", "./keys"),
+		JWTActiveKID: getEnv("JWT_ACTIVE_KID", ""),
+		JWTIssuer:    getEnv("JWT_ISSUER", "virtualfit"),
+
+		AccessTokenTTL:  getEnvDuration("ACCESS_TOKEN_TTL", 15*time.Minute),
+		RefreshTokenTTL: getEnvDuration("REFRESH_TOKEN_TTL", 30*24*time.Hour),
+
+		MFAIssuer:       getEnv("MFA_ISSUER", "VirtualFit"),
+		MFAChallengeTTL: getEnvDuration("MFA_CHALLENGE_TTL", 5*time.Minute),
+
+		LoginMaxFailures:   getEnvInt("LOGIN_MAX_FAILURES", 5),
+		LoginIPMaxFailures: getEnvInt("LOGIN_IP_MAX_FAILURES", 50),
+		LoginLockout:       getEnvDuration("LOGIN_LOCKOUT", 15*time.Minute),
+		LoginFailureWindow: getEnvDuration("LOGIN_FAILURE_WINDOW", time.Hour),
+		TrustedProxies:     getEnvList("TRUSTED_PROXIES"),
+
+		RateLimitStore: getEnv("RATE_LIMIT_STORE", StoreMemory),
+
+		PublicURL:            getEnv("PUBLIC_URL", "http://localhost:8080"),
+		PasswordResetURL:     getEnv("PASSWORD_RESET_URL", "http://localhost:3000/reset-password"),
+		EmailVerificationTTL: getEnvDuration("EMAIL_VERIFICATION_TTL", 48*time.Hour),
+		PasswordResetTTL:     getEnvDuration("PASSWORD_RESET_TTL", time.Hour),
+		UnverifiedAccess:     getEnv("UNVERIFIED_ACCESS", UnverifiedReadOnly),
+		AdminEmails:          getEnvList("ADMIN_EMAILS"),
+
+		Mailer:       getEnv("MAILER", MailerLog),
+		MailFrom:     getEnv("MAIL_FROM", "VirtualFit <no-reply@localhost>"),
+		MailDir:      getEnv("MAIL_DIR", "./mail"),
+		SMTPHost:     getEnv("SMTP_HOST", "localhost"),
+		SMTPPort:     getEnvInt("SMTP_PORT", 587),
+		SMTPUsername: getEnv("SMTP_USERNAME", ""),
+		SMTPPassword: getEnv("SMTP_PASSWORD", ""),
+		SMTPTimeout:  getEnvDuration("SMTP_TIMEOUT", 30*time.Second),
+
+		JobWorkers:           getEnvInt("JOB_WORKERS", 4),
+		JobMaxAttempts:       getEnvInt("JOB_MAX_ATTEMPTS", 5),
+		JobVisibilityTimeout: getEnvDuration("JOB_VISIBILITY_TIMEOUT", 2*time.Minute),
+		JobBackoffBase:       getEnvDuration("JOB_BACKOFF_BASE", 5*time.Second),
+		JobBackoffMax:        getEnvDuration("JOB_BACKOFF_MAX", 10*time.Minute),
+		JobPollInterval:      getEnvDuration("JOB_POLL_INTERVAL", 5*time.Second),
+
+		AnalyzerMode:    getEnv("ANALYZER_MODE", AnalyzerHTTP),
+		AnalyzerURL:     getEnv("ANALYZER_URL", "http://localhost:8000/analyze-food"),
+		AnalyzerTimeout: getEnvDuration("ANALYZER_TIMEOUT", 60*time.Second),
+		AnalyzerToken:   getEnv("ANALYZER_TOKEN", ""),
+	}
+
+	if err := validateSecret(config.JWTSecret); err != nil {
+		return nil, err
+	}
+	switch config.UnverifiedAccess {
+	case UnverifiedFull, UnverifiedReadOnly, UnverifiedNone:
+	default:
+		return nil, fmt.Errorf("UNVERIFIED_ACCESS must be %s, %s or %s", UnverifiedFull, UnverifiedReadOnly, UnverifiedNone)
+	}
+	config.RateLimits, err = parseRateLimits(os.Getenv("RATE_LIMITS"))
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case config.RateLimitStore != StoreMemory && config.RateLimitStore != StoreMongo:
+		return nil, fmt.Errorf("RATE_LIMIT_STORE must be %s or %s", StoreMemory, StoreMongo)
+	case config.RateLimitStore == StoreMongo && config.Store == StoreMemory:
+		return nil, errors.New("RATE_LIMIT_STORE=mongo needs STORE=mongo")
+	}
+	if config.LoginLockout <= 0 || config.LoginFailureWindow <= 0 {
+		return nil, errors.New("LOGIN_LOCKOUT and LOGIN_FAILURE_WINDOW must be positive")
+	}
+	config.PublicURL = strings.TrimSuffix(config.PublicURL, "/")
+
+	// The in-memory store needs no database connection
+	if config.Store == StoreMemory {
+		return config, nil
 	}
 
 	// Connect to MongoDB
@@ -52,10 +247,99 @@ func LoadConfig() (*Config, error) {
 	return config, nil
 }
 
+// minSecretLength is the shortest JWT_SECRET accepted at startup
+const minSecretLength = 32
+
+// validateSecret refuses to start with a missing or placeholder secret
+func validateSecret(secret string) error {
+	switch {
+	case secret == "":
+		return errors.New("JWT_SECRET must be set")
+	case strings.HasPrefix(secret, "your-secret-key"):
+		return errors.New("JWT_SECRET still has the example value; generate one with `openssl rand -hex 32`")
+	case len(secret) < minSecretLength:
+		return fmt.Errorf("JWT_SECRET must be at least %d characters", minSecretLength)
+	}
+	return nil
+}
+
+// parseRateLimits reads policies like "upload=10/1m,catalog=100/1m" on top of
+// the defaults
+func parseRateLimits(value string) (map[string]RateLimit, error) {
+	limits := make(map[string]RateLimit, len(defaultRateLimits))
+	for name, limit := range defaultRateLimits {
+		limits[name] = limit
+	}
+
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		name, spec, ok := strings.Cut(entry, "=")
+		if _, known := defaultRateLimits[name]; !ok || !known {
+			return nil, fmt.Errorf("RATE_LIMITS: unknown policy in %q", entry)
+		}
+		requests, period, ok := strings.Cut(spec, "/")
+		if !ok {
+			return nil, fmt.Errorf("RATE_LIMITS: %q must look like %s=10/1m", entry, name)
+		}
+
+		var limit RateLimit
+		var err error
+		if limit.Requests, err = strconv.Atoi(requests); err != nil || limit.Requests < 0 {
+			return nil, fmt.Errorf("RATE_LIMITS: invalid request count in %q", entry)
+		}
+		if limit.Period, err = time.ParseDuration(period); err != nil || limit.Period <= 0 {
+			return nil, fmt.Errorf("RATE_LIMITS: invalid period in %q", entry)
+		}
+		limits[name] = limit
+	}
+	return limits, nil
+}
+
 func getEnv(key, defaultValue string) string {
 	value := os.Getenv(key)
 	if value == "" {
 		return defaultValue
 	}
 	return value
-} 
\ No newline at end of file
+}
+
+// getEnvList parses a comma-separated list into lower-case entries
+func getEnvList(key string) []string {
+	var values []string
+	for _, value := range strings.Split(os.Getenv(key), ",") {
+		if value = strings.ToLower(strings.TrimSpace(value)); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+func getEnvInt(key string, defaultValue int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		log.Printf("Warning: invalid %s %q, using %d", key, value, defaultValue)
+		return defaultValue
+	}
+	return parsed
+}
+
+// getEnvDuration parses values like "30s" or "5m"
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		log.Printf("Warning: invalid %s %q, using %s", key, value, defaultValue)
+		return defaultValue
+	}
+	return parsed
+}
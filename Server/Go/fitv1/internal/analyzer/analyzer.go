@@ -0,0 +1,52 @@
+package analyzer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/AyushIIITU/virtualfit/internal/models"
+)
+
+// FoodAnalyzer identifies the food in an image and estimates its nutrients
+type FoodAnalyzer interface {
+	Analyze(ctx context.Context, imagePath string) (*Result, error)
+}
+
+// Result is the analysis of a single food image
+type Result struct {
+	FoodName    string
+	Ingredients []string
+	Nutrients   []models.Nutrient
+}
+
+// ErrMalformedResponse is wrapped by every MalformedResponseError
+var ErrMalformedResponse = errors.New("malformed analyzer response")
+
+// MalformedResponseError reports a response that does not have the expected shape.
+// Retrying the same image will not fix it.
+type MalformedResponseError struct {
+	Field  string
+	Reason string
+}
+
+func (e *MalformedResponseError) Error() string {
+	return fmt.Sprintf("%s: %s: %s", ErrMalformedResponse, e.Field, e.Reason)
+}
+
+func (e *MalformedResponseError) Unwrap() error { return ErrMalformedResponse }
+
+// StatusError reports a non-200 response from the analyzer
+type StatusError struct {
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("food analysis API returned status %d", e.StatusCode)
+}
+
+// Retryable reports whether the request may succeed if sent again.
+// Client errors mean the image itself was rejected.
+func (e *StatusError) Retryable() bool {
+	return e.StatusCode >= 500 || e.StatusCode == 408 || e.StatusCode == 429
+}
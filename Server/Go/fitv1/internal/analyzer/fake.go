@@ -0,0 +1,53 @@
+package analyzer
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"os"
+
+	"github.com/AyushIIITU/virtualfit/internal/models"
+)
+
+// fakeMeals are the canned results returned by FakeAnalyzer
+var fakeMeals = []struct {
+	name        string
+	ingredients []string
+	nutrition   [7]float64 // in nutritionFields order
+}{
+	{"Dal Tadka", []string{"lentils", "ghee", "cumin", "garlic", "tomato"}, [7]float64{198, 9.8, 7.1, 3.9, 24.6, 6.2, 2.1}},
+	{"Chicken Biryani", []string{"basmati rice", "chicken", "yogurt", "onion", "spices"}, [7]float64{412, 24.3, 14.8, 4.6, 45.2, 2.4, 3.0}},
+	{"Greek Salad", []string{"cucumber", "tomato", "feta", "olives", "olive oil"}, [7]float64{211, 5.6, 17.9, 6.3, 9.4, 2.8, 5.1}},
+	{"Oatmeal with Banana", []string{"oats", "milk", "banana", "honey"}, [7]float64{320, 10.5, 6.2, 2.1, 56.7, 6.9, 21.4}},
+	{"Paneer Tikka", []string{"paneer", "bell pepper", "onion", "yogurt", "spices"}, [7]float64{289, 16.2, 21.4, 11.8, 8.3, 1.9, 4.2}},
+}
+
+// FakeAnalyzer returns canned results without calling any service. The same
+// image bytes always produce the same result, so offline flows are repeatable.
+type FakeAnalyzer struct{}
+
+func NewFakeAnalyzer() *FakeAnalyzer {
+	return &FakeAnalyzer{}
+}
+
+func (a *FakeAnalyzer) Analyze(ctx context.Context, imagePath string) (*Result, error) {
+	data, err := os.ReadFile(imagePath)
+	if err != nil {
+		return nil, fmt.Errorf("opening image file: %w", err)
+	}
+
+	sum := sha256.Sum256(data)
+	meal := fakeMeals[binary.BigEndian.Uint64(sum[:8])%uint64(len(fakeMeals))]
+
+	nutrients := make([]models.Nutrient, len(nutritionFields))
+	for i, field := range nutritionFields {
+		nutrients[i] = models.Nutrient{Name: field.Name, Amount: meal.nutrition[i], Unit: field.Unit}
+	}
+
+	return &Result{
+		FoodName:    meal.name,
+		Ingredients: append([]string(nil), meal.ingredients...),
+		Nutrients:   nutrients,
+	}, nil
+}
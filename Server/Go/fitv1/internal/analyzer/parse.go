@@ -0,0 +1,174 @@
+package analyzer
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/AyushIIITU/virtualfit/internal/models"
+)
+
+// nutritionFields lists the entries of the analyzer's nutrition array in order
+var nutritionFields = []models.Nutrient{
+	{Name: models.NutrientCalories, Unit: "kcal"},
+	{Name: models.NutrientProtein, Unit: "g"},
+	{Name: models.NutrientFat, Unit: "g"},
+	{Name: models.NutrientSaturatedFat, Unit: "g"},
+	{Name: models.NutrientCarbohydrates, Unit: "g"},
+	{Name: models.NutrientFiber, Unit: "g"},
+	{Name: models.NutrientSugar, Unit: "g"},
+}
+
+// response is the JSON returned by the analyze-food endpoint. Ingredients and
+// nutrition are Python list literals, e.g. "['rice', 'salt']" and "[120.5, 3.0]".
+type response struct {
+	Status    string `json:"status"`
+	FoodName  string `json:"food_name"`
+	Nutrition *struct {
+		Name        string `json:"name"`
+		ID          int    `json:"id"`
+		Ingredients string `json:"ingredients"`
+		Nutrition   string `json:"nutrition"`
+	} `json:"nutrition"`
+}
+
+// ParseResponse converts an analyze-food response body into a Result
+func ParseResponse(body []byte) (*Result, error) {
+	var resp response
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, &MalformedResponseError{Field: "body", Reason: err.Error()}
+	}
+	if resp.FoodName == "" {
+		return nil, &MalformedResponseError{Field: "food_name", Reason: "missing"}
+	}
+	if resp.Nutrition == nil {
+		return nil, &MalformedResponseError{Field: "nutrition", Reason: "missing"}
+	}
+
+	ingredients, err := parseStringList(resp.Nutrition.Ingredients)
+	if err != nil {
+		return nil, &MalformedResponseError{Field: "nutrition.ingredients", Reason: err.Error()}
+	}
+
+	values, err := parseNumberList(resp.Nutrition.Nutrition)
+	if err != nil {
+		return nil, &MalformedResponseError{Field: "nutrition.nutrition", Reason: err.Error()}
+	}
+	if len(values) != len(nutritionFields) {
+		return nil, &MalformedResponseError{
+			Field:  "nutrition.nutrition",
+			Reason: fmt.Sprintf("expected %d values, got %d", len(nutritionFields), len(values)),
+		}
+	}
+
+	nutrients := make([]models.Nutrient, len(nutritionFields))
+	for i, field := range nutritionFields {
+		nutrients[i] = models.Nutrient{Name: field.Name, Amount: values[i], Unit: field.Unit}
+	}
+
+	return &Result{
+		FoodName:    resp.FoodName,
+		Ingredients: ingredients,
+		Nutrients:   nutrients,
+	}, nil
+}
+
+// listItem is one element of a Python list literal
+type listItem struct {
+	value  string
+	quoted bool
+}
+
+// parseList parses a flat Python list literal of strings and bare literals
+func parseList(s string) ([]listItem, error) {
+	s = strings.TrimSpace(s)
+	if len(s) < 2 || s[0] != '[' || s[len(s)-1] != ']' {
+		return nil, fmt.Errorf("not a list literal: %q", s)
+	}
+	body := strings.TrimSpace(s[1 : len(s)-1])
+	if body == "" {
+		return nil, nil
+	}
+
+	var items []listItem
+	for i := 0; ; {
+		for i < len(body) && body[i] == ' ' {
+			i++
+		}
+		if i == len(body) {
+			return nil, fmt.Errorf("trailing comma in %q", s)
+		}
+
+		var item listItem
+		if quote := body[i]; quote == '\'' || quote == '"' {
+			var b strings.Builder
+			i++
+			for ; i < len(body) && body[i] != quote; i++ {
+				if body[i] == '\\' && i+1 < len(body) {
+					i++
+				}
+				b.WriteByte(body[i])
+			}
+			if i == len(body) {
+				return nil, fmt.Errorf("unterminated string in %q", s)
+			}
+			i++
+			item = listItem{value: b.String(), quoted: true}
+		} else {
+			start := i
+			for i < len(body) && body[i] != ',' {
+				i++
+			}
+			item = listItem{value: strings.TrimSpace(body[start:i])}
+			if item.value == "" {
+				return nil, fmt.Errorf("empty element in %q", s)
+			}
+		}
+		items = append(items, item)
+
+		for i < len(body) && body[i] == ' ' {
+			i++
+		}
+		if i == len(body) {
+			return items, nil
+		}
+		if body[i] != ',' {
+			return nil, fmt.Errorf("expected ',' at offset %d in %q", i+1, s)
+		}
+		i++
+	}
+}
+
+// parseStringList parses a list literal whose elements are all quoted strings
+func parseStringList(s string) ([]string, error) {
+	items, err := parseList(s)
+	if err != nil {
+		return nil, err
+	}
+	values := make([]string, len(items))
+	for i, item := range items {
+		if !item.quoted {
+			return nil, fmt.Errorf("element %d is not a string: %s", i, item.value)
+		}
+		values[i] = item.value
+	}
+	return values, nil
+}
+
+// parseNumberList parses a list literal of numbers, quoted or not
+func parseNumberList(s string) ([]float64, error) {
+	items, err := parseList(s)
+	if err != nil {
+		return nil, err
+	}
+	values := make([]float64, len(items))
+	for i, item := range items {
+		value, err := strconv.ParseFloat(strings.TrimSpace(item.value), 64)
+		if err != nil {
+			return nil, fmt.Errorf("element %d is not a number: %q", i, item.value)
+		}
+		values[i] = value
+	}
+	return values, nil
+}
@@ -0,0 +1,182 @@
+package analyzer
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AyushIIITU/virtualfit/internal/models"
+)
+
+// validBody is a response in the shape the analysis service returns today
+const validBody = `{
+	"status": "success",
+	"food_name": "Vegetable Biryani",
+	"nutrition": {
+		"name": "Vegetable Biryani",
+		"id": 42,
+		"ingredients": "['rice', 'carrot', \"chef's masala\", 'it\\'s salt']",
+		"nutrition": "[350.5, 8.2, 12.0, 2.5, 52.3, 4.1, '3.6']"
+	}
+}`
+
+func TestParseResponse(t *testing.T) {
+	result, err := ParseResponse([]byte(validBody))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.FoodName != "Vegetable Biryani" {
+		t.Errorf("FoodName = %q", result.FoodName)
+	}
+	if want := []string{"rice", "carrot", "chef's masala", "it's salt"}; !slices.Equal(result.Ingredients, want) {
+		t.Errorf("Ingredients = %q, want %q", result.Ingredients, want)
+	}
+	want := []models.Nutrient{
+		{Name: models.NutrientCalories, Amount: 350.5, Unit: "kcal"},
+		{Name: models.NutrientProtein, Amount: 8.2, Unit: "g"},
+		{Name: models.NutrientFat, Amount: 12, Unit: "g"},
+		{Name: models.NutrientSaturatedFat, Amount: 2.5, Unit: "g"},
+		{Name: models.NutrientCarbohydrates, Amount: 52.3, Unit: "g"},
+		{Name: models.NutrientFiber, Amount: 4.1, Unit: "g"},
+		{Name: models.NutrientSugar, Amount: 3.6, Unit: "g"},
+	}
+	if !slices.Equal(result.Nutrients, want) {
+		t.Errorf("Nutrients = %v, want %v", result.Nutrients, want)
+	}
+}
+
+func TestParseResponseMalformed(t *testing.T) {
+	// body replaces one part of the valid response
+	body := func(old, new string) string { return strings.Replace(validBody, old, new, 1) }
+	tests := []struct {
+		name  string
+		body  string
+		field string
+	}{
+		{"not JSON", `{"food_name":`, "body"},
+		{"no food name", body(`"food_name": "Vegetable Biryani",`, ``), "food_name"},
+		{"no nutrition", `{"status": "success", "food_name": "Rice"}`, "nutrition"},
+		// The array used to be indexed up to [6] and panicked when shorter
+		{"short nutrition", body(`[350.5, 8.2, 12.0, 2.5, 52.3, 4.1, '3.6']`, `[350.5, 8.2, 12.0]`), "nutrition.nutrition"},
+		{"empty nutrition", body(`[350.5, 8.2, 12.0, 2.5, 52.3, 4.1, '3.6']`, `[]`), "nutrition.nutrition"},
+		{"long nutrition", body(`'3.6']`, `'3.6', 1]`), "nutrition.nutrition"},
+		{"nutrition not a list", body(`"[350.5, 8.2, 12.0, 2.5, 52.3, 4.1, '3.6']"`, `"350.5"`), "nutrition.nutrition"},
+		{"nutrition not numbers", body(`52.3`, `'lots'`), "nutrition.nutrition"},
+		{"nutrition trailing comma", body(`'3.6']`, `'3.6',]`), "nutrition.nutrition"},
+		{"ingredients not a list", body(`"['rice', 'carrot', \"chef's masala\", 'it\\'s salt']"`, `"rice, carrot"`), "nutrition.ingredients"},
+		{"ingredients unquoted", body(`'carrot'`, `carrot`), "nutrition.ingredients"},
+		{"ingredients unterminated", body(`'carrot'`, `'carrot`), "nutrition.ingredients"},
+		{"ingredients missing comma", body(`'rice', 'carrot'`, `'rice' 'carrot'`), "nutrition.ingredients"},
+		{"ingredients half open", body(`"['rice', 'carrot', \"chef's masala\", 'it\\'s salt']"`, `"['rice'"`), "nutrition.ingredients"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := ParseResponse([]byte(tt.body))
+			var malformed *MalformedResponseError
+			if !errors.As(err, &malformed) {
+				t.Fatalf("err = %v (result %+v), want a MalformedResponseError", err, result)
+			}
+			if malformed.Field != tt.field {
+				t.Errorf("Field = %q, want %q (%v)", malformed.Field, tt.field, err)
+			}
+			if !errors.Is(err, ErrMalformedResponse) {
+				t.Error("the error should wrap ErrMalformedResponse")
+			}
+		})
+	}
+}
+
+func TestParseList(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []listItem
+	}{
+		{"[]", nil},
+		{" [ ] ", nil},
+		{"['a']", []listItem{{"a", true}}},
+		{`["a, b", 'c']`, []listItem{{"a, b", true}, {"c", true}}},
+		{"[1, 2.5 ,3]", []listItem{{"1", false}, {"2.5", false}, {"3", false}}},
+		{`['it\'s']`, []listItem{{"it's", true}}},
+	}
+	for _, tt := range tests {
+		got, err := parseList(tt.in)
+		if err != nil {
+			t.Errorf("parseList(%q): %v", tt.in, err)
+			continue
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("parseList(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+
+	for _, in := range []string{"", "a", "[", "['a'", "a]", "[,]", "[1,,2]", "['a' 'b']", "{'a'}", "'a'"} {
+		if got, err := parseList(in); err == nil {
+			t.Errorf("parseList(%q) = %v, want an error", in, got)
+		}
+	}
+}
+
+func TestHTTPAnalyzer(t *testing.T) {
+	image := filepath.Join(t.TempDir(), "meal.jpg")
+	if err := os.WriteFile(image, []byte("not really a jpeg"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		status    int
+		body      string
+		retryable bool
+	}{
+		{"ok", http.StatusOK, validBody, false},
+		{"bad image", http.StatusUnprocessableEntity, `{"error": "no food"}`, false},
+		{"rate limited", http.StatusTooManyRequests, ``, true},
+		{"server error", http.StatusBadGateway, ``, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if got := r.Header.Get("Authorization"); got != "Bearer secret" {
+					t.Errorf("Authorization = %q", got)
+				}
+				file, header, err := r.FormFile("file")
+				if err != nil {
+					t.Errorf("no file part: %v", err)
+				} else {
+					file.Close()
+					if header.Filename != "meal.jpg" || header.Header.Get("Content-Type") != "image/jpeg" {
+						t.Errorf("file part %q of type %q", header.Filename, header.Header.Get("Content-Type"))
+					}
+				}
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			result, err := NewHTTPAnalyzer(server.URL, time.Second, "secret").Analyze(context.Background(), image)
+			if tt.status == http.StatusOK {
+				if err != nil || result.FoodName != "Vegetable Biryani" {
+					t.Fatalf("got %+v, %v", result, err)
+				}
+				return
+			}
+			var statusErr *StatusError
+			if !errors.As(err, &statusErr) {
+				t.Fatalf("err = %v, want a StatusError", err)
+			}
+			if statusErr.StatusCode != tt.status {
+				t.Errorf("StatusCode = %d, want %d", statusErr.StatusCode, tt.status)
+			}
+			if statusErr.Retryable() != tt.retryable {
+				t.Errorf("Retryable() = %v, want %v", statusErr.Retryable(), tt.retryable)
+			}
+		})
+	}
+}
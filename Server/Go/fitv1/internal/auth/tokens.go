@@ -0,0 +1,151 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// Token uses distinguish access tokens from other JWTs we issue
+const (
+	TokenUseAccess = "access"
+	TokenUseMFA    = "mfa" // proves the password step of a two-factor login
+)
+
+var ErrInvalidToken = errors.New("invalid token")
+
+// Claims are the claims of every JWT issued by the server
+type Claims struct {
+	UserID    string `json:"user_id"`
+	SessionID string `json:"sid,omitempty"` // refresh token family the token belongs to
+	TokenUse  string `json:"token_use"`
+
+	Role          string `json:"role"`
+	EmailVerified bool   `json:"email_verified"`
+	jwt.RegisteredClaims
+}
+
+// UserObjectID returns the user ID claim as an ObjectID
+func (c *Claims) UserObjectID() (bson.ObjectID, error) {
+	return bson.ObjectIDFromHex(c.UserID)
+}
+
+// SessionObjectID returns the session ID claim as an ObjectID
+func (c *Claims) SessionObjectID() (bson.ObjectID, error) {
+	return bson.ObjectIDFromHex(c.SessionID)
+}
+
+// TokenService signs and verifies access tokens and hashes opaque tokens
+type TokenService struct {
+	keys      *KeySet
+	hashKey   []byte
+	sealKey   []byte
+	issuer    string
+	accessTTL time.Duration
+}
+
+// NewTokenService signs tokens with keys. secret keys the HMAC under which
+// refresh and one-time tokens are stored, so a database leak alone does not
+// let anyone use them, and the key of secrets encrypted with Seal.
+func NewTokenService(keys *KeySet, secret, issuer string, accessTTL time.Duration) *TokenService {
+	return &TokenService{
+		keys:      keys,
+		hashKey:   []byte(secret),
+		sealKey:   deriveKey([]byte(secret), "virtualfit seal key"),
+		issuer:    issuer,
+		accessTTL: accessTTL,
+	}
+}
+
+// JWKS returns the public keys tokens can be verified with
+func (t *TokenService) JWKS() JWKS {
+	return t.keys.JWKS()
+}
+
+// AccessTTL is the lifetime of access tokens
+func (t *TokenService) AccessTTL() time.Duration {
+	return t.accessTTL
+}
+
+// IssueAccessToken signs a short-lived access token. The caller sets the user
+// and session claims; the token ID, issuer and lifetime are filled in here.
+func (t *TokenService) IssueAccessToken(claims *Claims) (string, error) {
+	now := time.Now()
+	claims.TokenUse = TokenUseAccess
+	claims.RegisteredClaims = jwt.RegisteredClaims{
+		ID:        bson.NewObjectID().Hex(),
+		Issuer:    t.issuer,
+		Subject:   claims.UserID,
+		IssuedAt:  jwt.NewNumericDate(now),
+		ExpiresAt: jwt.NewNumericDate(now.Add(t.accessTTL)),
+	}
+	return t.keys.sign(claims)
+}
+
+// IssueMFAToken signs a challenge token for a user who passed the password
+// step but still has to present a second factor
+func (t *TokenService) IssueMFAToken(userID bson.ObjectID, ttl time.Duration) (string, *Claims, error) {
+	now := time.Now()
+	claims := &Claims{
+		UserID:   userID.Hex(),
+		TokenUse: TokenUseMFA,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        bson.NewObjectID().Hex(),
+			Issuer:    t.issuer,
+			Subject:   userID.Hex(),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+		},
+	}
+	token, err := t.keys.sign(claims)
+	if err != nil {
+		return "", nil, err
+	}
+	return token, claims, nil
+}
+
+// ParseAccessToken verifies an access token's signature, expiry and use
+func (t *TokenService) ParseAccessToken(tokenString string) (*Claims, error) {
+	return t.parse(tokenString, TokenUseAccess)
+}
+
+// ParseMFAToken verifies an MFA challenge token
+func (t *TokenService) ParseMFAToken(tokenString string) (*Claims, error) {
+	return t.parse(tokenString, TokenUseMFA)
+}
+
+func (t *TokenService) parse(tokenString, use string) (*Claims, error) {
+	claims := &Claims{}
+	_, err := jwt.ParseWithClaims(tokenString, claims, t.keys.keyFunc,
+		jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Alg(), jwt.SigningMethodEdDSA.Alg()}),
+		jwt.WithIssuer(t.issuer),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil || claims.TokenUse != use || claims.ID == "" {
+		return nil, ErrInvalidToken
+	}
+	return claims, nil
+}
+
+// NewOpaqueToken returns a random URL-safe token for refresh and one-time links
+func NewOpaqueToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// HashToken returns the digest under which an opaque token is stored
+func (t *TokenService) HashToken(token string) string {
+	mac := hmac.New(sha256.New, t.hashKey)
+	mac.Write([]byte(token))
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,61 @@
+package auth
+
+import (
+	"testing"
+	"time"
+)
+
+// rfcKey is the SHA-1 seed of the RFC 6238 Appendix B test vectors
+var rfcKey = []byte("12345678901234567890")
+
+func TestTOTPCode(t *testing.T) {
+	// Appendix B lists 8-digit codes; 6-digit ones are their last six digits
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+	for _, tt := range tests {
+		if got := totpCode(rfcKey, totpStep(time.Unix(tt.unix, 0))); got != tt.want {
+			t.Errorf("T=%d: got %s, want %s", tt.unix, got, tt.want)
+		}
+	}
+}
+
+func TestValidateTOTP(t *testing.T) {
+	secret := totpEncoding.EncodeToString(rfcKey)
+	now := time.Unix(1111111111, 0)
+	step := totpStep(now)
+	code := func(step int64) string { return totpCode(rfcKey, step) }
+
+	tests := []struct {
+		name   string
+		secret string
+		code   string
+		step   int64
+		ok     bool
+	}{
+		{"current step", secret, code(step), step, true},
+		{"one step behind", secret, code(step - 1), step - 1, true},
+		{"one step ahead", secret, code(step + 1), step + 1, true},
+		{"two steps behind", secret, code(step - 2), 0, false},
+		{"two steps ahead", secret, code(step + 2), 0, false},
+		{"spaced", secret, code(step)[:3] + " " + code(step)[3:], step, true},
+		{"lowercase secret", "gezdgnbvgy3tqojqgezdgnbvgy3tqojq", code(step), step, true},
+		{"too short", secret, code(step)[:5], 0, false},
+		{"wrong code", secret, "000000", 0, false},
+		{"bad secret", "not base32!", code(step), 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := ValidateTOTP(tt.secret, tt.code, now)
+		if ok != tt.ok || got != tt.step {
+			t.Errorf("%s: got step %d, %v; want %d, %v", tt.name, got, ok, tt.step, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+// Package goals reads a user's free-text fitness goals, such as "lose weight"
+// or "build muscle", so nutrition targets and training programs agree on what
+// the user is after.
+package goals
+
+import "strings"
+
+// Intent is what a user's goals ask for. Several can hold at once: wanting
+// both fat loss and muscle gain, in one goal or across several, is
+// recomposition.
+type Intent struct {
+	Lose      bool // fat or weight
+	Gain      bool // muscle
+	Strength  bool
+	Endurance bool
+}
+
+// Keywords of each intent. "recomp" is both a loss and a gain.
+var (
+	loseWords      = []string{"recomp", "lose", "loss", "fat", "cut", "lean", "slim"}
+	gainWords      = []string{"recomp", "muscle", "hypertrophy", "gain", "bulk", "mass", "tone"}
+	strengthWords  = []string{"strength", "strong", "powerlift"}
+	enduranceWords = []string{"endurance", "stamina", "run", "marathon", "cardio"}
+)
+
+// Parse reads the intent of free-text goals
+func Parse(goals []string) Intent {
+	var intent Intent
+	for _, goal := range goals {
+		goal = strings.ToLower(goal)
+		intent.Lose = intent.Lose || containsAny(goal, loseWords...)
+		intent.Gain = intent.Gain || containsAny(goal, gainWords...)
+		intent.Strength = intent.Strength || containsAny(goal, strengthWords...)
+		intent.Endurance = intent.Endurance || containsAny(goal, enduranceWords...)
+	}
+	return intent
+}
+
+// Recomposition reports whether the goals ask to lose fat and gain muscle
+func (i Intent) Recomposition() bool {
+	return i.Lose && i.Gain
+}
+
+func containsAny(s string, words ...string) bool {
+	for _, word := range words {
+		if strings.Contains(s, word) {
+			return true
+		}
+	}
+	return false
+}
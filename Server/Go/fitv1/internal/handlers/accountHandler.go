@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/AyushIIITU/virtualfit/internal/auth"
+	"github.com/AyushIIITU/virtualfit/internal/models"
+	"github.com/AyushIIITU/virtualfit/internal/service"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// accountError maps errors of the account change flows to responses
+func accountError(c *gin.Context, err error) {
+	if loginThrottled(c, err) {
+		return
+	}
+	switch {
+	case errors.Is(err, service.ErrInvalidPassword):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrEmailTaken):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrEmailUnchanged), errors.Is(err, service.ErrInvalidOneTimeToken):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+// ChangePassword replaces the caller's password and ends their other sessions
+func (h *Handler) ChangePassword(c *gin.Context) {
+	var req models.PasswordChangeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	claims, exists := c.Get("claims")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	err := h.service.ChangePassword(c.Request.Context(), claims.(*auth.Claims), req.CurrentPassword, req.NewPassword, c.ClientIP())
+	if err != nil {
+		accountError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "password changed; other sessions were signed out"})
+}
+
+// RequestEmailChange sends a confirmation link to the caller's new address
+func (h *Handler) RequestEmailChange(c *gin.Context) {
+	var req models.EmailChangeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	err := h.service.RequestEmailChange(c.Request.Context(), userID.(bson.ObjectID), req.NewEmail, req.Password, c.ClientIP())
+	if err != nil {
+		accountError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"message": "confirmation link sent to the new address"})
+}
+
+// ConfirmEmailChange switches the account's email from the emailed link
+func (h *Handler) ConfirmEmailChange(c *gin.Context) {
+	token := c.Query("token")
+	if token == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "token is required"})
+		return
+	}
+
+	if err := h.service.ConfirmEmailChange(c.Request.Context(), token); err != nil {
+		accountError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "email address changed"})
+}
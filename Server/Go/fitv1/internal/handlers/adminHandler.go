@@ -0,0 +1,304 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/AyushIIITU/virtualfit/internal/jobs"
+	"github.com/AyushIIITU/virtualfit/internal/models"
+	"github.com/AyushIIITU/virtualfit/internal/service"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// maxPageSize bounds the limit of paginated listings
+const maxPageSize = 100
+
+// auditActor identifies the caller of a privileged route for the audit log
+func auditActor(c *gin.Context) (models.AuditActor, bool) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		return models.AuditActor{}, false
+	}
+	return models.AuditActor{UserID: userID.(bson.ObjectID), IP: c.ClientIP()}, true
+}
+
+// pageParams reads limit and offset, defaulting to the first 20 records
+func pageParams(c *gin.Context) (int, int) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit <= 0 {
+		limit = 20
+	}
+	limit = min(limit, maxPageSize)
+
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+	return limit, offset
+}
+
+func pageResponse(data any, total int64, limit, offset int) gin.H {
+	return gin.H{
+		"data": data,
+		"pagination": gin.H{
+			"total":   total,
+			"limit":   limit,
+			"offset":  offset,
+			"hasMore": offset+limit < int(total),
+		},
+	}
+}
+
+// ListUsers searches accounts by name or email (q), role and disabled flag
+func (h *Handler) ListUsers(c *gin.Context) {
+	actor, ok := auditActor(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	limit, offset := pageParams(c)
+	criteria := models.UserSearchCriteria{
+		Query:  c.Query("q"),
+		Role:   c.Query("role"),
+		Limit:  limit,
+		Offset: offset,
+	}
+	if v := c.Query("disabled"); v != "" {
+		disabled, err := strconv.ParseBool(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "disabled must be true or false"})
+			return
+		}
+		criteria.Disabled = &disabled
+	}
+
+	users, total, err := h.service.ListUsers(c.Request.Context(), actor, criteria)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, pageResponse(usersResponse(users), total, limit, offset))
+}
+
+// GetUser returns one account
+func (h *Handler) GetUser(c *gin.Context) {
+	actor, ok := auditActor(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	id, err := bson.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user ID"})
+		return
+	}
+
+	user, err := h.service.GetUser(c.Request.Context(), actor, id)
+	if err != nil {
+		adminError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, userResponse(user))
+}
+
+// DisableUser blocks an account and ends its sessions
+func (h *Handler) DisableUser(c *gin.Context) {
+	h.setUserDisabled(c, true)
+}
+
+// EnableUser re-enables a disabled account
+func (h *Handler) EnableUser(c *gin.Context) {
+	h.setUserDisabled(c, false)
+}
+
+func (h *Handler) setUserDisabled(c *gin.Context, disabled bool) {
+	actor, ok := auditActor(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	id, err := bson.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user ID"})
+		return
+	}
+
+	if err := h.service.SetUserDisabled(c.Request.Context(), actor, id, disabled); err != nil {
+		adminError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "user updated", "disabled": disabled})
+}
+
+// ForcePasswordReset requires the user to set a new password before logging in again
+func (h *Handler) ForcePasswordReset(c *gin.Context) {
+	actor, ok := auditActor(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	id, err := bson.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user ID"})
+		return
+	}
+
+	if err := h.service.ForcePasswordReset(c.Request.Context(), actor, id); err != nil {
+		adminError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "password reset required; reset link sent"})
+}
+
+// ResetMFA turns off two-factor authentication for a user who lost access to it
+func (h *Handler) ResetMFA(c *gin.Context) {
+	actor, ok := auditActor(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	id, err := bson.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user ID"})
+		return
+	}
+
+	if err := h.service.ResetMFA(c.Request.Context(), actor, id); err != nil {
+		adminError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "two-factor authentication reset"})
+}
+
+// SetUserRole changes a user's role
+func (h *Handler) SetUserRole(c *gin.Context) {
+	actor, ok := auditActor(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	id, err := bson.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user ID"})
+		return
+	}
+	var req models.RoleUpdateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.service.SetUserRole(c.Request.Context(), actor, id, req.Role); err != nil {
+		adminError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "role updated", "role": req.Role})
+}
+
+// ListJobs lists background jobs, by default those that failed or are dead
+func (h *Handler) ListJobs(c *gin.Context) {
+	actor, ok := auditActor(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	limit, offset := pageParams(c)
+	query := models.JobQuery{
+		Type:   c.Query("type"),
+		Limit:  limit,
+		Offset: offset,
+	}
+	for _, state := range c.QueryArray("state") {
+		query.States = append(query.States, models.JobState(state))
+	}
+	if len(query.States) == 0 {
+		query.States = []models.JobState{models.JobFailed, models.JobDead}
+	}
+
+	jobList, total, err := h.service.ListJobs(c.Request.Context(), actor, query)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, pageResponse(jobList, total, limit, offset))
+}
+
+// RetryJob requeues a failed or dead job
+func (h *Handler) RetryJob(c *gin.Context) {
+	actor, ok := auditActor(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	id, err := bson.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid job ID"})
+		return
+	}
+
+	job, err := h.service.RetryJob(c.Request.Context(), actor, id)
+	if err != nil {
+		adminError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, job)
+}
+
+// ListAudit returns the audit log, filtered by actor_id, action or target_id
+func (h *Handler) ListAudit(c *gin.Context) {
+	actor, ok := auditActor(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	limit, offset := pageParams(c)
+	query := models.AuditQuery{
+		Action:   c.Query("action"),
+		TargetID: c.Query("target_id"),
+		Limit:    limit,
+		Offset:   offset,
+	}
+	if v := c.Query("actor_id"); v != "" {
+		actorID, err := bson.ObjectIDFromHex(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid actor ID"})
+			return
+		}
+		query.ActorID = actorID
+	}
+
+	entries, total, err := h.service.ListAudit(c.Request.Context(), actor, query)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, pageResponse(entries, total, limit, offset))
+}
+
+// adminError maps service errors of privileged routes to status codes
+func adminError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrSelfAction), errors.Is(err, service.ErrInvalidWorkoutSlug):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, jobs.ErrNotRetryable):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
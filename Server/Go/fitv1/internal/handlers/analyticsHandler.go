@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/AyushIIITU/virtualfit/internal/models"
+	"github.com/AyushIIITU/virtualfit/internal/service"
+	"github.com/AyushIIITU/virtualfit/internal/timeutil"
+	"github.com/AyushIIITU/virtualfit/internal/units"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// GetTrainingAnalytics reports sets and tonnage per muscle group per day or
+// week, the workload ratio and the muscles left untrained
+func (h *Handler) GetTrainingAnalytics(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	prefs, ok := h.preferences(c)
+	if !ok {
+		return
+	}
+	query := models.TrainingQuery{
+		UserID:      userID.(bson.ObjectID),
+		Granularity: c.DefaultQuery("granularity", timeutil.Week),
+		Location:    prefs.Location,
+	}
+	if !timeutil.ValidGranularity(query.Granularity) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": service.ErrInvalidGranularity.Error()})
+		return
+	}
+
+	var err error
+	if query.SecondaryWeight, err = strconv.ParseFloat(c.DefaultQuery("secondary_weight", "0.5"), 64); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": service.ErrInvalidSecondaryWeight.Error()})
+		return
+	}
+	if query.UntrainedDays, err = strconv.Atoi(c.DefaultQuery("untrained_days", "7")); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": service.ErrInvalidUntrainedDays.Error()})
+		return
+	}
+
+	// Default to the last 4 weeks, or the last 7 days
+	to, ok, err := dateParam(c, "to", prefs.Location)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !ok {
+		to = time.Now().In(prefs.Location)
+	}
+	from, ok, err := dateParam(c, "from", prefs.Location)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !ok {
+		from = to.AddDate(0, 0, -27)
+		if query.Granularity == timeutil.Day {
+			from = to.AddDate(0, 0, -6)
+		}
+	}
+	query.From, query.To = from, to
+
+	analytics, err := h.service.GetTrainingAnalytics(c.Request.Context(), query)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidRange),
+			errors.Is(err, service.ErrRangeTooLarge),
+			errors.Is(err, service.ErrInvalidSecondaryWeight),
+			errors.Is(err, service.ErrInvalidUntrainedDays):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+	analytics.Units = prefs.Units
+	units.FromCanonical(analytics, prefs.Units)
+
+	c.JSON(http.StatusOK, analytics)
+}
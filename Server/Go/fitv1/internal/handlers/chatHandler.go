@@ -1,25 +1,38 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 
 	"github.com/AyushIIITU/virtualfit/internal/models"
+	"github.com/AyushIIITU/virtualfit/internal/service"
 	"github.com/gin-gonic/gin"
 	"go.mongodb.org/mongo-driver/v2/bson"
 )
 
 // StoreSocketID handles the storing of a socket ID
 func (h *Handler) StoreSocketID(c *gin.Context) {
+	callerID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
 	var req models.SocketRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	userID, err := bson.ObjectIDFromHex(req.UserID)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
-		return
+	// The user ID is optional and defaults to the caller
+	userID := callerID.(bson.ObjectID)
+	if req.UserID != "" {
+		var err error
+		userID, err = bson.ObjectIDFromHex(req.UserID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+			return
+		}
 	}
 
 	chat := &models.Chat{
@@ -27,7 +40,11 @@ func (h *Handler) StoreSocketID(c *gin.Context) {
 		UserID:   userID,
 	}
 
-	if err := h.service.StoreSocketID(c.Request.Context(), chat); err != nil {
+	if err := h.service.StoreSocketID(c.Request.Context(), callerID.(bson.ObjectID), chat); err != nil {
+		if errors.Is(err, service.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -37,6 +54,12 @@ func (h *Handler) StoreSocketID(c *gin.Context) {
 
 // GetAllSocketIDs retrieves all socket IDs for a user
 func (h *Handler) GetAllSocketIDs(c *gin.Context) {
+	callerID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
 	userIDStr := c.Param("id")
 	if userIDStr == "" {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "User ID is required"})
@@ -49,8 +72,12 @@ func (h *Handler) GetAllSocketIDs(c *gin.Context) {
 		return
 	}
 
-	socketIDs, err := h.service.GetAllSocketIDsByUserID(c.Request.Context(), userID)
+	socketIDs, err := h.service.GetAllSocketIDsByUserID(c.Request.Context(), callerID.(bson.ObjectID), userID)
 	if err != nil {
+		if errors.Is(err, service.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve socket IDs"})
 		return
 	}
@@ -60,13 +87,23 @@ func (h *Handler) GetAllSocketIDs(c *gin.Context) {
 
 // DisconnectSocket removes a socket connection
 func (h *Handler) DisconnectSocket(c *gin.Context) {
-	socketID := c.Param("socketID")
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	socketID := c.Param("id")
 	if socketID == "" {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Socket ID is required"})
 		return
 	}
 
-	if err := h.service.DisconnectSocket(c.Request.Context(), socketID); err != nil {
+	if err := h.service.DisconnectSocket(c.Request.Context(), userID.(bson.ObjectID), socketID); err != nil {
+		if errors.Is(err, service.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
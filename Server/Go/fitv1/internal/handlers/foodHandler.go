@@ -1,7 +1,7 @@
 package handlers
 
 import (
-	"fmt"
+	"errors"
 	"log"
 	"net/http"
 	"os"
@@ -9,6 +9,7 @@ import (
 	"strings"
 
 	"github.com/AyushIIITU/virtualfit/internal/models"
+	"github.com/AyushIIITU/virtualfit/internal/service"
 	"github.com/gin-gonic/gin"
 	"go.mongodb.org/mongo-driver/v2/bson"
 )
@@ -34,18 +35,22 @@ func (h *Handler) CreateFoodIntake(c *gin.Context) {
 		return
 	}
 
+	ext, err := sniffImage(file)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
 	// Create uploads directory if it doesn't exist
-	uploadDir := "uploads/food_images"
-	log.Printf("Creating upload directory: %s", uploadDir)
-	if err := os.MkdirAll(uploadDir, 0755); err != nil {
+	log.Printf("Creating upload directory: %s", foodImageDir)
+	if err := os.MkdirAll(foodImageDir, 0755); err != nil {
 		log.Printf("Error creating upload directory: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create upload directory"})
 		return
 	}
 
 	// Generate unique filename
-	filename := fmt.Sprintf("%s_%s", userID.(bson.ObjectID).Hex(), file.Filename)
-	filepath := filepath.Join(uploadDir, filename)
+	filepath, imageURL := foodImagePath(userID.(bson.ObjectID), ext)
 	log.Printf("Generated file path: %s", filepath)
 
 	// Save the file
@@ -56,14 +61,12 @@ func (h *Handler) CreateFoodIntake(c *gin.Context) {
 		return
 	}
 
-	// Create image URL
-	imageURL := fmt.Sprintf("/api/v1/food-images/%s", filename)
-
 	// Create food intake record
 	log.Printf("Creating food intake record")
 	foodIntake := &models.FoodIntake{
 		UserID:    userID.(bson.ObjectID),
 		ImagePath: filepath,
+		ImageUrl:  imageURL,
 		Status:    false,
 	}
 
@@ -76,7 +79,7 @@ func (h *Handler) CreateFoodIntake(c *gin.Context) {
 		return
 	}
 
-	log.Printf("Successfully created food intake with ID: %s", createdFoodIntake.ID)
+	log.Printf("Successfully created food intake with ID: %s", createdFoodIntake.ID.Hex())
 	c.JSON(http.StatusCreated, gin.H{
 		"message":  "Food image uploaded and processing started",
 		"food_id":  createdFoodIntake.ID,
@@ -85,10 +88,75 @@ func (h *Handler) CreateFoodIntake(c *gin.Context) {
 	})
 }
 
-// ServeFoodImage serves food images
+// CreateManualFoodIntake logs a meal entered by hand, optionally with a base64 image
+func (h *Handler) CreateManualFoodIntake(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var req models.FoodIntakeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	nutrients := req.Nutrients()
+	if !normalizeNutrients(c, nutrients) {
+		return
+	}
+
+	var imagePath, imageURL string
+	if req.ImageBase64 != "" {
+		data, ext, err := decodeBase64Image(req.ImageBase64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if err := os.MkdirAll(foodImageDir, 0755); err != nil {
+			log.Printf("Error creating upload directory: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create upload directory"})
+			return
+		}
+
+		imagePath, imageURL = foodImagePath(userID.(bson.ObjectID), ext)
+		if err := os.WriteFile(imagePath, data, 0644); err != nil {
+			log.Printf("Error saving decoded image: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to save image"})
+			return
+		}
+	}
+
+	foodIntake, err := h.service.CreateManualFoodIntake(c.Request.Context(), userID.(bson.ObjectID), &req, nutrients, imagePath, imageURL)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, foodIntake)
+}
+
+// ServeFoodImage serves the image of one of the caller's food intakes
 func (h *Handler) ServeFoodImage(c *gin.Context) {
-	filename := c.Param("filename")
-	filePath := filepath.Join("uploads/food_images", filename)
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	filename := filepath.Base(c.Param("filename"))
+	filePath := filepath.Join(foodImageDir, filename)
+
+	// Filenames are guessable, so only the owner of an entry gets its image
+	if err := h.service.AuthorizeFoodImage(c.Request.Context(), userID.(bson.ObjectID), filePath); err != nil {
+		if errors.Is(err, service.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "image not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
 
 	// Check if file exists
 	if _, err := os.Stat(filePath); os.IsNotExist(err) {
@@ -115,15 +183,25 @@ func (h *Handler) ServeFoodImage(c *gin.Context) {
 }
 
 func (h *Handler) GetFoodIntake(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
 	id, err := bson.ObjectIDFromHex(c.Param("id"))
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid food intake ID"})
 		return
 	}
 
-	foodIntake, err := h.service.GetFoodIntake(c.Request.Context(), id)
+	foodIntake, err := h.service.GetFoodIntake(c.Request.Context(), userID.(bson.ObjectID), id)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "food intake not found"})
+		if errors.Is(err, service.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "food intake not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
@@ -149,6 +227,12 @@ func (h *Handler) ListUserFoodIntake(c *gin.Context) {
 func (h *Handler) GetFoodIntakeStatus(c *gin.Context) {
 	log.Printf("Getting food intake status")
 
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
 	id, err := bson.ObjectIDFromHex(c.Param("id"))
 	if err != nil {
 		log.Printf("Invalid food intake ID: %s", c.Param("id"))
@@ -157,10 +241,14 @@ func (h *Handler) GetFoodIntakeStatus(c *gin.Context) {
 	}
 
 	log.Printf("Fetching food intake with ID: %s", id.Hex())
-	foodIntake, err := h.service.GetFoodIntake(c.Request.Context(), id)
+	foodIntake, err := h.service.GetFoodIntake(c.Request.Context(), userID.(bson.ObjectID), id)
 	if err != nil {
 		log.Printf("Error fetching food intake: %v", err)
-		c.JSON(http.StatusNotFound, gin.H{"error": "food intake not found"})
+		if errors.Is(err, service.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "food intake not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
@@ -171,10 +259,92 @@ func (h *Handler) GetFoodIntakeStatus(c *gin.Context) {
 		return
 	}
 
+	// If analysis gave up, surface the reason instead of "processing"
+	if foodIntake.AnalysisStatus == models.FoodAnalysisFailed {
+		log.Printf("Food intake analysis failed: %s", foodIntake.AnalysisError)
+		c.JSON(http.StatusOK, gin.H{
+			"id":       foodIntake.ID,
+			"status":   "failed",
+			"error":    foodIntake.AnalysisError,
+			"attempts": foodIntake.AnalysisAttempts,
+		})
+		return
+	}
+
 	// If still processing, return minimal info
 	log.Printf("Food intake still processing")
-	c.JSON(http.StatusOK, gin.H{
-		"id":     foodIntake.ID,
-		"status": "processing",
-	})
+	response := gin.H{
+		"id":       foodIntake.ID,
+		"status":   "processing",
+		"attempts": foodIntake.AnalysisAttempts,
+	}
+	if foodIntake.AnalysisError != "" {
+		response["last_error"] = foodIntake.AnalysisError
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// UpdateFoodIntake corrects the name, meal, portion or nutrients of an entry
+func (h *Handler) UpdateFoodIntake(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	id, err := bson.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid food intake ID"})
+		return
+	}
+
+	var update models.FoodIntakeUpdate
+	if err := c.ShouldBindJSON(&update); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !normalizeNutrients(c, update.Nutrients) {
+		return
+	}
+
+	foodIntake, err := h.service.UpdateFoodIntake(c.Request.Context(), userID.(bson.ObjectID), id, &update)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "food intake not found"})
+		case errors.Is(err, service.ErrFoodIntakeProcessing):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, foodIntake)
+}
+
+// DeleteFoodIntake removes an entry and its image
+func (h *Handler) DeleteFoodIntake(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	id, err := bson.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid food intake ID"})
+		return
+	}
+
+	if err := h.service.DeleteFoodIntake(c.Request.Context(), userID.(bson.ObjectID), id); err != nil {
+		if errors.Is(err, service.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "food intake not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "food intake deleted"})
 }
@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// foodImageDir is where uploaded food images are stored
+const foodImageDir = "uploads/food_images"
+
+// maxFoodImageSize bounds decoded base64 uploads
+const maxFoodImageSize = 10 << 20
+
+// foodImageExtensions maps the image types we accept to file extensions
+var foodImageExtensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+	"image/webp": ".webp",
+}
+
+// foodImagePath returns where a new upload of a user is stored and the URL it
+// is served from. Every upload gets a name of its own, so entries never share
+// an image file whatever the client called it.
+func foodImagePath(userID bson.ObjectID, ext string) (string, string) {
+	name := fmt.Sprintf("%s_%s%s", userID.Hex(), bson.NewObjectID().Hex(), ext)
+	return filepath.Join(foodImageDir, name), fmt.Sprintf("/api/v1/food-images/%s", name)
+}
+
+// sniffImage returns the file extension of an uploaded image by its content
+func sniffImage(file *multipart.FileHeader) (string, error) {
+	f, err := file.Open()
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	head := make([]byte, 512)
+	n, err := io.ReadFull(f, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return "", errors.New("image is empty")
+	}
+	ext, ok := foodImageExtensions[http.DetectContentType(head[:n])]
+	if !ok {
+		return "", errors.New("image must be a JPEG, PNG, GIF or WebP image")
+	}
+	return ext, nil
+}
+
+// decodeBase64Image decodes a raw or data-URL base64 image and returns its
+// bytes and file extension
+func decodeBase64Image(encoded string) ([]byte, string, error) {
+	// Accept data URLs such as "data:image/png;base64,iVBOR..."
+	if strings.HasPrefix(encoded, "data:") {
+		comma := strings.IndexByte(encoded, ',')
+		if comma < 0 || !strings.HasSuffix(encoded[:comma], ";base64") {
+			return nil, "", errors.New("imageBase64 must be base64 encoded")
+		}
+		encoded = encoded[comma+1:]
+	}
+
+	if base64.StdEncoding.DecodedLen(len(encoded)) > maxFoodImageSize {
+		return nil, "", errors.New("image is too large")
+	}
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, "", errors.New("imageBase64 is not valid base64")
+	}
+
+	ext, ok := foodImageExtensions[http.DetectContentType(data)]
+	if !ok {
+		return nil, "", errors.New("imageBase64 must be a JPEG, PNG, GIF or WebP image")
+	}
+	return data, ext, nil
+}
@@ -8,6 +8,7 @@ import (
 
 	// "github.com/AyushIIITU/virtualfit/internal/service"
 	"github.com/AyushIIITU/virtualfit/internal/service"
+	"github.com/AyushIIITU/virtualfit/internal/units"
 	"github.com/gin-gonic/gin"
 
 	// "github.com/golang-jwt/jwt"
@@ -37,5 +38,6 @@ func (h *Handler) GetDietPlanData(c *gin.Context) {
 		return
 	}
 
+	units.FromCanonical(dietPlanData, dietPlanData.Units)
 	c.JSON(http.StatusOK, dietPlanData)
 }
@@ -0,0 +1,244 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/AyushIIITU/virtualfit/internal/models"
+	"github.com/AyushIIITU/virtualfit/internal/service"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// measurementError maps body measurement errors to responses
+func measurementError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "measurement not found"})
+	case errors.Is(err, service.ErrInvalidMeasurement),
+		errors.Is(err, service.ErrEmptyMeasurement),
+		errors.Is(err, service.ErrFutureMeasurement),
+		errors.Is(err, service.ErrUnknownMetric),
+		errors.Is(err, service.ErrInvalidWindow),
+		errors.Is(err, service.ErrInvalidRange),
+		errors.Is(err, service.ErrRangeTooLarge),
+		errors.Is(err, service.ErrInvalidProfile):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+// CreateBodyMeasurement logs weight, body fat or girth measurements
+func (h *Handler) CreateBodyMeasurement(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var req models.BodyMeasurementUpdate
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	system, ok := h.unitSystem(c, req.Units)
+	if !ok || !toCanonical(c, &req, system) {
+		return
+	}
+
+	measurement, err := h.service.CreateBodyMeasurement(c.Request.Context(), userID.(bson.ObjectID), &req)
+	if err != nil {
+		measurementError(c, err)
+		return
+	}
+
+	measurementsResponse(system, measurement)
+	c.JSON(http.StatusCreated, measurement)
+}
+
+// ListBodyMeasurements pages through the caller's entries, newest first,
+// optionally only those between two dates or with a given metric
+func (h *Handler) ListBodyMeasurements(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	prefs, ok := h.preferences(c)
+	if !ok {
+		return
+	}
+
+	query := models.MeasurementQuery{UserID: userID.(bson.ObjectID), Metric: c.Query("metric")}
+	from, ok, err := dateParam(c, "from", prefs.Location)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if ok {
+		query.From = from
+	}
+	to, ok, err := dateParam(c, "to", prefs.Location)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if ok {
+		query.To = to.AddDate(0, 0, 1)
+	}
+	query.Limit, query.Offset = pageParams(c)
+
+	measurements, total, err := h.service.ListBodyMeasurements(c.Request.Context(), query)
+	if err != nil {
+		measurementError(c, err)
+		return
+	}
+	measurementsResponse(prefs.Units, measurements...)
+
+	c.JSON(http.StatusOK, pageResponse(measurements, total, query.Limit, query.Offset))
+}
+
+func (h *Handler) GetBodyMeasurement(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	id, err := bson.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid measurement ID"})
+		return
+	}
+
+	system, ok := h.unitSystem(c, "")
+	if !ok {
+		return
+	}
+
+	measurement, err := h.service.GetBodyMeasurement(c.Request.Context(), userID.(bson.ObjectID), id)
+	if err != nil {
+		measurementError(c, err)
+		return
+	}
+	measurementsResponse(system, measurement)
+
+	c.JSON(http.StatusOK, measurement)
+}
+
+// UpdateBodyMeasurement corrects the time or values of an entry
+func (h *Handler) UpdateBodyMeasurement(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	id, err := bson.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid measurement ID"})
+		return
+	}
+
+	var update models.BodyMeasurementUpdate
+	if err := c.ShouldBindJSON(&update); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	system, ok := h.unitSystem(c, update.Units)
+	if !ok || !toCanonical(c, &update, system) {
+		return
+	}
+
+	measurement, err := h.service.UpdateBodyMeasurement(c.Request.Context(), userID.(bson.ObjectID), id, &update)
+	if err != nil {
+		measurementError(c, err)
+		return
+	}
+	measurementsResponse(system, measurement)
+
+	c.JSON(http.StatusOK, measurement)
+}
+
+func (h *Handler) DeleteBodyMeasurement(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	id, err := bson.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid measurement ID"})
+		return
+	}
+
+	if err := h.service.DeleteBodyMeasurement(c.Request.Context(), userID.(bson.ObjectID), id); err != nil {
+		measurementError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "measurement deleted"})
+}
+
+// GetMeasurementTrend reports the moving average, weekly rate and projected
+// goal date of one metric, by default weight over the last 90 days
+func (h *Handler) GetMeasurementTrend(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	window, err := strconv.Atoi(c.DefaultQuery("window", "7"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": service.ErrInvalidWindow.Error()})
+		return
+	}
+
+	var goal *float64
+	if v := c.Query("goal"); v != "" {
+		g, err := strconv.ParseFloat(v, 64)
+		if err != nil || g <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "goal must be a positive number"})
+			return
+		}
+		goal = &g
+	}
+
+	prefs, ok := h.preferences(c)
+	if !ok {
+		return
+	}
+	to, ok, err := dateParam(c, "to", prefs.Location)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !ok {
+		to = time.Now().In(prefs.Location)
+	}
+	from, ok, err := dateParam(c, "from", prefs.Location)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !ok {
+		from = to.AddDate(0, 0, -89)
+	}
+
+	metric := c.DefaultQuery("metric", models.MetricWeight)
+	goalToCanonical(goal, metric, prefs.Units)
+
+	trend, err := h.service.GetMeasurementTrend(c.Request.Context(), userID.(bson.ObjectID), metric, from, to, prefs.Location, window, goal)
+	if err != nil {
+		measurementError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, trendResponse(trend, prefs.Units))
+}
@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/AyushIIITU/virtualfit/internal/service"
+	"github.com/AyushIIITU/virtualfit/internal/timeutil"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// GetNutritionSummary reports intake per day or week against the user's targets
+func (h *Handler) GetNutritionSummary(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	// Days are cut in the user's timezone unless the request names another
+	prefs, ok := h.preferences(c)
+	if !ok {
+		return
+	}
+	loc := prefs.Location
+	if tz := c.Query("tz"); tz != "" {
+		var err error
+		if loc, err = time.LoadLocation(tz); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid timezone"})
+			return
+		}
+	}
+
+	granularity := c.DefaultQuery("granularity", timeutil.Day)
+	if !timeutil.ValidGranularity(granularity) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": service.ErrInvalidGranularity.Error()})
+		return
+	}
+
+	// Default to the last 7 days, or the last 4 weeks
+	to := time.Now().In(loc)
+	if v := c.Query("to"); v != "" {
+		var err error
+		if to, err = time.ParseInLocation(timeutil.DateLayout, v, loc); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "to must be a date like 2006-01-02"})
+			return
+		}
+	}
+	from := to.AddDate(0, 0, -6)
+	if granularity == timeutil.Week {
+		from = to.AddDate(0, 0, -27)
+	}
+	if v := c.Query("from"); v != "" {
+		var err error
+		if from, err = time.ParseInLocation(timeutil.DateLayout, v, loc); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "from must be a date like 2006-01-02"})
+			return
+		}
+	}
+
+	summary, err := h.service.GetNutritionSummary(c.Request.Context(), userID.(bson.ObjectID), from, to, granularity, loc)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidRange) || errors.Is(err, service.ErrRangeTooLarge) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, summary)
+}
@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/AyushIIITU/virtualfit/internal/models"
+	"github.com/AyushIIITU/virtualfit/internal/timeutil"
+	"github.com/AyushIIITU/virtualfit/internal/units"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// preferences returns the caller's units, timezone and week start, loading
+// them once per request
+func (h *Handler) preferences(c *gin.Context) (models.Preferences, bool) {
+	if prefs, exists := c.Get("preferences"); exists {
+		return prefs.(models.Preferences), true
+	}
+
+	userID, exists := c.Get("userID")
+	if !exists {
+		return models.Preferences{Units: units.Metric, Location: time.UTC, WeekStart: time.Monday}, true
+	}
+	prefs, err := h.service.Preferences(c.Request.Context(), userID.(bson.ObjectID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return models.Preferences{}, false
+	}
+	c.Set("preferences", prefs)
+	return prefs, true
+}
+
+// dateParam parses an optional date-only query parameter as local midnight in loc
+func dateParam(c *gin.Context, name string, loc *time.Location) (time.Time, bool, error) {
+	v := c.Query(name)
+	if v == "" {
+		return time.Time{}, false, nil
+	}
+	t, err := time.ParseInLocation(timeutil.DateLayout, v, loc)
+	if err != nil {
+		return time.Time{}, false, errors.New(name + " must be a date like 2006-01-02")
+	}
+	return t, true, nil
+}
@@ -0,0 +1,246 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/AyushIIITU/virtualfit/internal/models"
+	"github.com/AyushIIITU/virtualfit/internal/service"
+	"github.com/AyushIIITU/virtualfit/internal/units"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// programError maps training program errors to responses
+func programError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "program not found"})
+	case errors.Is(err, service.ErrInvalidProgram),
+		errors.Is(err, service.ErrProgramExercise),
+		errors.Is(err, service.ErrUnknownWorkout),
+		errors.Is(err, service.ErrUnknownProgramDay):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrNoProgram):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+// programID parses the program ID in the path
+func programID(c *gin.Context) (bson.ObjectID, bool) {
+	id, err := bson.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid program ID"})
+		return id, false
+	}
+	return id, true
+}
+
+// GenerateProgram builds and saves a program from the caller's profile
+func (h *Handler) GenerateProgram(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	// Every field is optional, so an empty body is fine
+	var req models.ProgramGenerateRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	system, ok := h.unitSystem(c, "")
+	if !ok {
+		return
+	}
+
+	program, err := h.service.GenerateProgram(c.Request.Context(), userID.(bson.ObjectID), &req)
+	if err != nil {
+		programError(c, err)
+		return
+	}
+	programsResponse(system, program)
+
+	c.JSON(http.StatusCreated, program)
+}
+
+// CreateProgram saves a program written by hand
+func (h *Handler) CreateProgram(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var input models.ProgramInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	system, ok := h.unitSystem(c, input.Units)
+	if !ok || !toCanonical(c, &input, system) {
+		return
+	}
+
+	program, err := h.service.CreateProgram(c.Request.Context(), userID.(bson.ObjectID), &input)
+	if err != nil {
+		programError(c, err)
+		return
+	}
+	programsResponse(system, program)
+
+	c.JSON(http.StatusCreated, program)
+}
+
+// ListPrograms pages through the caller's programs, newest first
+func (h *Handler) ListPrograms(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	system, ok := h.unitSystem(c, "")
+	if !ok {
+		return
+	}
+	query := models.ProgramQuery{UserID: userID.(bson.ObjectID)}
+	query.Limit, query.Offset = pageParams(c)
+
+	programs, total, err := h.service.ListPrograms(c.Request.Context(), query)
+	if err != nil {
+		programError(c, err)
+		return
+	}
+	programsResponse(system, programs...)
+
+	c.JSON(http.StatusOK, pageResponse(programs, total, query.Limit, query.Offset))
+}
+
+func (h *Handler) GetProgram(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	id, ok := programID(c)
+	if !ok {
+		return
+	}
+
+	system, ok := h.unitSystem(c, "")
+	if !ok {
+		return
+	}
+
+	program, err := h.service.GetProgram(c.Request.Context(), userID.(bson.ObjectID), id)
+	if err != nil {
+		programError(c, err)
+		return
+	}
+	programsResponse(system, program)
+
+	c.JSON(http.StatusOK, program)
+}
+
+// UpdateProgram changes a program's name, goal, length, days or progression
+func (h *Handler) UpdateProgram(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	id, ok := programID(c)
+	if !ok {
+		return
+	}
+	var update models.ProgramUpdate
+	if err := c.ShouldBindJSON(&update); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	system, ok := h.unitSystem(c, update.Units)
+	if !ok || !toCanonical(c, &update, system) {
+		return
+	}
+
+	program, err := h.service.UpdateProgram(c.Request.Context(), userID.(bson.ObjectID), id, &update)
+	if err != nil {
+		programError(c, err)
+		return
+	}
+	programsResponse(system, program)
+
+	c.JSON(http.StatusOK, program)
+}
+
+func (h *Handler) DeleteProgram(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	id, ok := programID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteProgram(c.Request.Context(), userID.(bson.ObjectID), id); err != nil {
+		programError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "program deleted"})
+}
+
+// GetNextSession recommends the load and reps of the next session of a
+// program day, by default the caller's newest program and the day after the
+// one last trained
+func (h *Handler) GetNextSession(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	query := models.NextSessionQuery{UserID: userID.(bson.ObjectID)}
+	if v := c.Query("program_id"); v != "" {
+		id, err := bson.ObjectIDFromHex(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid program ID"})
+			return
+		}
+		query.ProgramID = id
+	}
+	if v := c.Query("day_id"); v != "" {
+		id, err := bson.ObjectIDFromHex(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid day ID"})
+			return
+		}
+		query.DayID = id
+	}
+	system, ok := h.unitSystem(c, "")
+	if !ok {
+		return
+	}
+
+	next, err := h.service.GetNextSession(c.Request.Context(), query)
+	if err != nil {
+		programError(c, err)
+		return
+	}
+	next.Units = system
+	units.FromCanonical(next, system)
+
+	c.JSON(http.StatusOK, next)
+}
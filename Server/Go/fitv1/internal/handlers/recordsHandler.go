@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/AyushIIITU/virtualfit/internal/records"
+	"github.com/AyushIIITU/virtualfit/internal/units"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// recordsError maps personal record errors to responses
+func recordsError(c *gin.Context, err error) {
+	if errors.Is(err, records.ErrUnknownFormula) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+}
+
+// GetPersonalRecords lists the caller's records for every exercise they have logged
+func (h *Handler) GetPersonalRecords(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	system, ok := h.unitSystem(c, "")
+	if !ok {
+		return
+	}
+
+	result, err := h.service.GetPersonalRecords(c.Request.Context(), userID.(bson.ObjectID), c.Query("formula"))
+	if err != nil {
+		recordsError(c, err)
+		return
+	}
+	result.Units = system
+	units.FromCanonical(result, system)
+
+	c.JSON(http.StatusOK, result)
+}
+
+// GetRecordHistory reports each session of one exercise with the records it
+// set, optionally only between two dates
+func (h *Handler) GetRecordHistory(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	workoutID, err := bson.ObjectIDFromHex(c.Param("workoutId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid workout ID"})
+		return
+	}
+	prefs, ok := h.preferences(c)
+	if !ok {
+		return
+	}
+	from, _, err := dateParam(c, "from", prefs.Location)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	to, ok, err := dateParam(c, "to", prefs.Location)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if ok {
+		to = to.AddDate(0, 0, 1)
+	}
+
+	history, err := h.service.GetRecordHistory(c.Request.Context(), userID.(bson.ObjectID), workoutID, c.Query("formula"), from, to)
+	if err != nil {
+		recordsError(c, err)
+		return
+	}
+	history.Units = prefs.Units
+	units.FromCanonical(history, prefs.Units)
+
+	c.JSON(http.StatusOK, history)
+}
@@ -0,0 +1,404 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/AyushIIITU/virtualfit/internal/models"
+	"github.com/AyushIIITU/virtualfit/internal/service"
+	"github.com/AyushIIITU/virtualfit/internal/units"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// sessionError maps workout session errors to responses
+func sessionError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "session not found"})
+	case errors.Is(err, service.ErrConflict):
+		c.JSON(http.StatusConflict, gin.H{"error": "the session kept changing; try again"})
+	case errors.Is(err, service.ErrEntryNotFound), errors.Is(err, service.ErrSetNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrInvalidSession),
+		errors.Is(err, service.ErrFutureSession),
+		errors.Is(err, service.ErrSessionEnd),
+		errors.Is(err, service.ErrEmptySet),
+		errors.Is(err, service.ErrUnknownWorkout),
+		errors.Is(err, service.ErrSessionTooLarge),
+		errors.Is(err, service.ErrInvalidPosition):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+// sessionIDs parses the session, entry and set IDs in the path, as far as
+// the route has them
+func sessionIDs(c *gin.Context) (session, entry, set bson.ObjectID, ok bool) {
+	for _, p := range []struct {
+		param, name string
+		id          *bson.ObjectID
+	}{
+		{"id", "session", &session},
+		{"entryId", "entry", &entry},
+		{"setId", "set", &set},
+	} {
+		v := c.Param(p.param)
+		if v == "" {
+			break
+		}
+		id, err := bson.ObjectIDFromHex(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid " + p.name + " ID"})
+			return session, entry, set, false
+		}
+		*p.id = id
+	}
+	return session, entry, set, true
+}
+
+// sessionsResponse expresses the loads and distances of sessions in system
+func sessionsResponse(system string, sessions ...*models.WorkoutSession) {
+	for _, session := range sessions {
+		session.Units = system
+		units.FromCanonical(session, system)
+	}
+}
+
+// CreateWorkoutSession starts a session, optionally with its exercises and sets
+func (h *Handler) CreateWorkoutSession(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var input models.WorkoutSessionInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	system, ok := h.unitSystem(c, input.Units)
+	if !ok || !toCanonical(c, &input, system) {
+		return
+	}
+
+	session, err := h.service.CreateWorkoutSession(c.Request.Context(), userID.(bson.ObjectID), &input)
+	if err != nil {
+		sessionError(c, err)
+		return
+	}
+
+	sessionsResponse(system, session)
+	c.JSON(http.StatusCreated, session)
+}
+
+// ListWorkoutSessions pages through the caller's sessions, newest first,
+// optionally only those between two dates or with a given catalog exercise
+func (h *Handler) ListWorkoutSessions(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	prefs, ok := h.preferences(c)
+	if !ok {
+		return
+	}
+
+	query := models.WorkoutSessionQuery{UserID: userID.(bson.ObjectID)}
+	if v := c.Query("workout_id"); v != "" {
+		id, err := bson.ObjectIDFromHex(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid workout ID"})
+			return
+		}
+		query.WorkoutID = id
+	}
+	from, ok, err := dateParam(c, "from", prefs.Location)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if ok {
+		query.From = from
+	}
+	to, ok, err := dateParam(c, "to", prefs.Location)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if ok {
+		query.To = to.AddDate(0, 0, 1)
+	}
+	query.Limit, query.Offset = pageParams(c)
+
+	sessions, total, err := h.service.ListWorkoutSessions(c.Request.Context(), query)
+	if err != nil {
+		sessionError(c, err)
+		return
+	}
+	sessionsResponse(prefs.Units, sessions...)
+
+	c.JSON(http.StatusOK, pageResponse(sessions, total, query.Limit, query.Offset))
+}
+
+func (h *Handler) GetWorkoutSession(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	id, _, _, ok := sessionIDs(c)
+	if !ok {
+		return
+	}
+	system, ok := h.unitSystem(c, "")
+	if !ok {
+		return
+	}
+
+	session, err := h.service.GetWorkoutSession(c.Request.Context(), userID.(bson.ObjectID), id)
+	if err != nil {
+		sessionError(c, err)
+		return
+	}
+	sessionsResponse(system, session)
+
+	c.JSON(http.StatusOK, session)
+}
+
+// UpdateWorkoutSession changes the name, notes or times of a session
+func (h *Handler) UpdateWorkoutSession(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	id, _, _, ok := sessionIDs(c)
+	if !ok {
+		return
+	}
+	var update models.WorkoutSessionUpdate
+	if err := c.ShouldBindJSON(&update); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	system, ok := h.unitSystem(c, "")
+	if !ok {
+		return
+	}
+
+	session, err := h.service.UpdateWorkoutSession(c.Request.Context(), userID.(bson.ObjectID), id, &update)
+	if err != nil {
+		sessionError(c, err)
+		return
+	}
+	sessionsResponse(system, session)
+
+	c.JSON(http.StatusOK, session)
+}
+
+func (h *Handler) DeleteWorkoutSession(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	id, _, _, ok := sessionIDs(c)
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteWorkoutSession(c.Request.Context(), userID.(bson.ObjectID), id); err != nil {
+		sessionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "session deleted"})
+}
+
+// AddSessionEntry adds a catalog exercise, with any sets already done, to a session
+func (h *Handler) AddSessionEntry(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	id, _, _, ok := sessionIDs(c)
+	if !ok {
+		return
+	}
+	var req models.SessionEntryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	system, ok := h.unitSystem(c, req.Units)
+	if !ok || !toCanonical(c, &req, system) {
+		return
+	}
+
+	session, err := h.service.AddSessionEntry(c.Request.Context(), userID.(bson.ObjectID), id, &req.SessionEntryInput)
+	if err != nil {
+		sessionError(c, err)
+		return
+	}
+	sessionsResponse(system, session)
+
+	c.JSON(http.StatusCreated, session)
+}
+
+// UpdateSessionEntry changes an entry's notes or moves it within its session
+func (h *Handler) UpdateSessionEntry(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	id, entryID, _, ok := sessionIDs(c)
+	if !ok {
+		return
+	}
+	var update models.SessionEntryUpdate
+	if err := c.ShouldBindJSON(&update); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	system, ok := h.unitSystem(c, "")
+	if !ok {
+		return
+	}
+
+	session, err := h.service.UpdateSessionEntry(c.Request.Context(), userID.(bson.ObjectID), id, entryID, &update)
+	if err != nil {
+		sessionError(c, err)
+		return
+	}
+	sessionsResponse(system, session)
+
+	c.JSON(http.StatusOK, session)
+}
+
+func (h *Handler) DeleteSessionEntry(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	id, entryID, _, ok := sessionIDs(c)
+	if !ok {
+		return
+	}
+	system, ok := h.unitSystem(c, "")
+	if !ok {
+		return
+	}
+
+	session, err := h.service.DeleteSessionEntry(c.Request.Context(), userID.(bson.ObjectID), id, entryID)
+	if err != nil {
+		sessionError(c, err)
+		return
+	}
+	sessionsResponse(system, session)
+
+	c.JSON(http.StatusOK, session)
+}
+
+// AddWorkoutSet logs a set of an exercise entry
+func (h *Handler) AddWorkoutSet(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	id, entryID, _, ok := sessionIDs(c)
+	if !ok {
+		return
+	}
+	var req models.WorkoutSetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	system, ok := h.unitSystem(c, req.Units)
+	if !ok || !toCanonical(c, &req, system) {
+		return
+	}
+
+	session, err := h.service.AddWorkoutSet(c.Request.Context(), userID.(bson.ObjectID), id, entryID, &req.WorkoutSetUpdate)
+	if err != nil {
+		sessionError(c, err)
+		return
+	}
+	sessionsResponse(system, session)
+
+	c.JSON(http.StatusCreated, session)
+}
+
+// UpdateWorkoutSet corrects the values of a set
+func (h *Handler) UpdateWorkoutSet(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	id, entryID, setID, ok := sessionIDs(c)
+	if !ok {
+		return
+	}
+	var req models.WorkoutSetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	system, ok := h.unitSystem(c, req.Units)
+	if !ok || !toCanonical(c, &req, system) {
+		return
+	}
+
+	session, err := h.service.UpdateWorkoutSet(c.Request.Context(), userID.(bson.ObjectID), id, entryID, setID, &req.WorkoutSetUpdate)
+	if err != nil {
+		sessionError(c, err)
+		return
+	}
+	sessionsResponse(system, session)
+
+	c.JSON(http.StatusOK, session)
+}
+
+func (h *Handler) DeleteWorkoutSet(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	id, entryID, setID, ok := sessionIDs(c)
+	if !ok {
+		return
+	}
+	system, ok := h.unitSystem(c, "")
+	if !ok {
+		return
+	}
+
+	session, err := h.service.DeleteWorkoutSet(c.Request.Context(), userID.(bson.ObjectID), id, entryID, setID)
+	if err != nil {
+		sessionError(c, err)
+		return
+	}
+	sessionsResponse(system, session)
+
+	c.JSON(http.StatusOK, session)
+}
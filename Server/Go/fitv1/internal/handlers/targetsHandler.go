@@ -0,0 +1,33 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/AyushIIITU/virtualfit/internal/targets"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// GetTargetRecommendation returns recommended daily calorie and macro targets
+// for the caller. ?formula= picks mifflin-st-jeor, harris-benedict or
+// katch-mcardle.
+func (h *Handler) GetTargetRecommendation(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	rec, err := h.service.RecommendTargets(c.Request.Context(), userID.(bson.ObjectID), c.Query("formula"))
+	if err != nil {
+		if errors.Is(err, targets.ErrUnknownFormula) || errors.Is(err, targets.ErrBodyFatRequired) || errors.Is(err, targets.ErrIncomplete) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, rec)
+}
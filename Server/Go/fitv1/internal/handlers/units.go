@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/AyushIIITU/virtualfit/internal/models"
+	"github.com/AyushIIITU/virtualfit/internal/units"
+	"github.com/gin-gonic/gin"
+)
+
+// Body quantities are stored in metric units and nutrient amounts in g and
+// kcal. Requests are converted to them, and responses to the caller's
+// preferred units, by the helpers in this file.
+
+// unitSystem returns the unit system a request's values are in: the one the
+// request names, else the caller's preference, else metric
+func (h *Handler) unitSystem(c *gin.Context, declared string) (string, bool) {
+	if declared != "" {
+		if !units.ValidSystem(declared) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": units.ErrUnknownSystem.Error()})
+			return "", false
+		}
+		return declared, true
+	}
+
+	prefs, ok := h.preferences(c)
+	return prefs.Units, ok
+}
+
+// toCanonical converts a request body from system to metric units
+func toCanonical(c *gin.Context, v any, system string) bool {
+	if err := units.ToCanonical(v, system); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return false
+	}
+	return true
+}
+
+// normalizeNutrients converts nutrient amounts to g, or kcal for calories
+func normalizeNutrients(c *gin.Context, nutrients []models.Nutrient) bool {
+	for i := range nutrients {
+		if err := nutrients[i].Normalize(); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return false
+		}
+	}
+	return true
+}
+
+// userResponse expresses a user's body stats in their preferred units
+func userResponse(user *models.User) *models.User {
+	user.Units = user.UnitSystem()
+	units.FromCanonical(user, user.Units)
+	return user
+}
+
+// usersResponse expresses each user's body stats in their own preferred units
+func usersResponse(users []*models.User) []*models.User {
+	for _, user := range users {
+		userResponse(user)
+	}
+	return users
+}
+
+// measurementsResponse expresses body measurements in system
+func measurementsResponse(system string, measurements ...*models.BodyMeasurement) {
+	for _, measurement := range measurements {
+		measurement.Units = system
+		units.FromCanonical(measurement, system)
+	}
+}
+
+// programsResponse expresses programs in system
+func programsResponse(system string, programs ...*models.Program) {
+	for _, program := range programs {
+		program.Units = system
+		units.FromCanonical(program, system)
+	}
+}
+
+// trendResponse expresses a measurement trend in system, rounded to the
+// display precision of the unit. The quantity of its values depends on the metric, so they are
+// not tagged.
+func trendResponse(trend *models.MeasurementTrend, system string) *models.MeasurementTrend {
+	quantity := models.MetricQuantities[trend.Metric]
+	from, to := units.UnitOf(quantity, units.Metric), units.UnitOf(quantity, system)
+	convert := func(v *float64) {
+		if v != nil {
+			converted, _ := units.Convert(*v, from, to)
+			*v = units.RoundIn(converted, to)
+		}
+	}
+
+	trend.Unit = to
+	for i := range trend.Points {
+		convert(&trend.Points[i].Value)
+		convert(&trend.Points[i].MovingAverage)
+	}
+	convert(trend.Current)
+	convert(trend.WeeklyRate)
+	convert(trend.Goal)
+	return trend
+}
+
+// goalToCanonical converts a trend goal for metric from system to metric units
+func goalToCanonical(goal *float64, metric, system string) {
+	quantity, ok := models.MetricQuantities[metric]
+	if !ok || goal == nil {
+		return
+	}
+	*goal, _ = units.Convert(*goal, units.UnitOf(quantity, system), units.UnitOf(quantity, units.Metric))
+}
@@ -1,13 +1,12 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
-	"os"
-	"time"
 
 	"github.com/AyushIIITU/virtualfit/internal/models"
+	"github.com/AyushIIITU/virtualfit/internal/service"
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt"
 	"go.mongodb.org/mongo-driver/v2/bson"
 )
 
@@ -17,14 +16,26 @@ func (h *Handler) Register(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	system, ok := h.unitSystem(c, userReg.Units)
+	if !ok || !toCanonical(c, &userReg, system) {
+		return
+	}
 
 	user, err := h.service.RegisterUser(c.Request.Context(), &userReg)
 	if err != nil {
+		if errors.Is(err, service.ErrEmailTaken) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, service.ErrInvalidProfile) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusCreated, user)
+	c.JSON(http.StatusCreated, userResponse(user))
 }
 
 func (h *Handler) Login(c *gin.Context) {
@@ -34,35 +45,51 @@ func (h *Handler) Login(c *gin.Context) {
 		return
 	}
 
-	user, err := h.service.LoginUser(c.Request.Context(), &login)
+	user, err := h.service.LoginUser(c.Request.Context(), &login, c.ClientIP())
 	if err != nil {
+		if loginThrottled(c, err) {
+			return
+		}
+		if errors.Is(err, service.ErrAccountDisabled) || errors.Is(err, service.ErrPasswordResetRequired) {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
 		return
 	}
 
-	// Generate JWT token
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"user_id": user.ID.Hex(),
-		"exp":     time.Now().Add(time.Hour * 24).Unix(), // Token expires in 24 hours
-	})
+	// Users with two-factor authentication finish logging in at /auth/mfa/verify
+	if user.MFAEnabled {
+		challenge, err := h.service.CreateMFAChallenge(c.Request.Context(), user)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+			return
+		}
+		c.JSON(http.StatusOK, challenge)
+		return
+	}
 
-	// Sign the token with the secret key
-	tokenString, err := token.SignedString([]byte(os.Getenv("JWT_SECRET")))
+	tokens, err := h.service.IssueTokens(c.Request.Context(), user)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
 		return
 	}
 
-	// Return user data and token
+	// Return user data and tokens
 	c.JSON(http.StatusOK, gin.H{
-		"user":  user,
-		"token": tokenString,
+		"user":          userResponse(user),
+		"token":         tokens.AccessToken,
+		"refresh_token": tokens.RefreshToken,
+		"token_type":    tokens.TokenType,
+		"expires_in":    tokens.ExpiresIn,
 	})
 }
 
+// UpdateProfile applies a JSON Merge Patch to the caller's profile and
+// returns the updated profile
 func (h *Handler) UpdateProfile(c *gin.Context) {
-	var user models.User
-	if err := c.ShouldBindJSON(&user); err != nil {
+	var patch models.ProfileUpdate
+	if err := c.ShouldBindJSON(&patch); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
@@ -73,13 +100,26 @@ func (h *Handler) UpdateProfile(c *gin.Context) {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
 		return
 	}
-	user.ID = userID.(bson.ObjectID)
 
-	err := h.service.UpdateUserProfile(c.Request.Context(), &user)
+	// Values are in the units the patch switches to, if any
+	var declared string
+	if patch.Units != nil {
+		declared = *patch.Units
+	}
+	system, ok := h.unitSystem(c, declared)
+	if !ok || !toCanonical(c, &patch, system) {
+		return
+	}
+
+	user, err := h.service.UpdateUserProfile(c.Request.Context(), userID.(bson.ObjectID), &patch)
 	if err != nil {
+		if errors.Is(err, service.ErrInvalidProfile) || errors.Is(err, service.ErrAutoTargets) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "profile updated successfully"})
+	c.JSON(http.StatusOK, userResponse(user))
 }
@@ -9,6 +9,7 @@ import (
 
 	"github.com/AyushIIITU/virtualfit/internal/models"
 	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/v2/bson"
 )
 
 func (h *Handler) ListWorkoutAPI(c *gin.Context) {
@@ -42,6 +43,7 @@ func (h *Handler) ListWorkoutAPI(c *gin.Context) {
 	for _, workout := range workouts {
 		// Use the ID_Default field which is already a string
 		workoutResponse := models.WorkoutResponse{
+			ObjectID:         workout.ID.Hex(),
 			ID:               workout.ID_Default,
 			Name:             workout.Name,
 			Force:            workout.Force,
@@ -121,6 +123,7 @@ func (h *Handler) SearchWorkoutAPI(c *gin.Context) {
 	var response []models.WorkoutResponse
 	for _, workout := range workouts {
 		workoutResponse := models.WorkoutResponse{
+			ObjectID:         workout.ID.Hex(),
 			ID:               workout.ID_Default,
 			Name:             workout.Name,
 			Force:            workout.Force,
@@ -176,3 +179,73 @@ func (h *Handler) GetWorkoutImage(c *gin.Context) {
 	c.Header("Content-Type", contentType)
 	c.File(imagePath)
 }
+
+// CreateWorkout adds a workout to the catalog
+func (h *Handler) CreateWorkout(c *gin.Context) {
+	actor, ok := auditActor(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	var input models.WorkoutInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	workout, err := h.service.CreateWorkout(c.Request.Context(), actor, &input)
+	if err != nil {
+		adminError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, workout)
+}
+
+// UpdateWorkout replaces a catalog workout
+func (h *Handler) UpdateWorkout(c *gin.Context) {
+	actor, ok := auditActor(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	id, err := bson.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid workout ID"})
+		return
+	}
+	var input models.WorkoutInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	workout, err := h.service.UpdateWorkout(c.Request.Context(), actor, id, &input)
+	if err != nil {
+		adminError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, workout)
+}
+
+// DeleteWorkout removes a workout from the catalog
+func (h *Handler) DeleteWorkout(c *gin.Context) {
+	actor, ok := auditActor(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	id, err := bson.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid workout ID"})
+		return
+	}
+
+	if err := h.service.DeleteWorkout(c.Request.Context(), actor, id); err != nil {
+		adminError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "workout deleted"})
+}
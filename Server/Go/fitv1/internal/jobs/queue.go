@@ -0,0 +1,257 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"github.com/AyushIIITU/virtualfit/internal/models"
+	"github.com/AyushIIITU/virtualfit/internal/repository"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+var log = logrus.New()
+
+// Handler runs one attempt of a job. Returning an error schedules a retry
+// unless the job is out of attempts or the error is Permanent.
+type Handler func(ctx context.Context, job *models.Job) error
+
+// FailureHook is called after every failed attempt, once the job's next state
+// (failed or dead) has been stored
+type FailureHook func(ctx context.Context, job *models.Job, err error)
+
+// Options configures the worker pool
+type Options struct {
+	Workers           int
+	MaxAttempts       int
+	VisibilityTimeout time.Duration // how long a claimed job stays invisible to other workers
+	BackoffBase       time.Duration
+	BackoffMax        time.Duration
+	PollInterval      time.Duration
+}
+
+type registration struct {
+	run       Handler
+	onFailure FailureHook
+}
+
+// Queue is a durable job queue with a bounded worker pool
+type Queue struct {
+	store    repository.JobStore
+	opts     Options
+	handlers map[string]registration
+	wake     chan struct{}
+	cancel   context.CancelFunc
+	wg       sync.WaitGroup
+}
+
+func NewQueue(store repository.JobStore, opts Options) *Queue {
+	if opts.Workers <= 0 {
+		opts.Workers = 1
+	}
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = 1
+	}
+	if opts.VisibilityTimeout <= 0 {
+		opts.VisibilityTimeout = 2 * time.Minute
+	}
+	if opts.BackoffBase <= 0 {
+		opts.BackoffBase = time.Second
+	}
+	if opts.BackoffMax < opts.BackoffBase {
+		opts.BackoffMax = opts.BackoffBase
+	}
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = 5 * time.Second
+	}
+	return &Queue{
+		store:    store,
+		opts:     opts,
+		handlers: make(map[string]registration),
+		wake:     make(chan struct{}, 1),
+	}
+}
+
+// Register sets the handler for a job type. It must be called before Start.
+func (q *Queue) Register(jobType string, run Handler, onFailure FailureHook) {
+	q.handlers[jobType] = registration{run: run, onFailure: onFailure}
+}
+
+// Enqueue stores a new job that is due immediately
+func (q *Queue) Enqueue(ctx context.Context, jobType string, refID bson.ObjectID) (*models.Job, error) {
+	now := time.Now()
+	job, err := q.store.EnqueueJob(ctx, &models.Job{
+		Type:        jobType,
+		RefID:       refID,
+		State:       models.JobQueued,
+		MaxAttempts: q.opts.MaxAttempts,
+		RunAt:       now,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Nudge an idle worker instead of waiting for the next poll
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+	return job, nil
+}
+
+// Start recovers jobs abandoned by a previous process and launches the workers
+func (q *Queue) Start(ctx context.Context) error {
+	recovered, err := q.store.RecoverJobs(ctx, time.Now())
+	if err != nil {
+		return err
+	}
+	if recovered > 0 {
+		log.Printf("Recovered %d abandoned jobs", recovered)
+	}
+
+	ctx, q.cancel = context.WithCancel(ctx)
+	for i := 0; i < q.opts.Workers; i++ {
+		q.wg.Add(1)
+		go q.work(ctx)
+	}
+	return nil
+}
+
+// Stop signals the workers to exit and waits for in-flight jobs to finish
+func (q *Queue) Stop() {
+	if q.cancel != nil {
+		q.cancel()
+	}
+	q.wg.Wait()
+}
+
+func (q *Queue) work(ctx context.Context) {
+	defer q.wg.Done()
+
+	ticker := time.NewTicker(q.opts.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		// Drain every due job before going idle
+		for ctx.Err() == nil {
+			job, err := q.store.ClaimJob(ctx, time.Now(), q.opts.VisibilityTimeout)
+			if errors.Is(err, repository.ErrNotFound) {
+				break
+			}
+			if err != nil {
+				if ctx.Err() == nil {
+					log.Printf("Error claiming job: %v", err)
+				}
+				break
+			}
+			q.run(job)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-q.wake:
+		case <-ticker.C:
+		}
+	}
+}
+
+// run executes one attempt and records the outcome. Jobs are run on a fresh
+// context bounded by the visibility timeout so shutdown lets them finish.
+func (q *Queue) run(job *models.Job) {
+	reg, ok := q.handlers[job.Type]
+	var err error
+	switch {
+	case !ok:
+		err = Permanent(errors.New("no handler registered for job type " + job.Type))
+	case job.Attempts > job.MaxAttempts:
+		// Claiming counts an attempt, so a job reclaimed after its worker
+		// died on the last attempt is already past its budget
+		err = Permanent(fmt.Errorf("lease expired on the last of %d attempts", job.MaxAttempts))
+	default:
+		runCtx, cancel := context.WithTimeout(context.Background(), q.opts.VisibilityTimeout)
+		err = reg.run(runCtx, job)
+		cancel()
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	now := time.Now()
+	job.LeaseExpiresAt = time.Time{}
+	if err == nil {
+		job.State = models.JobSucceeded
+		job.LastError = ""
+		job.FinishedAt = now
+	} else {
+		job.LastError = err.Error()
+		if IsPermanent(err) || job.Attempts >= job.MaxAttempts {
+			job.State = models.JobDead
+			job.FinishedAt = now
+		} else {
+			job.State = models.JobFailed
+			job.RunAt = now.Add(q.backoff(job.Attempts))
+		}
+	}
+
+	if updateErr := q.store.UpdateJob(ctx, job); updateErr != nil {
+		// The lease expired and someone else owns the job now
+		log.Printf("Error recording result of job %s: %v", job.ID.Hex(), updateErr)
+		return
+	}
+
+	if err != nil {
+		log.Printf("Job %s (%s) attempt %d/%d failed: %v", job.ID.Hex(), job.Type, job.Attempts, job.MaxAttempts, err)
+		if ok && reg.onFailure != nil {
+			reg.onFailure(ctx, job, err)
+		}
+	}
+}
+
+// backoff returns the delay before the next attempt: BackoffBase doubled for
+// every previous attempt, capped at BackoffMax, with up to 20% jitter
+func (q *Queue) backoff(attempts int) time.Duration {
+	delay := q.opts.BackoffBase
+	for i := 1; i < attempts && delay < q.opts.BackoffMax; i++ {
+		delay *= 2
+	}
+	if delay > q.opts.BackoffMax {
+		delay = q.opts.BackoffMax
+	}
+	jitter := time.Duration(rand.Int64N(int64(delay)/5 + 1))
+	return delay + jitter
+}
+
+// ErrNotRetryable is returned when retrying a job that is still in progress
+var ErrNotRetryable = errors.New("only failed or dead jobs can be retried")
+
+// Retry puts a failed or dead job back in the queue with a fresh set of attempts
+func (q *Queue) Retry(ctx context.Context, id bson.ObjectID) (*models.Job, error) {
+	job, err := q.store.GetJobByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if job.State != models.JobFailed && job.State != models.JobDead {
+		return nil, ErrNotRetryable
+	}
+
+	job.State = models.JobQueued
+	job.RunAt = time.Now()
+	job.MaxAttempts = job.Attempts + q.opts.MaxAttempts
+	job.FinishedAt = time.Time{}
+	if err := q.store.UpdateJob(ctx, job); err != nil {
+		return nil, err
+	}
+
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+	return job, nil
+}
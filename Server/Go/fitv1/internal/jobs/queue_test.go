@@ -0,0 +1,83 @@
+package jobs
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/AyushIIITU/virtualfit/internal/models"
+	"github.com/AyushIIITU/virtualfit/internal/repository"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// waitForState polls until the job reaches state or the test times out
+func waitForState(t *testing.T, store *repository.Memory, id bson.ObjectID, state models.JobState) *models.Job {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		job, err := store.GetJobByID(context.Background(), id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if job.State == state {
+			return job
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("job is %s, want %s", job.State, state)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestReclaimedJobs(t *testing.T) {
+	tests := []struct {
+		name     string
+		attempts int // made before the worker died
+		want     models.JobState
+		runs     int32
+		failures int32
+	}{
+		{"attempts left", 1, models.JobSucceeded, 1, 0},
+		{"last attempt", 2, models.JobDead, 0, 1},
+		{"already past the budget", 3, models.JobDead, 0, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := repository.NewMemory()
+			now := time.Now()
+			job, err := store.EnqueueJob(context.Background(), &models.Job{
+				Type:           "test",
+				State:          models.JobRunning,
+				Attempts:       tt.attempts,
+				MaxAttempts:    2,
+				RunAt:          now.Add(-time.Hour),
+				LeaseExpiresAt: now.Add(-time.Minute),
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var runs, failures atomic.Int32
+			q := NewQueue(store, Options{MaxAttempts: 2, PollInterval: 10 * time.Millisecond})
+			q.Register("test",
+				func(ctx context.Context, job *models.Job) error { runs.Add(1); return nil },
+				func(ctx context.Context, job *models.Job, err error) { failures.Add(1) })
+			if err := q.Start(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+			got := waitForState(t, store, job.ID, tt.want)
+			q.Stop()
+
+			if runs.Load() != tt.runs {
+				t.Errorf("handler ran %d times, want %d", runs.Load(), tt.runs)
+			}
+			if failures.Load() != tt.failures {
+				t.Errorf("failure hook ran %d times, want %d", failures.Load(), tt.failures)
+			}
+			if tt.want == models.JobDead && got.LastError == "" {
+				t.Error("a dead job should say why")
+			}
+		})
+	}
+}
@@ -1,18 +1,25 @@
 package middleware
 
 import (
+	"context"
 	"net/http"
+	"slices"
 	"strings"
 	"time"
 
-	// "github.com/ayushIIITU/fitv1/config"
 	"github.com/AyushIIITU/virtualfit/config"
+	"github.com/AyushIIITU/virtualfit/internal/auth"
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt/v5"
-	"go.mongodb.org/mongo-driver/v2/bson"
 )
 
-func AuthMiddleware(config *config.Config) gin.HandlerFunc {
+// TokenVerifier validates access tokens, including revocation
+type TokenVerifier interface {
+	VerifyAccessToken(ctx context.Context, token string) (*auth.Claims, error)
+}
+
+// AuthMiddleware authenticates the bearer token and stores the caller's user
+// ID and token claims in the context
+func AuthMiddleware(verifier TokenVerifier) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
@@ -28,25 +35,14 @@ func AuthMiddleware(config *config.Config) gin.HandlerFunc {
 			return
 		}
 
-		tokenString := parts[1]
-		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-			return []byte(config.JWTSecret), nil
-		})
-
-		if err != nil || !token.Valid {
+		claims, err := verifier.VerifyAccessToken(c.Request.Context(), parts[1])
+		if err != nil {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
 			c.Abort()
 			return
 		}
 
-		claims, ok := token.Claims.(jwt.MapClaims)
-		if !ok {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid token claims"})
-			c.Abort()
-			return
-		}
-
-		userID, err := bson.ObjectIDFromHex(claims["user_id"].(string))
+		userID, err := claims.UserObjectID()
 		if err != nil {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid user ID in token"})
 			c.Abort()
@@ -54,6 +50,42 @@ func AuthMiddleware(config *config.Config) gin.HandlerFunc {
 		}
 
 		c.Set("userID", userID)
+		c.Set("claims", claims)
+		c.Next()
+	}
+}
+
+// RequireVerifiedEmail applies the configured access policy to accounts that
+// have not verified their email address. It must run after AuthMiddleware.
+func RequireVerifiedEmail(policy string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims, ok := c.MustGet("claims").(*auth.Claims)
+		if !ok || claims.EmailVerified || policy == config.UnverifiedFull {
+			c.Next()
+			return
+		}
+
+		readOnly := c.Request.Method == http.MethodGet || c.Request.Method == http.MethodHead
+		if policy == config.UnverifiedReadOnly && readOnly {
+			c.Next()
+			return
+		}
+
+		c.JSON(http.StatusForbidden, gin.H{"error": "email address is not verified"})
+		c.Abort()
+	}
+}
+
+// RequireRole lets only users holding one of roles through. It must run after
+// AuthMiddleware.
+func RequireRole(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims, ok := c.MustGet("claims").(*auth.Claims)
+		if !ok || !slices.Contains(roles, claims.Role) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "insufficient permissions"})
+			c.Abort()
+			return
+		}
 		c.Next()
 	}
 }
@@ -88,7 +120,7 @@ func CORSMiddleware() gin.HandlerFunc {
 		c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
 		c.Writer.Header().Set("Access-Control-Allow-Credentials", "true")
 		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With")
-		c.Writer.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS, GET, PUT, DELETE")
+		c.Writer.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS, GET, PUT, PATCH, DELETE")
 
 		if c.Request.Method == "OPTIONS" {
 			c.AbortWithStatus(204)
@@ -97,4 +129,4 @@ func CORSMiddleware() gin.HandlerFunc {
 
 		c.Next()
 	}
-} 
\ No newline at end of file
+}
@@ -0,0 +1,71 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/AyushIIITU/virtualfit/config"
+	"github.com/AyushIIITU/virtualfit/internal/models"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+var log = logrus.New()
+
+// RateLimitStore keeps the token buckets behind RateLimit
+type RateLimitStore interface {
+	TakeRateToken(ctx context.Context, key string, capacity int, period time.Duration, now time.Time) (*models.RateBucket, error)
+}
+
+// RateLimit applies the named token bucket policy to each caller, identified
+// by user ID after AuthMiddleware and by client IP otherwise. Responses carry
+// RateLimit-* headers; rejected requests get 429 with Retry-After. If the
+// store fails the request is let through, so an outage does not take the API
+// down with it.
+func RateLimit(store RateLimitStore, name string, limit config.RateLimit) gin.HandlerFunc {
+	if limit.Requests <= 0 {
+		return func(c *gin.Context) { c.Next() }
+	}
+	policy := fmt.Sprintf("%d;w=%d", limit.Requests, int(limit.Period.Seconds()))
+
+	return func(c *gin.Context) {
+		key := name + ":ip:" + c.ClientIP()
+		if userID, ok := c.Get("userID"); ok {
+			key = name + ":user:" + userID.(bson.ObjectID).Hex()
+		}
+
+		now := time.Now()
+		bucket, err := store.TakeRateToken(c.Request.Context(), key, limit.Requests, limit.Period, now)
+		if err != nil {
+			log.Errorf("Rate limit %s unavailable: %v", name, err)
+			c.Next()
+			return
+		}
+
+		c.Header("RateLimit-Policy", policy)
+		c.Header("RateLimit-Limit", strconv.Itoa(limit.Requests))
+		c.Header("RateLimit-Remaining", strconv.Itoa(int(bucket.Tokens)))
+		c.Header("RateLimit-Reset", strconv.Itoa(ceilSeconds(bucket.ExpiresAt.Sub(now))))
+		if bucket.Allowed {
+			c.Next()
+			return
+		}
+
+		// Time until the bucket refills to one whole token
+		wait := time.Duration((1 - bucket.Tokens) * float64(limit.Period) / float64(limit.Requests))
+		seconds := ceilSeconds(wait)
+		c.Header("Retry-After", strconv.Itoa(seconds))
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "rate limit exceeded", "retry_after": seconds})
+		c.Abort()
+	}
+}
+
+// ceilSeconds rounds up to whole seconds, with a minimum of one
+func ceilSeconds(d time.Duration) int {
+	return max(1, int(math.Ceil(d.Seconds())))
+}
@@ -0,0 +1,81 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/AyushIIITU/virtualfit/config"
+	"github.com/AyushIIITU/virtualfit/internal/models"
+	"github.com/AyushIIITU/virtualfit/internal/repository"
+	"github.com/gin-gonic/gin"
+)
+
+func rateLimited(store RateLimitStore, limit config.RateLimit) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/", RateLimit(store, "test", limit), func(c *gin.Context) { c.Status(http.StatusOK) })
+	return router
+}
+
+func get(router *gin.Engine, ip string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = ip + ":1234"
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestRateLimitHeaders(t *testing.T) {
+	router := rateLimited(repository.NewMemory(), config.RateLimit{Requests: 2, Period: time.Minute})
+
+	tests := []struct {
+		ip        string
+		code      int
+		remaining string
+		reset     string
+		retry     string
+	}{
+		{"192.0.2.1", http.StatusOK, "1", "30", ""},
+		{"192.0.2.1", http.StatusOK, "0", "60", ""},
+		{"192.0.2.1", http.StatusTooManyRequests, "0", "60", "30"}, // a token comes back every 30 seconds
+		{"192.0.2.2", http.StatusOK, "1", "30", ""},
+	}
+	for i, tt := range tests {
+		w := get(router, tt.ip)
+		if w.Code != tt.code {
+			t.Errorf("request %d: %d, want %d", i, w.Code, tt.code)
+		}
+		want := map[string]string{
+			"RateLimit-Policy":    "2;w=60",
+			"RateLimit-Limit":     "2",
+			"RateLimit-Remaining": tt.remaining,
+			"RateLimit-Reset":     tt.reset,
+			"Retry-After":         tt.retry,
+		}
+		for header, value := range want {
+			if got := w.Header().Get(header); got != value {
+				t.Errorf("request %d: %s %q, want %q", i, header, got, value)
+			}
+		}
+	}
+}
+
+// brokenStore fails every request for a token
+type brokenStore struct{}
+
+func (brokenStore) TakeRateToken(context.Context, string, int, time.Duration, time.Time) (*models.RateBucket, error) {
+	return nil, errors.New("store unavailable")
+}
+
+func TestRateLimitLetsRequestsThroughWhenTheStoreFails(t *testing.T) {
+	router := rateLimited(brokenStore{}, config.RateLimit{Requests: 1, Period: time.Minute})
+	for i := 0; i < 3; i++ {
+		if w := get(router, "192.0.2.1"); w.Code != http.StatusOK || w.Header().Get("RateLimit-Limit") != "" {
+			t.Errorf("request %d: %d with headers %v", i, w.Code, w.Header())
+		}
+	}
+}
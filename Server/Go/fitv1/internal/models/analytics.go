@@ -0,0 +1,75 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// TrainingQuery selects the training analysed by the analytics endpoint
+type TrainingQuery struct {
+	UserID          bson.ObjectID
+	From            time.Time // local dates, both inclusive
+	To              time.Time
+	Granularity     string // "day" or "week"
+	Location        *time.Location
+	SecondaryWeight float64 // share of a set counted for secondary muscles
+	UntrainedDays   int     // days without work before a muscle is reported
+}
+
+// MuscleLoad is the work a muscle group got. A set counts fully for the
+// primary muscles of its exercise and by the secondary weight for the
+// secondary ones; tonnage is weighted the same way.
+type MuscleLoad struct {
+	Muscle        string  `json:"muscle"`
+	Sets          float64 `json:"sets"`
+	PrimarySets   int     `json:"primary_sets"`
+	SecondarySets int     `json:"secondary_sets"`
+	Tonnage       float64 `json:"tonnage" unit:"load"` // load times reps
+}
+
+// WorkloadRatio compares the tonnage of the last 7 days (acute) with the
+// weekly average of the last 28 days (chronic), up to AsOf
+type WorkloadRatio struct {
+	AsOf    time.Time `json:"as_of"`
+	Acute   float64   `json:"acute" unit:"load"`
+	Chronic float64   `json:"chronic" unit:"load"`
+	Ratio   *float64  `json:"ratio"`          // unset without chronic load
+	Zone    string    `json:"zone,omitempty"` // low, optimal, high or very_high
+}
+
+// TrainingPeriod is the training of one day or week
+type TrainingPeriod struct {
+	Start    time.Time     `json:"start"`
+	End      time.Time     `json:"end"`
+	Days     int           `json:"days"`
+	Sessions int           `json:"sessions"`
+	Sets     int           `json:"sets"` // working sets
+	Reps     int           `json:"reps"`
+	Tonnage  float64       `json:"tonnage" unit:"load"`
+	Muscles  []MuscleLoad  `json:"muscles"`  // most sets first
+	Workload WorkloadRatio `json:"workload"` // at the end of the period
+}
+
+// UntrainedMuscle is a muscle group without work for a while
+type UntrainedMuscle struct {
+	Muscle      string     `json:"muscle"`
+	LastTrained *time.Time `json:"last_trained"` // unset when never trained
+	DaysSince   *int       `json:"days_since"`
+}
+
+// TrainingAnalytics is the response of the training analytics endpoint
+type TrainingAnalytics struct {
+	From            time.Time         `json:"from"`
+	To              time.Time         `json:"to"`
+	Granularity     string            `json:"granularity"`
+	Timezone        string            `json:"timezone"`
+	WeekStart       string            `json:"week_start,omitempty"` // first day of weekly periods
+	Units           string            `json:"units"`
+	SecondaryWeight float64           `json:"secondary_weight"`
+	Periods         []TrainingPeriod  `json:"periods"`
+	Muscles         []MuscleLoad      `json:"muscles"` // over the whole range
+	Workload        WorkloadRatio     `json:"workload"`
+	UntrainedDays   int               `json:"untrained_days"`
+	Untrained       []UntrainedMuscle `json:"untrained"`
+}
@@ -0,0 +1,52 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// Audited actions
+const (
+	AuditUserList       = "user.list"
+	AuditUserView       = "user.view"
+	AuditUserDisable    = "user.disable"
+	AuditUserEnable     = "user.enable"
+	AuditUserForceReset = "user.force_password_reset"
+	AuditUserRoleChange = "user.role_change"
+	AuditUserMFAReset   = "user.mfa_reset"
+	AuditLoginLockout   = "login.lockout"
+	AuditJobList        = "job.list"
+	AuditJobRetry       = "job.retry"
+	AuditLogView        = "audit.view"
+	AuditWorkoutCreate  = "workout.create"
+	AuditWorkoutUpdate  = "workout.update"
+	AuditWorkoutDelete  = "workout.delete"
+)
+
+// AuditActor identifies who performed a privileged action
+type AuditActor struct {
+	UserID bson.ObjectID
+	IP     string
+}
+
+// AuditEntry records one privileged action
+type AuditEntry struct {
+	ID         bson.ObjectID `bson:"_id,omitempty" json:"id"`
+	ActorID    bson.ObjectID `bson:"actor_id" json:"actor_id"`
+	ActorIP    string        `bson:"actor_ip" json:"actor_ip"`
+	Action     string        `bson:"action" json:"action"`
+	TargetType string        `bson:"target_type,omitempty" json:"target_type,omitempty"`
+	TargetID   string        `bson:"target_id,omitempty" json:"target_id,omitempty"`
+	Details    bson.M        `bson:"details,omitempty" json:"details,omitempty"`
+	CreatedAt  time.Time     `bson:"created_at" json:"created_at"`
+}
+
+// AuditQuery selects audit entries, newest first
+type AuditQuery struct {
+	ActorID  bson.ObjectID
+	Action   string
+	TargetID string
+	Limit    int
+	Offset   int
+}
@@ -1,15 +1,59 @@
 package models
 
 import (
+	"regexp"
+	"strconv"
 	"time"
 
 	"go.mongodb.org/mongo-driver/v2/bson"
 )
 
+// Exercise is a legacy log entry of one catalog exercise with a free-text rep
+// count. Entries are migrated into workout sessions at startup.
 type Exercise struct {
-	ID            bson.ObjectID `bson:"_id,omitempty" json:"id"`
-	WorkoutOut    bson.ObjectID `bson:"workouts" json:"workouts"`
-	RepCount      string            `bson:"rep_count" json:"rep_count"`
-	Time          time.Time         `bson:"time" json:"time"`
-	CreatedAt     time.Time         `bson:"created_at" json:"created_at"`
-} 
\ No newline at end of file
+	ID         bson.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID     bson.ObjectID `bson:"user_id" json:"user_id"`
+	WorkoutOut bson.ObjectID `bson:"workouts" json:"workouts"`
+	RepCount   string        `bson:"rep_count" json:"rep_count"`
+	Time       time.Time     `bson:"time" json:"time"`
+	CreatedAt  time.Time     `bson:"created_at" json:"created_at"`
+}
+
+// repCountPattern matches rep counts like "12", "12 reps", "3x10" or "3 x 10 reps"
+var repCountPattern = regexp.MustCompile(`^\s*(?:(\d{1,2})\s*[xX×*]\s*)?(\d{1,4})\s*(?:reps?)?\s*$`)
+
+// Session converts the entry into a session with one exercise. The session
+// keeps the entry's ID, so references to it stay valid. Rep counts that cannot
+// be read as sets are kept in the exercise notes.
+func (e *Exercise) Session() *WorkoutSession {
+	startedAt := e.Time
+	if startedAt.IsZero() {
+		startedAt = e.CreatedAt
+	}
+
+	entry := SessionEntry{ID: bson.NewObjectID(), WorkoutID: e.WorkoutOut, Sets: []WorkoutSet{}}
+	if m := repCountPattern.FindStringSubmatch(e.RepCount); m != nil {
+		sets := 1
+		if m[1] != "" {
+			sets, _ = strconv.Atoi(m[1])
+		}
+		reps, _ := strconv.Atoi(m[2])
+		for i := 0; i < sets; i++ {
+			r := reps
+			entry.Sets = append(entry.Sets, WorkoutSet{ID: bson.NewObjectID(), Reps: &r})
+		}
+	}
+	// Keep whatever could not be read as sets, such as "0x10", as a note
+	if len(entry.Sets) == 0 && e.RepCount != "" {
+		entry.Notes = e.RepCount
+	}
+
+	return &WorkoutSession{
+		ID:        e.ID,
+		UserID:    e.UserID,
+		StartedAt: startedAt,
+		Entries:   []SessionEntry{entry},
+		CreatedAt: e.CreatedAt,
+		UpdatedAt: time.Now(),
+	}
+}
@@ -1,40 +1,196 @@
 package models
 
 import (
+	"cmp"
+	"errors"
+	"fmt"
+	"strings"
 	"time"
 
+	"github.com/AyushIIITU/virtualfit/internal/units"
 	"go.mongodb.org/mongo-driver/v2/bson"
 )
 
-// FoodIntakeRequest represents the request for creating a food intake record
+// FoodIntakeRequest represents the request for creating a food intake record.
+// Calories are in EnergyUnit (kcal by default) and the macronutrients in
+// MassUnit (g by default).
 type FoodIntakeRequest struct {
 	FoodName    string    `json:"foodName" binding:"required"`
-	Calories    float64   `json:"calories"`
-	Protein     float64   `json:"protein"`
-	Carbs       float64   `json:"carbs"`
-	Fat         float64   `json:"fat"`
+	Calories    float64   `json:"calories" binding:"gte=0"`
+	Protein     float64   `json:"protein" binding:"gte=0"`
+	Carbs       float64   `json:"carbs" binding:"gte=0"`
+	Fat         float64   `json:"fat" binding:"gte=0"`
+	EnergyUnit  string    `json:"energyUnit"`
+	MassUnit    string    `json:"massUnit"`
 	Date        time.Time `json:"date" binding:"required"`
 	MealType    string    `json:"mealType" binding:"required"`
 	ImageBase64 string    `json:"imageBase64"`
 }
 
+// Nutrients returns the amounts of the request in the units it gives them in.
+// Normalize them before storing.
+func (r *FoodIntakeRequest) Nutrients() []Nutrient {
+	energy, mass := cmp.Or(r.EnergyUnit, units.Kilocalorie), cmp.Or(r.MassUnit, units.Gram)
+	return []Nutrient{
+		{Name: NutrientCalories, Amount: r.Calories, Unit: energy},
+		{Name: NutrientProtein, Amount: r.Protein, Unit: mass},
+		{Name: NutrientCarbohydrates, Amount: r.Carbs, Unit: mass},
+		{Name: NutrientFat, Amount: r.Fat, Unit: mass},
+	}
+}
+
+// Analysis states of a food intake image
+const (
+	FoodAnalysisPending    = "pending"
+	FoodAnalysisProcessing = "processing"
+	FoodAnalysisRetrying   = "retrying"
+	FoodAnalysisCompleted  = "completed"
+	FoodAnalysisFailed     = "failed"
+	FoodAnalysisManual     = "manual" // logged by hand, or completed by hand after the analysis failed
+)
+
+// Nutrient names shared by analyzed and manually logged food
+const (
+	NutrientCalories      = "Calories"
+	NutrientProtein       = "Protein"
+	NutrientFat           = "Fat"
+	NutrientSaturatedFat  = "Saturated Fat"
+	NutrientCarbohydrates = "Carbohydrates"
+	NutrientFiber         = "Fiber"
+	NutrientSugar         = "Sugar"
+)
+
 // FoodIntake represents a food intake record in the database
 type FoodIntake struct {
-	ID          string        `bson:"_id,omitempty" json:"_id,omitempty"`
-	UserID      bson.ObjectID `bson:"users" json:"users"`
-	FoodName    string        `bson:"foodName" json:"foodName"`
-	Nutrients   []Nutrient    `bson:"nutrients" json:"nutrients"`
-	Date        time.Time     `bson:"date" json:"date"`
-	Ingredients []string      `bson:"ingredients" json:"ingredients"`
-	MealType    string        `bson:"mealType" json:"mealType"`
-	ImageUrl    string        `bson:"imageUrl" json:"imageUrl,omitempty"`
-	ImagePath   string        `bson:"imagePath" json:"imagePath,omitempty"`
-	Status      bool          `bson:"status" json:"status"`
-	CreatedAt   time.Time     `bson:"createdAt" json:"createdAt"`
-	UpdatedAt   time.Time     `bson:"updatedAt" json:"updatedAt"`
+	ID               bson.ObjectID       `bson:"_id,omitempty" json:"_id,omitempty"`
+	UserID           bson.ObjectID       `bson:"users" json:"users"`
+	FoodName         string              `bson:"foodName" json:"foodName"`
+	Nutrients        []Nutrient          `bson:"nutrients" json:"nutrients"`
+	Date             time.Time           `bson:"date" json:"date"`
+	Ingredients      []string            `bson:"ingredients" json:"ingredients"`
+	MealType         string              `bson:"mealType" json:"mealType"`
+	ImageUrl         string              `bson:"imageUrl" json:"imageUrl,omitempty"`
+	ImagePath        string              `bson:"imagePath" json:"imagePath,omitempty"`
+	Status           bool                `bson:"status" json:"status"`
+	AnalysisStatus   string              `bson:"analysisStatus" json:"analysisStatus"` // see FoodAnalysis* constants
+	AnalysisError    string              `bson:"analysisError" json:"analysisError,omitempty"`
+	AnalysisAttempts int                 `bson:"analysisAttempts" json:"analysisAttempts"`
+	Original         *FoodAnalysisResult `bson:"original,omitempty" json:"original,omitempty"` // analyzer output, kept when the user corrects the entry
+	Portion          float64             `bson:"portion" json:"portion"`                       // multiplier applied to the base serving
+	CorrectedAt      *time.Time          `bson:"correctedAt" json:"correctedAt,omitempty"`
+	CreatedAt        time.Time           `bson:"createdAt" json:"createdAt"`
+	UpdatedAt        time.Time           `bson:"updatedAt" json:"updatedAt"`
 }
+
+// Nutrient is an amount of one nutrient, stored in g, or kcal for calories
 type Nutrient struct {
-	Name   string  `json:"name"`
+	Name   string  `json:"name" binding:"required"`
+	Amount float64 `json:"amount" binding:"gte=0"`
+	Unit   string  `json:"unit"`
+}
+
+// ErrNutrientUnit is returned for a nutrient amount in an unknown or unsuitable unit
+var ErrNutrientUnit = errors.New("invalid nutrient unit")
+
+// Normalize converts the amount to grams, or kilocalories for calories. An
+// empty unit is taken to be the stored one already.
+func (n *Nutrient) Normalize() error {
+	unit := units.Gram
+	if strings.EqualFold(n.Name, NutrientCalories) {
+		unit = units.Kilocalorie
+	}
+	if n.Unit == "" {
+		n.Unit = unit
+		return nil
+	}
+	if !units.SameDimension(n.Unit, unit) {
+		return fmt.Errorf("%w: %s cannot be given in %q", ErrNutrientUnit, n.Name, n.Unit)
+	}
+	amount, err := units.Convert(n.Amount, n.Unit, unit)
+	if err != nil {
+		return err
+	}
+	n.Amount, n.Unit = amount, unit
+	return nil
+}
+
+// FoodAnalysisResult is what the analyzer reported for a food image
+type FoodAnalysisResult struct {
+	FoodName    string     `bson:"foodName" json:"foodName"`
+	Ingredients []string   `bson:"ingredients" json:"ingredients"`
+	Nutrients   []Nutrient `bson:"nutrients" json:"nutrients"`
+}
+
+// FoodIntakeUpdate corrects a food intake. Absent fields are left unchanged.
+// Portion rescales every nutrient relative to the base serving; nutrient
+// amounts given in the same request are taken as-is after rescaling.
+type FoodIntakeUpdate struct {
+	FoodName    *string    `json:"foodName" binding:"omitempty,min=1"`
+	MealType    *string    `json:"mealType" binding:"omitempty,min=1"`
+	Date        *time.Time `json:"date"`
+	Ingredients *[]string  `json:"ingredients"`
+	Nutrients   []Nutrient `json:"nutrients" binding:"dive"`
+	Portion     *float64   `json:"portion" binding:"omitempty,gt=0"`
+}
+
+// NutritionQuery selects the food intake records aggregated into a summary
+type NutritionQuery struct {
+	UserID      bson.ObjectID
+	From        time.Time // inclusive
+	To          time.Time // exclusive
+	Granularity string    // "day" or "week"
+	Timezone    string    // IANA zone the periods are cut in
+	WeekStart   time.Weekday
+}
+
+// NutritionBucket is the total of one nutrient for one meal type in one period
+type NutritionBucket struct {
+	Period   time.Time `bson:"period" json:"period"`
+	MealType string    `bson:"meal_type" json:"meal_type"`
+	Nutrient string    `bson:"nutrient" json:"nutrient"`
+	Unit     string    `bson:"unit" json:"unit"`
+	Amount   float64   `bson:"amount" json:"amount"`
+	Entries  int       `bson:"entries" json:"entries"`
+}
+
+// NutrientTotal is an aggregated nutrient amount
+type NutrientTotal struct {
 	Amount float64 `json:"amount"`
 	Unit   string  `json:"unit"`
 }
+
+// MealBreakdown is the intake of one meal type within a period
+type MealBreakdown struct {
+	Entries   int                      `json:"entries"`
+	Nutrients map[string]NutrientTotal `json:"nutrients"`
+}
+
+// TargetProgress compares consumption with the user's target for a period
+type TargetProgress struct {
+	Nutrient  string  `json:"nutrient"`
+	Unit      string  `json:"unit"`
+	Target    float64 `json:"target"`
+	Consumed  float64 `json:"consumed"`
+	Remaining float64 `json:"remaining"`
+	Percent   float64 `json:"percent"`
+}
+
+// NutritionPeriod is the summary of one day or week
+type NutritionPeriod struct {
+	Start   time.Time                `json:"start"`
+	End     time.Time                `json:"end"`
+	Days    int                      `json:"days"`
+	Totals  map[string]NutrientTotal `json:"totals"`
+	Meals   map[string]MealBreakdown `json:"meals"`
+	Targets []TargetProgress         `json:"targets"`
+}
+
+// NutritionSummary is the response of the nutrition summary endpoint
+type NutritionSummary struct {
+	From        time.Time         `json:"from"`
+	To          time.Time         `json:"to"`
+	Granularity string            `json:"granularity"`
+	Timezone    string            `json:"timezone"`
+	WeekStart   string            `json:"week_start,omitempty"` // first day of weekly periods
+	Periods     []NutritionPeriod `json:"periods"`
+}
@@ -0,0 +1,47 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// JobState is the lifecycle state of a background job
+type JobState string
+
+const (
+	JobQueued    JobState = "queued"
+	JobRunning   JobState = "running"
+	JobSucceeded JobState = "succeeded"
+	JobFailed    JobState = "failed" // failed at least once, waiting for a retry
+	JobDead      JobState = "dead"   // out of attempts, will not run again
+)
+
+// Job types handled by the worker pool
+const (
+	JobTypeFoodAnalysis = "food_analysis"
+)
+
+// Job is a unit of background work persisted in the jobs collection
+type Job struct {
+	ID             bson.ObjectID `bson:"_id,omitempty" json:"id"`
+	Type           string        `bson:"type" json:"type"`
+	RefID          bson.ObjectID `bson:"ref_id" json:"ref_id"` // record the job operates on
+	State          JobState      `bson:"state" json:"state"`
+	Attempts       int           `bson:"attempts" json:"attempts"`
+	MaxAttempts    int           `bson:"max_attempts" json:"max_attempts"`
+	RunAt          time.Time     `bson:"run_at" json:"run_at"`
+	LeaseExpiresAt time.Time     `bson:"lease_expires_at,omitempty" json:"lease_expires_at,omitempty"`
+	LastError      string        `bson:"last_error,omitempty" json:"last_error,omitempty"`
+	CreatedAt      time.Time     `bson:"created_at" json:"created_at"`
+	UpdatedAt      time.Time     `bson:"updated_at" json:"updated_at"`
+	FinishedAt     time.Time     `bson:"finished_at,omitempty" json:"finished_at,omitempty"`
+}
+
+// JobQuery selects jobs for inspection
+type JobQuery struct {
+	States []JobState
+	Type   string
+	Limit  int
+	Offset int
+}
@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// LoginFailures counts recent failed logins for one key, either an account
+// email or a client IP. The record expires once failures stop for a while.
+type LoginFailures struct {
+	Key         string     `bson:"_id" json:"key"`
+	Count       int        `bson:"count" json:"count"`       // failures since the last lockout
+	Lockouts    int        `bson:"lockouts" json:"lockouts"` // lockouts before the record expires
+	LastAt      time.Time  `bson:"last_at" json:"last_at"`
+	LockedUntil *time.Time `bson:"locked_until" json:"locked_until,omitempty"`
+	ExpiresAt   time.Time  `bson:"expires_at" json:"expires_at"`
+}
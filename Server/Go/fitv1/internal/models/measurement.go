@@ -0,0 +1,155 @@
+package models
+
+import (
+	"time"
+
+	"github.com/AyushIIITU/virtualfit/internal/units"
+	"github.com/go-playground/validator/v10"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// Body metrics that can be logged and trended. The names match the JSON and
+// BSON fields of BodyMeasurement.
+const (
+	MetricWeight         = "weight"
+	MetricBodyFatPercent = "body_fat_percent"
+	MetricWaist          = "waist"
+	MetricHip            = "hip"
+	MetricChest          = "chest"
+	MetricArm            = "arm"
+	MetricNeck           = "neck"
+)
+
+// MetricQuantities maps each body metric to the kind of quantity it is
+var MetricQuantities = map[string]string{
+	MetricWeight:         units.BodyWeight,
+	MetricBodyFatPercent: units.Ratio,
+	MetricWaist:          units.Length,
+	MetricHip:            units.Length,
+	MetricChest:          units.Length,
+	MetricArm:            units.Length,
+	MetricNeck:           units.Length,
+}
+
+// BodyMeasurement is one time-stamped entry of a user's body measurements.
+// Only the metrics taken at that time are set.
+type BodyMeasurement struct {
+	ID             bson.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID         bson.ObjectID `bson:"user_id" json:"user_id"`
+	MeasuredAt     time.Time     `bson:"measured_at" json:"measured_at"`
+	Weight         *float64      `bson:"weight,omitempty" json:"weight,omitempty" unit:"body_weight"`
+	BodyFatPercent *float64      `bson:"body_fat_percent,omitempty" json:"body_fat_percent,omitempty"`
+	Waist          *float64      `bson:"waist,omitempty" json:"waist,omitempty" unit:"length"`
+	Hip            *float64      `bson:"hip,omitempty" json:"hip,omitempty" unit:"length"`
+	Chest          *float64      `bson:"chest,omitempty" json:"chest,omitempty" unit:"length"`
+	Arm            *float64      `bson:"arm,omitempty" json:"arm,omitempty" unit:"length"`
+	Neck           *float64      `bson:"neck,omitempty" json:"neck,omitempty" unit:"length"`
+	Note           string        `bson:"note,omitempty" json:"note,omitempty"`
+	Units          string        `bson:"-" json:"units,omitempty"` // system of the values in a response
+	CreatedAt      time.Time     `bson:"created_at" json:"created_at"`
+	UpdatedAt      time.Time     `bson:"updated_at" json:"updated_at"`
+}
+
+// Value returns the entry's value for a metric, or nil when it was not taken
+func (m *BodyMeasurement) Value(metric string) *float64 {
+	switch metric {
+	case MetricWeight:
+		return m.Weight
+	case MetricBodyFatPercent:
+		return m.BodyFatPercent
+	case MetricWaist:
+		return m.Waist
+	case MetricHip:
+		return m.Hip
+	case MetricChest:
+		return m.Chest
+	case MetricArm:
+		return m.Arm
+	case MetricNeck:
+		return m.Neck
+	}
+	return nil
+}
+
+// BodyMeasurementUpdate sets the metrics of a new entry, or changes an
+// existing one. Absent fields are left unchanged. New entries default to now.
+// Values are in the units named by Units, or the user's preferred units.
+type BodyMeasurementUpdate struct {
+	Units          string     `json:"units" validate:"omitempty,oneof=metric imperial"`
+	MeasuredAt     *time.Time `json:"measured_at"`
+	Weight         *float64   `json:"weight" validate:"omitnil,gt=0,lt=700" unit:"body_weight"`
+	BodyFatPercent *float64   `json:"body_fat_percent" validate:"omitnil,gt=2,lt=70"`
+	Waist          *float64   `json:"waist" validate:"omitnil,gt=0,lt=400" unit:"length"`
+	Hip            *float64   `json:"hip" validate:"omitnil,gt=0,lt=400" unit:"length"`
+	Chest          *float64   `json:"chest" validate:"omitnil,gt=0,lt=400" unit:"length"`
+	Arm            *float64   `json:"arm" validate:"omitnil,gt=0,lt=200" unit:"length"`
+	Neck           *float64   `json:"neck" validate:"omitnil,gt=0,lt=200" unit:"length"`
+	Note           *string    `json:"note" validate:"omitnil,max=500"`
+}
+
+// Validate checks the values, which must be in metric units by now
+func (u *BodyMeasurementUpdate) Validate() error {
+	validate := validator.New()
+	return validate.Struct(u)
+}
+
+// Apply merges the update into an entry
+func (u *BodyMeasurementUpdate) Apply(m *BodyMeasurement) {
+	setField(&m.MeasuredAt, u.MeasuredAt)
+	setField(&m.Note, u.Note)
+	for _, p := range []struct{ dst, value **float64 }{
+		{&m.Weight, &u.Weight},
+		{&m.BodyFatPercent, &u.BodyFatPercent},
+		{&m.Waist, &u.Waist},
+		{&m.Hip, &u.Hip},
+		{&m.Chest, &u.Chest},
+		{&m.Arm, &u.Arm},
+		{&m.Neck, &u.Neck},
+	} {
+		if *p.value != nil {
+			v := **p.value
+			*p.dst = &v
+		}
+	}
+}
+
+// MeasurementQuery selects a user's body measurements, newest first
+type MeasurementQuery struct {
+	UserID bson.ObjectID
+	Metric string    // only entries where this metric was taken, if set
+	From   time.Time // inclusive, if set
+	To     time.Time // exclusive, if set
+	Limit  int
+	Offset int
+}
+
+// TrendPoint is one logged value with its moving average
+type TrendPoint struct {
+	MeasuredAt    time.Time `json:"measured_at"`
+	Value         float64   `json:"value"`
+	MovingAverage float64   `json:"moving_average"`
+}
+
+// Goal states of a measurement trend
+const (
+	GoalReached  = "reached"
+	GoalOnTrack  = "on_track"
+	GoalOffTrack = "off_track"
+)
+
+// MeasurementTrend is the response of the measurement trend endpoint
+type MeasurementTrend struct {
+	Metric     string       `json:"metric"`
+	Unit       string       `json:"unit"`
+	From       time.Time    `json:"from"`
+	To         time.Time    `json:"to"`
+	WindowDays int          `json:"window_days"`
+	Points     []TrendPoint `json:"points"`
+	// Current is the latest moving average
+	Current *float64 `json:"current"`
+	// WeeklyRate is the change per week over the last four weeks of the range
+	WeeklyRate        *float64   `json:"weekly_rate"`
+	Goal              *float64   `json:"goal,omitempty"`
+	GoalStatus        string     `json:"goal_status,omitempty"` // see Goal* constants
+	ProjectedGoalDate *time.Time `json:"projected_goal_date,omitempty"`
+}
@@ -0,0 +1,197 @@
+package models
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// ProfileUpdate is a JSON Merge Patch (RFC 7396) of the editable profile
+// fields. Absent fields are left alone and null removes a field's value.
+// The rules match UserRegister; required fields cannot be removed.
+type ProfileUpdate struct {
+	Name                   *string    `json:"name" validate:"omitnil,required"`
+	DOB                    *time.Time `json:"dob" validate:"omitnil,required"`
+	Gender                 *string    `json:"gender" validate:"omitnil,required,oneof=Male Female Other"`
+	Height                 *float64   `json:"height" validate:"omitnil,required,gt=0" unit:"length"`
+	Weight                 *float64   `json:"weight" validate:"omitnil,required,gt=0" unit:"body_weight"`
+	BodyFatPercent         *float64   `json:"body_fat_percent" validate:"omitnil,gt=2,lt=70"`
+	GoalWeight             *float64   `json:"goal_weight" validate:"omitnil,gt=0,lt=700" unit:"body_weight"`
+	Units                  *string    `json:"units" validate:"omitnil,oneof=metric imperial"`
+	OneRepMaxFormula       *string    `json:"one_rep_max_formula" validate:"omitnil,oneof=epley brzycki lombardi"`
+	Region                 *string    `json:"region" validate:"omitnil,required"`
+	Timezone               *string    `json:"timezone" validate:"omitnil,timezone"`
+	Locale                 *string    `json:"locale" validate:"omitnil,bcp47_language_tag"`
+	Goals                  *[]string  `json:"goals" validate:"omitnil,required,min=1,dive,required"`
+	DietaryRestrictions    *[]string  `json:"dietary_restrictions"`
+	DailyCalorieIntake     *int       `json:"daily_calorie_intake" validate:"omitnil,required,min=1000,max=5000"`
+	DailyProteinIntake     *int       `json:"daily_protein_intake" validate:"omitnil,required,min=30,max=500"`
+	DailyCarbIntake        *int       `json:"daily_carb_intake" validate:"omitnil,min=0,max=1000"`
+	DailyFatIntake         *int       `json:"daily_fat_intake" validate:"omitnil,min=0,max=500"`
+	AutoTargets            *bool      `json:"auto_targets"`
+	FoodsToAvoid           *[]string  `json:"foods_to_avoid"`
+	PreferredMealFrequency *int       `json:"preferred_meal_frequency" validate:"omitnil,required,min=1,max=6"`
+	CurrentFitnessLevel    *string    `json:"current_fitness_level" validate:"omitnil,required,oneof=Beginner Intermediate Advanced"`
+	HealthConsiderations   *[]string  `json:"health_considerations"`
+	InterestedActivities   *[]string  `json:"interested_activities"`
+	Equipment              *[]string  `json:"equipment" validate:"omitnil,dive,min=1"`
+	DaysPerWeek            *int       `json:"days_per_week" validate:"omitnil,required,min=1,max=7"`
+	MedicalConditions      *[]string  `json:"medical_conditions"`
+	FoodAllergies          *[]string  `json:"food_allergies"`
+
+	// Removed lists the fields the patch set to null
+	Removed []string `json:"-"`
+}
+
+// protectedProfileFields are User fields that only their own flows may change
+var protectedProfileFields = []string{
+	"id", "email", "password", "password_hash", "email_verified", "role", "disabled",
+	"password_reset_required", "mfa_enabled", "exercises", "food_album", "created_at", "updated_at",
+}
+
+// ErrInvalidPatch is returned for a profile update that is not a JSON object
+var ErrInvalidPatch = errors.New("profile update must be a JSON object")
+
+// profileFields maps the JSON name of each editable field to whether it is required
+var profileFields = func() map[string]bool {
+	fields := make(map[string]bool)
+	t := reflect.TypeOf(ProfileUpdate{})
+	for i := 0; i < t.NumField(); i++ {
+		name := strings.Split(t.Field(i).Tag.Get("json"), ",")[0]
+		if name == "-" {
+			continue
+		}
+		rules := strings.Split(t.Field(i).Tag.Get("validate"), ",")
+		fields[name] = slices.Contains(rules, "required")
+	}
+	return fields
+}()
+
+// UnmarshalJSON decodes a merge patch, rejecting protected and unknown fields
+func (p *ProfileUpdate) UnmarshalJSON(data []byte) error {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil || raw == nil {
+		return ErrInvalidPatch
+	}
+
+	var removed []string
+	for name, value := range raw {
+		required, editable := profileFields[name]
+		switch {
+		case !editable && slices.Contains(protectedProfileFields, name):
+			return fmt.Errorf("%s cannot be changed through a profile update", name)
+		case !editable:
+			return fmt.Errorf("unknown profile field %s", name)
+		case bytes.Equal(bytes.TrimSpace(value), []byte("null")):
+			if required {
+				return fmt.Errorf("%s is required and cannot be removed", name)
+			}
+			removed = append(removed, name)
+		}
+	}
+	sort.Strings(removed)
+
+	// Decode through a type without this method to fill the pointer fields
+	type plain ProfileUpdate
+	if err := json.Unmarshal(data, (*plain)(p)); err != nil {
+		return err
+	}
+	p.Removed = removed
+	return nil
+}
+
+// Validate checks the fields present in the patch
+func (p *ProfileUpdate) Validate() error {
+	validate := validator.New()
+	return validate.Struct(p)
+}
+
+// SetsTargets reports whether the patch sets any daily nutrition target
+func (p *ProfileUpdate) SetsTargets() bool {
+	return p.DailyCalorieIntake != nil || p.DailyProteinIntake != nil ||
+		p.DailyCarbIntake != nil || p.DailyFatIntake != nil
+}
+
+// Apply merges the patch into a user
+func (p *ProfileUpdate) Apply(u *User) {
+	setField(&u.Name, p.Name)
+	setField(&u.DOB, p.DOB)
+	setField(&u.Gender, p.Gender)
+	setField(&u.Height, p.Height)
+	setField(&u.Weight, p.Weight)
+	setField(&u.BodyFatPercent, p.BodyFatPercent)
+	setField(&u.GoalWeight, p.GoalWeight)
+	setField(&u.Units, p.Units)
+	setField(&u.OneRepMaxFormula, p.OneRepMaxFormula)
+	setField(&u.Region, p.Region)
+	setField(&u.Timezone, p.Timezone)
+	setField(&u.Locale, p.Locale)
+	setField(&u.Goals, p.Goals)
+	setField(&u.DietaryRestrictions, p.DietaryRestrictions)
+	setField(&u.DailyCalorieIntake, p.DailyCalorieIntake)
+	setField(&u.DailyProteinIntake, p.DailyProteinIntake)
+	setField(&u.DailyCarbIntake, p.DailyCarbIntake)
+	setField(&u.DailyFatIntake, p.DailyFatIntake)
+	setField(&u.AutoTargets, p.AutoTargets)
+	setField(&u.FoodsToAvoid, p.FoodsToAvoid)
+	setField(&u.PreferredMealFrequency, p.PreferredMealFrequency)
+	setField(&u.CurrentFitnessLevel, p.CurrentFitnessLevel)
+	setField(&u.HealthConsiderations, p.HealthConsiderations)
+	setField(&u.InterestedActivities, p.InterestedActivities)
+	setField(&u.Equipment, p.Equipment)
+	setField(&u.DaysPerWeek, p.DaysPerWeek)
+	setField(&u.MedicalConditions, p.MedicalConditions)
+	setField(&u.FoodAllergies, p.FoodAllergies)
+
+	// Only optional fields can be removed
+	for _, name := range p.Removed {
+		switch name {
+		case "body_fat_percent":
+			u.BodyFatPercent = 0
+		case "goal_weight":
+			u.GoalWeight = 0
+		case "units":
+			u.Units = ""
+		case "one_rep_max_formula":
+			u.OneRepMaxFormula = ""
+		case "timezone":
+			u.Timezone = ""
+		case "locale":
+			u.Locale = ""
+		case "daily_carb_intake":
+			u.DailyCarbIntake = 0
+		case "daily_fat_intake":
+			u.DailyFatIntake = 0
+		case "auto_targets":
+			u.AutoTargets = false
+		case "dietary_restrictions":
+			u.DietaryRestrictions = nil
+		case "foods_to_avoid":
+			u.FoodsToAvoid = nil
+		case "health_considerations":
+			u.HealthConsiderations = nil
+		case "interested_activities":
+			u.InterestedActivities = nil
+		case "equipment":
+			u.Equipment = nil
+		case "medical_conditions":
+			u.MedicalConditions = nil
+		case "food_allergies":
+			u.FoodAllergies = nil
+		}
+	}
+}
+
+func setField[T any](dst *T, value *T) {
+	if value != nil {
+		*dst = *value
+	}
+}
@@ -0,0 +1,235 @@
+package models
+
+import (
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// Program is a week of training days, repeated for a number of weeks
+type Program struct {
+	ID        bson.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID    bson.ObjectID `bson:"user_id" json:"user_id"`
+	Name      string        `bson:"name" json:"name"`
+	Goal      string        `bson:"goal,omitempty" json:"goal,omitempty"`   // strength, hypertrophy, endurance, fat_loss, recomposition or general
+	Split     string        `bson:"split,omitempty" json:"split,omitempty"` // full_body, upper_lower or push_pull_legs when generated
+	Weeks     int           `bson:"weeks" json:"weeks"`
+	Days      []ProgramDay  `bson:"days" json:"days"`
+	Notes     []string      `bson:"notes,omitempty" json:"notes,omitempty"` // left by the generator
+	Generated bool          `bson:"generated" json:"generated"`
+	// Progression is nil for programs saved before it existed, which
+	// progress by the defaults
+	Progression *Progression `bson:"progression,omitempty" json:"progression,omitempty"`
+	Units       string       `bson:"-" json:"units,omitempty"` // system of the values in a response
+	CreatedAt   time.Time    `bson:"created_at" json:"created_at"`
+	UpdatedAt   time.Time    `bson:"updated_at" json:"updated_at"`
+}
+
+// Progression is how the loads and reps of a program's exercises move from
+// session to session
+type Progression struct {
+	Strategy      string  `bson:"strategy" json:"strategy"`               // double, linear or rpe
+	Increment     float64 `bson:"increment" json:"increment" unit:"load"` // load added at a time
+	DeloadAfter   int     `bson:"deload_after" json:"deload_after"`       // stalled sessions in a row
+	DeloadPercent float64 `bson:"deload_percent" json:"deload_percent"`   // load taken off for a deload
+	TargetRPE     float64 `bson:"target_rpe" json:"target_rpe"`           // for rpe
+}
+
+// ProgressionUpdate changes a program's progression. Absent fields are left
+// unchanged.
+type ProgressionUpdate struct {
+	Strategy      *string  `json:"strategy" validate:"omitnil,oneof=double linear rpe"`
+	Increment     *float64 `json:"increment" validate:"omitnil,gt=0,max=50" unit:"load"`
+	DeloadAfter   *int     `json:"deload_after" validate:"omitnil,min=1,max=10"`
+	DeloadPercent *float64 `json:"deload_percent" validate:"omitnil,min=5,max=50"`
+	TargetRPE     *float64 `json:"target_rpe" validate:"omitnil,min=5,max=10"`
+}
+
+// Apply merges the update into a progression
+func (u *ProgressionUpdate) Apply(p *Progression) {
+	setField(&p.Strategy, u.Strategy)
+	setField(&p.Increment, u.Increment)
+	setField(&p.DeloadAfter, u.DeloadAfter)
+	setField(&p.DeloadPercent, u.DeloadPercent)
+	setField(&p.TargetRPE, u.TargetRPE)
+}
+
+// ProgramDay is one training day of a program's week
+type ProgramDay struct {
+	ID        bson.ObjectID     `bson:"_id" json:"id"`
+	Name      string            `bson:"name" json:"name"`
+	Focus     []string          `bson:"focus,omitempty" json:"focus,omitempty"` // muscle groups
+	Exercises []ProgramExercise `bson:"exercises" json:"exercises"`
+}
+
+// ProgramExercise is a catalog exercise of a day with its sets. Strength
+// exercises have a rep range; timed ones a duration.
+type ProgramExercise struct {
+	ID        bson.ObjectID `bson:"_id" json:"id"`
+	WorkoutID bson.ObjectID `bson:"workout_id" json:"workout_id"` // _id in the workout catalog
+	Sets      int           `bson:"sets" json:"sets"`
+	RepsMin   *int          `bson:"reps_min,omitempty" json:"reps_min,omitempty"`
+	RepsMax   *int          `bson:"reps_max,omitempty" json:"reps_max,omitempty"`
+	Duration  *int          `bson:"duration,omitempty" json:"duration,omitempty"` // seconds per set
+	Rest      *int          `bson:"rest,omitempty" json:"rest,omitempty"`         // seconds between sets
+	Notes     string        `bson:"notes,omitempty" json:"notes,omitempty"`
+}
+
+// ProgramExerciseInput is an exercise of a day in a request
+type ProgramExerciseInput struct {
+	WorkoutID bson.ObjectID `json:"workout_id" validate:"required"`
+	Sets      int           `json:"sets" validate:"min=1,max=20"`
+	RepsMin   *int          `json:"reps_min" validate:"omitnil,min=1,max=100"`
+	RepsMax   *int          `json:"reps_max" validate:"omitnil,min=1,max=100"`
+	Duration  *int          `json:"duration" validate:"omitnil,min=1,max=7200"`
+	Rest      *int          `json:"rest" validate:"omitnil,min=0,max=900"`
+	Notes     string        `json:"notes" validate:"max=500"`
+}
+
+// ProgramDayInput is a day of a program in a request
+type ProgramDayInput struct {
+	Name      string                 `json:"name" validate:"required,max=50"`
+	Focus     []string               `json:"focus" validate:"max=10,dive,required"`
+	Exercises []ProgramExerciseInput `json:"exercises" validate:"max=20,dive"`
+}
+
+// Day converts the input to a day with new IDs
+func (in *ProgramDayInput) Day() ProgramDay {
+	day := ProgramDay{ID: bson.NewObjectID(), Name: in.Name, Focus: in.Focus, Exercises: []ProgramExercise{}}
+	for _, e := range in.Exercises {
+		day.Exercises = append(day.Exercises, ProgramExercise{
+			ID:        bson.NewObjectID(),
+			WorkoutID: e.WorkoutID,
+			Sets:      e.Sets,
+			RepsMin:   e.RepsMin,
+			RepsMax:   e.RepsMax,
+			Duration:  e.Duration,
+			Rest:      e.Rest,
+			Notes:     e.Notes,
+		})
+	}
+	return day
+}
+
+// ProgramInput creates a program by hand. Values are in the units named by
+// Units, or the user's preferred units.
+type ProgramInput struct {
+	Name        string             `json:"name" validate:"required,max=100"`
+	Goal        string             `json:"goal" validate:"omitempty,oneof=strength hypertrophy endurance fat_loss recomposition general"`
+	Weeks       int                `json:"weeks" validate:"omitempty,min=1,max=52"` // defaults to 4
+	Days        []ProgramDayInput  `json:"days" validate:"required,min=1,max=7,dive"`
+	Progression *ProgressionUpdate `json:"progression"` // over the defaults
+	Units       string             `json:"units" validate:"omitempty,oneof=metric imperial"`
+}
+
+// Validate checks the values
+func (in *ProgramInput) Validate() error {
+	validate := validator.New()
+	return validate.Struct(in)
+}
+
+// ProgramUpdate changes a program. Days replace all the days of the program.
+// Absent fields are left unchanged. Values are in the units named by Units,
+// or the user's preferred units.
+type ProgramUpdate struct {
+	Name        *string            `json:"name" validate:"omitnil,required,max=100"`
+	Goal        *string            `json:"goal" validate:"omitnil,oneof=strength hypertrophy endurance fat_loss recomposition general"`
+	Weeks       *int               `json:"weeks" validate:"omitnil,min=1,max=52"`
+	Days        *[]ProgramDayInput `json:"days" validate:"omitnil,min=1,max=7,dive"`
+	Progression *ProgressionUpdate `json:"progression"`
+	Units       string             `json:"units" validate:"omitempty,oneof=metric imperial"`
+}
+
+// Validate checks the fields present in the update
+func (u *ProgramUpdate) Validate() error {
+	validate := validator.New()
+	return validate.Struct(u)
+}
+
+// Apply merges the update into a program
+func (u *ProgramUpdate) Apply(p *Program) {
+	setField(&p.Name, u.Name)
+	setField(&p.Goal, u.Goal)
+	setField(&p.Weeks, u.Weeks)
+	if u.Progression != nil {
+		if p.Progression == nil {
+			p.Progression = &Progression{}
+		}
+		u.Progression.Apply(p.Progression)
+	}
+	if u.Days != nil {
+		p.Days = []ProgramDay{}
+		for i := range *u.Days {
+			p.Days = append(p.Days, (*u.Days)[i].Day())
+		}
+	}
+}
+
+// ProgramGenerateRequest generates a program from the user's profile. Set
+// fields override the profile for this program only.
+type ProgramGenerateRequest struct {
+	Name        string    `json:"name" validate:"max=100"`
+	Weeks       int       `json:"weeks" validate:"omitempty,min=1,max=52"` // defaults to 4
+	DaysPerWeek int       `json:"days_per_week" validate:"omitempty,min=1,max=7"`
+	Goal        string    `json:"goal" validate:"omitempty,oneof=strength hypertrophy endurance fat_loss recomposition general"`
+	Equipment   *[]string `json:"equipment" validate:"omitnil,dive,min=1"`
+}
+
+// Validate checks the values
+func (r *ProgramGenerateRequest) Validate() error {
+	validate := validator.New()
+	return validate.Struct(r)
+}
+
+// NextSessionQuery selects the program day to recommend the next session of
+type NextSessionQuery struct {
+	UserID    bson.ObjectID
+	ProgramID bson.ObjectID // the user's newest program when zero
+	DayID     bson.ObjectID // the day after the one last trained when zero
+}
+
+// ProgramQuery selects a user's programs, newest first
+type ProgramQuery struct {
+	UserID bson.ObjectID
+	Limit  int
+	Offset int
+}
+
+// NextSession is the recommended dose of each exercise of a program day.
+// Loads are in the units named by Units.
+type NextSession struct {
+	ProgramID   bson.ObjectID  `json:"program_id"`
+	ProgramName string         `json:"program_name"`
+	DayID       bson.ObjectID  `json:"day_id"`
+	DayName     string         `json:"day_name"`
+	Progression Progression    `json:"progression"`
+	Units       string         `json:"units"`
+	Exercises   []NextExercise `json:"exercises"`
+}
+
+// NextExercise is the recommendation for one exercise of the day. Load is
+// null when there is no history to go by, and for timed exercises.
+type NextExercise struct {
+	ExerciseID bson.ObjectID    `json:"exercise_id"` // in the program day
+	WorkoutID  bson.ObjectID    `json:"workout_id"`
+	Name       string           `json:"name"`
+	Action     string           `json:"action"` // start, increase_load, add_reps, repeat, decrease_load or deload
+	Sets       int              `json:"sets"`
+	Reps       *int             `json:"reps,omitempty"`
+	Duration   *int             `json:"duration,omitempty"` // seconds per set
+	Load       *float64         `json:"load" unit:"load"`
+	TargetRPE  *float64         `json:"target_rpe,omitempty"`
+	Stalls     int              `json:"stalls"` // sessions in a row without progress
+	Last       *LastPerformance `json:"last,omitempty"`
+	Reason     string           `json:"reason"`
+}
+
+// LastPerformance is the heaviest working sets of the last session of an exercise
+type LastPerformance struct {
+	StartedAt time.Time `json:"started_at"`
+	Load      float64   `json:"load" unit:"load"`
+	Sets      int       `json:"sets"`
+	Reps      int       `json:"reps"` // fewest of those sets
+	RPE       *float64  `json:"rpe,omitempty"`
+}
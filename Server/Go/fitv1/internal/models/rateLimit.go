@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// RateBucket is the token bucket of one caller under one rate limit policy
+type RateBucket struct {
+	Key       string    `bson:"_id" json:"key"`
+	Tokens    float64   `bson:"tokens" json:"tokens"`
+	Allowed   bool      `bson:"allowed" json:"allowed"` // whether the last request got a token
+	UpdatedAt time.Time `bson:"updated_at" json:"updated_at"`
+	ExpiresAt time.Time `bson:"expires_at" json:"expires_at"` // when the bucket is full again
+}
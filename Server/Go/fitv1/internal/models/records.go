@@ -0,0 +1,61 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// PersonalRecord is a user's best of one kind for an exercise. Which value
+// fields are set depends on the kind: the lifted set for heaviest load and
+// most reps, the estimate for estimated 1RM and the volume for session volume.
+type PersonalRecord struct {
+	Kind         string         `json:"kind"` // see the kinds in package records
+	WorkoutID    bson.ObjectID  `json:"workout_id"`
+	SessionID    bson.ObjectID  `json:"session_id"`
+	SetID        *bson.ObjectID `json:"set_id,omitempty"`
+	AchievedAt   time.Time      `json:"achieved_at"`
+	Load         *float64       `json:"load,omitempty" unit:"load"`
+	Reps         *int           `json:"reps,omitempty"`
+	Estimated1RM *float64       `json:"estimated_1rm,omitempty" unit:"load"`
+	Volume       *float64       `json:"volume,omitempty" unit:"load"` // load times reps
+}
+
+// ExerciseRecords are the records of one catalog exercise
+type ExerciseRecords struct {
+	WorkoutID bson.ObjectID    `json:"workout_id"`
+	Name      string           `json:"name"`
+	Records   []PersonalRecord `json:"records"`
+}
+
+// PersonalRecords is the response of the records endpoint
+type PersonalRecords struct {
+	Formula   string            `json:"formula"`
+	Units     string            `json:"units"`
+	Exercises []ExerciseRecords `json:"exercises"`
+}
+
+// RecordSession is what one session of an exercise amounted to
+type RecordSession struct {
+	SessionID    bson.ObjectID `json:"session_id"`
+	StartedAt    time.Time     `json:"started_at"`
+	Sets         int           `json:"sets"` // working sets
+	Reps         int           `json:"reps"`
+	TopLoad      float64       `json:"top_load" unit:"load"`
+	Estimated1RM float64       `json:"estimated_1rm" unit:"load"`
+	Volume       float64       `json:"volume" unit:"load"`
+	// Records are the kinds of record set in the session
+	Records []string `json:"records"`
+}
+
+// RecordHistory is the response of the record history endpoint
+type RecordHistory struct {
+	WorkoutID bson.ObjectID `json:"workout_id"`
+	Name      string        `json:"name"`
+	Formula   string        `json:"formula"`
+	Units     string        `json:"units"`
+	// Sessions are oldest first
+	Sessions []RecordSession `json:"sessions"`
+	// Records are the best values within the range
+	Records []PersonalRecord `json:"records"`
+}
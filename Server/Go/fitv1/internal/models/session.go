@@ -0,0 +1,195 @@
+package models
+
+import (
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// WorkoutSession is one training session: the catalog exercises done, in
+// order, and the sets of each
+type WorkoutSession struct {
+	ID        bson.ObjectID  `bson:"_id,omitempty" json:"id"`
+	UserID    bson.ObjectID  `bson:"user_id" json:"user_id"`
+	Name      string         `bson:"name,omitempty" json:"name,omitempty"`
+	StartedAt time.Time      `bson:"started_at" json:"started_at"`
+	EndedAt   *time.Time     `bson:"ended_at,omitempty" json:"ended_at,omitempty"`
+	Notes     string         `bson:"notes,omitempty" json:"notes,omitempty"`
+	Entries   []SessionEntry `bson:"entries" json:"entries"`
+	Units     string         `bson:"-" json:"units,omitempty"` // system of the values in a response
+	// NewRecords are the personal records set by the sets a request logged
+	NewRecords []PersonalRecord `bson:"-" json:"new_records,omitempty"`
+	CreatedAt  time.Time        `bson:"created_at" json:"created_at"`
+	UpdatedAt  time.Time        `bson:"updated_at" json:"updated_at"`
+}
+
+// SessionEntry is one exercise of a session
+type SessionEntry struct {
+	ID        bson.ObjectID `bson:"_id" json:"id"`
+	WorkoutID bson.ObjectID `bson:"workout_id" json:"workout_id"` // _id in the workout catalog
+	Notes     string        `bson:"notes,omitempty" json:"notes,omitempty"`
+	Sets      []WorkoutSet  `bson:"sets" json:"sets"`
+}
+
+// WorkoutSet is one set of an exercise. Strength sets have reps and usually a
+// load; cardio and timed sets have a duration or distance.
+type WorkoutSet struct {
+	ID       bson.ObjectID `bson:"_id" json:"id"`
+	Reps     *int          `bson:"reps,omitempty" json:"reps,omitempty"`
+	Load     *float64      `bson:"load,omitempty" json:"load,omitempty" unit:"load"` // external weight
+	RPE      *float64      `bson:"rpe,omitempty" json:"rpe,omitempty"`               // rate of perceived exertion, 1-10
+	RIR      *int          `bson:"rir,omitempty" json:"rir,omitempty"`               // reps in reserve
+	Duration *int          `bson:"duration,omitempty" json:"duration,omitempty"`     // seconds
+	Distance *float64      `bson:"distance,omitempty" json:"distance,omitempty" unit:"distance"`
+	Rest     *int          `bson:"rest,omitempty" json:"rest,omitempty"` // seconds rested after the set
+	Warmup   bool          `bson:"warmup,omitempty" json:"warmup,omitempty"`
+}
+
+// Entry returns the session's entry with the given ID, or nil
+func (s *WorkoutSession) Entry(id bson.ObjectID) *SessionEntry {
+	for i := range s.Entries {
+		if s.Entries[i].ID == id {
+			return &s.Entries[i]
+		}
+	}
+	return nil
+}
+
+// Set returns the entry's set with the given ID, or nil
+func (e *SessionEntry) Set(id bson.ObjectID) *WorkoutSet {
+	for i := range e.Sets {
+		if e.Sets[i].ID == id {
+			return &e.Sets[i]
+		}
+	}
+	return nil
+}
+
+// WorkoutSetUpdate sets the values of a new set, or changes an existing one.
+// Absent fields are left unchanged.
+type WorkoutSetUpdate struct {
+	Reps     *int     `json:"reps" validate:"omitnil,min=0,max=1000"`
+	Load     *float64 `json:"load" validate:"omitnil,min=0,max=1000" unit:"load"`
+	RPE      *float64 `json:"rpe" validate:"omitnil,min=1,max=10"`
+	RIR      *int     `json:"rir" validate:"omitnil,min=0,max=10"`
+	Duration *int     `json:"duration" validate:"omitnil,min=0,max=86400"`
+	Distance *float64 `json:"distance" validate:"omitnil,min=0,max=1000000" unit:"distance"`
+	Rest     *int     `json:"rest" validate:"omitnil,min=0,max=3600"`
+	Warmup   *bool    `json:"warmup"`
+}
+
+// Validate checks the values, which must be in metric units by now
+func (u *WorkoutSetUpdate) Validate() error {
+	validate := validator.New()
+	return validate.Struct(u)
+}
+
+// Apply merges the update into a set
+func (u *WorkoutSetUpdate) Apply(set *WorkoutSet) {
+	setOptional(&set.Reps, u.Reps)
+	setOptional(&set.Load, u.Load)
+	setOptional(&set.RPE, u.RPE)
+	setOptional(&set.RIR, u.RIR)
+	setOptional(&set.Duration, u.Duration)
+	setOptional(&set.Distance, u.Distance)
+	setOptional(&set.Rest, u.Rest)
+	setField(&set.Warmup, u.Warmup)
+}
+
+// SessionEntryInput adds an exercise to a session
+type SessionEntryInput struct {
+	WorkoutID bson.ObjectID      `json:"workout_id" validate:"required"`
+	Notes     string             `json:"notes" validate:"max=500"`
+	Sets      []WorkoutSetUpdate `json:"sets" validate:"dive"`
+	// Position is where the entry goes in the session; it is appended when absent
+	Position *int `json:"position" validate:"omitnil,min=0"`
+}
+
+// Validate checks the values, which must be in metric units by now
+func (in *SessionEntryInput) Validate() error {
+	validate := validator.New()
+	return validate.Struct(in)
+}
+
+// SessionEntryUpdate changes an entry's notes or moves it within the session
+type SessionEntryUpdate struct {
+	Notes    *string `json:"notes" validate:"omitnil,max=500"`
+	Position *int    `json:"position" validate:"omitnil,min=0"`
+}
+
+// Validate checks the fields present in the update
+func (u *SessionEntryUpdate) Validate() error {
+	validate := validator.New()
+	return validate.Struct(u)
+}
+
+// WorkoutSessionInput starts a session, optionally with its exercises and
+// sets. Values are in the units named by Units, or the user's preferred units.
+type WorkoutSessionInput struct {
+	Units     string              `json:"units" validate:"omitempty,oneof=metric imperial"`
+	Name      string              `json:"name" validate:"max=100"`
+	StartedAt *time.Time          `json:"started_at"` // defaults to now
+	EndedAt   *time.Time          `json:"ended_at"`
+	Notes     string              `json:"notes" validate:"max=1000"`
+	Entries   []SessionEntryInput `json:"entries" validate:"dive"`
+}
+
+// Validate checks the values, which must be in metric units by now
+func (in *WorkoutSessionInput) Validate() error {
+	validate := validator.New()
+	return validate.Struct(in)
+}
+
+// WorkoutSessionUpdate changes a session's details. Absent fields are left unchanged.
+type WorkoutSessionUpdate struct {
+	Name      *string    `json:"name" validate:"omitnil,max=100"`
+	StartedAt *time.Time `json:"started_at"`
+	EndedAt   *time.Time `json:"ended_at"`
+	Notes     *string    `json:"notes" validate:"omitnil,max=1000"`
+}
+
+// Validate checks the fields present in the update
+func (u *WorkoutSessionUpdate) Validate() error {
+	validate := validator.New()
+	return validate.Struct(u)
+}
+
+// Apply merges the update into a session
+func (u *WorkoutSessionUpdate) Apply(s *WorkoutSession) {
+	setField(&s.Name, u.Name)
+	setField(&s.StartedAt, u.StartedAt)
+	setOptional(&s.EndedAt, u.EndedAt)
+	setField(&s.Notes, u.Notes)
+}
+
+// SessionEntryRequest is the body of a request adding an entry to a session.
+// Values are in the units named by Units, or the user's preferred units.
+type SessionEntryRequest struct {
+	Units string `json:"units"`
+	SessionEntryInput
+}
+
+// WorkoutSetRequest is the body of a request adding or changing a set
+type WorkoutSetRequest struct {
+	Units string `json:"units"`
+	WorkoutSetUpdate
+}
+
+// WorkoutSessionQuery selects a user's sessions, newest first
+type WorkoutSessionQuery struct {
+	UserID    bson.ObjectID
+	WorkoutID bson.ObjectID // only sessions with this catalog exercise, if set
+	From      time.Time     // inclusive, if set
+	To        time.Time     // exclusive, if set
+	Limit     int
+	Offset    int
+}
+
+// setOptional copies a value into an optional field
+func setOptional[T any](dst **T, value *T) {
+	if value != nil {
+		v := *value
+		*dst = &v
+	}
+}
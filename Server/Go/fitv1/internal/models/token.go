@@ -0,0 +1,88 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// RefreshToken is a stored refresh token. Only the hash of the token is kept.
+// Every rotation creates a new token in the same family; presenting a token
+// that was already rotated or revoked revokes the whole family.
+type RefreshToken struct {
+	ID              bson.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID          bson.ObjectID `bson:"user_id" json:"user_id"`
+	FamilyID        bson.ObjectID `bson:"family_id" json:"family_id"`
+	TokenHash       string        `bson:"token_hash" json:"-"`
+	AccessJTI       string        `bson:"access_jti" json:"-"` // access token issued alongside
+	AccessExpiresAt time.Time     `bson:"access_expires_at" json:"-"`
+	ExpiresAt       time.Time     `bson:"expires_at" json:"expires_at"`
+	CreatedAt       time.Time     `bson:"created_at" json:"created_at"`
+	RotatedAt       *time.Time    `bson:"rotated_at" json:"rotated_at,omitempty"`
+	RevokedAt       *time.Time    `bson:"revoked_at" json:"revoked_at,omitempty"`
+}
+
+// RevokedToken is a denylisted access token, kept until the token expires
+type RevokedToken struct {
+	JTI       string        `bson:"_id" json:"jti"`
+	UserID    bson.ObjectID `bson:"user_id" json:"user_id"`
+	ExpiresAt time.Time     `bson:"expires_at" json:"expires_at"`
+}
+
+// AuthTokens is returned on login and refresh
+type AuthTokens struct {
+	AccessToken  string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int    `json:"expires_in"` // access token lifetime in seconds
+}
+
+// RefreshRequest carries a refresh token
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+// One-time token purposes
+const (
+	TokenPurposePasswordReset     = "password_reset"
+	TokenPurposeEmailVerification = "email_verification"
+	TokenPurposeAccountUnlock     = "account_unlock"
+	TokenPurposeEmailChange       = "email_change"
+)
+
+// OneTimeToken is a single-use, expiring token sent by email. Only the hash of
+// the token is kept.
+type OneTimeToken struct {
+	ID        bson.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID    bson.ObjectID `bson:"user_id" json:"user_id"`
+	Purpose   string        `bson:"purpose" json:"purpose"`
+	TokenHash string        `bson:"token_hash" json:"-"`
+	Email     string        `bson:"email" json:"email"`                             // account address when issued
+	NewEmail  string        `bson:"new_email,omitempty" json:"new_email,omitempty"` // address an email change confirms
+	ExpiresAt time.Time     `bson:"expires_at" json:"expires_at"`
+	CreatedAt time.Time     `bson:"created_at" json:"created_at"`
+	UsedAt    *time.Time    `bson:"used_at" json:"used_at,omitempty"`
+}
+
+// ForgotPasswordRequest starts a password reset
+type ForgotPasswordRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// ResetPasswordRequest completes a password reset
+type ResetPasswordRequest struct {
+	Token    string `json:"token" binding:"required"`
+	Password string `json:"password" binding:"required,min=8"`
+}
+
+// PasswordChangeRequest changes the password of a logged-in user
+type PasswordChangeRequest struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+	NewPassword     string `json:"new_password" binding:"required,min=8"`
+}
+
+// EmailChangeRequest starts moving an account to a new email address
+type EmailChangeRequest struct {
+	NewEmail string `json:"new_email" binding:"required,email"`
+	Password string `json:"password" binding:"required"`
+}
@@ -1,32 +1,61 @@
 package models
 
 import (
+	"strings"
 	"time"
 
+	"github.com/AyushIIITU/virtualfit/internal/timeutil"
+	"github.com/AyushIIITU/virtualfit/internal/units"
 	"github.com/go-playground/validator/v10"
 	"go.mongodb.org/mongo-driver/v2/bson"
 )
 
+// Roles a user can hold. Accounts without a role are treated as RoleUser.
+const (
+	RoleUser  = "user"
+	RoleCoach = "coach"
+	RoleAdmin = "admin"
+)
+
 type User struct {
 	ID                     bson.ObjectID   `bson:"_id,omitempty" json:"id"`
 	Name                   string          `bson:"name" json:"name" validate:"required"`
 	Email                  string          `bson:"email" json:"email" validate:"required,email"`
-	PasswordHash           string          `bson:"password_hash" json:"password_hash,omitempty" validate:"required"`
+	PasswordHash           string          `bson:"password_hash" json:"-" validate:"required"`
+	EmailVerified          bool            `bson:"email_verified" json:"email_verified"`
+	Role                   string          `bson:"role" json:"role"`
+	Disabled               bool            `bson:"disabled" json:"disabled"`
+	PasswordResetRequired  bool            `bson:"password_reset_required" json:"password_reset_required"`
+	MFAEnabled             bool            `bson:"mfa_enabled" json:"mfa_enabled"`
+	MFASecret              string          `bson:"mfa_secret" json:"-"`         // sealed TOTP secret
+	MFAPendingSecret       string          `bson:"mfa_pending_secret" json:"-"` // sealed secret awaiting confirmation
+	MFARecoveryCodes       []string        `bson:"mfa_recovery_codes" json:"-"` // hashes of unused recovery codes
+	MFALastStep            int64           `bson:"mfa_last_step" json:"-"`      // last accepted TOTP time step
 	DOB                    time.Time       `bson:"dob" json:"dob" validate:"required"`
 	Gender                 string          `bson:"gender" json:"gender" validate:"required,oneof=Male Female Other"`
-	Height                 float64         `bson:"height" json:"height" validate:"required,gt=0"`
-	Weight                 float64         `bson:"weight" json:"weight" validate:"required,gt=0"`
+	Height                 float64         `bson:"height" json:"height" validate:"required,gt=0" unit:"length"`
+	Weight                 float64         `bson:"weight" json:"weight" validate:"required,gt=0" unit:"body_weight"`
+	BodyFatPercent         float64         `bson:"body_fat_percent,omitempty" json:"body_fat_percent,omitempty"`
+	GoalWeight             float64         `bson:"goal_weight,omitempty" json:"goal_weight,omitempty" unit:"body_weight"`
+	Units                  string          `bson:"units,omitempty" json:"units"`                                       // preferred unit system; metric when empty
+	OneRepMaxFormula       string          `bson:"one_rep_max_formula,omitempty" json:"one_rep_max_formula,omitempty"` // epley when empty
 	Region                 string          `bson:"region" json:"region" validate:"required"`
+	Timezone               string          `bson:"timezone,omitempty" json:"timezone,omitempty"` // IANA zone; Region hints one when empty
+	Locale                 string          `bson:"locale,omitempty" json:"locale,omitempty"`     // BCP 47 tag, e.g. en-IN
 	Goals                  []string        `bson:"goals" json:"goals" validate:"required,min=1,dive,required"`
 	DietaryRestrictions    []string        `bson:"dietary_restrictions" json:"dietary_restrictions"`
 	DailyCalorieIntake     int             `bson:"daily_calorie_intake" json:"daily_calorie_intake" validate:"required,min=1000,max=5000"`
 	DailyProteinIntake     int             `bson:"daily_protein_intake" json:"daily_protein_intake" validate:"required,min=30,max=500"`
+	DailyCarbIntake        int             `bson:"daily_carb_intake,omitempty" json:"daily_carb_intake,omitempty"`
+	DailyFatIntake         int             `bson:"daily_fat_intake,omitempty" json:"daily_fat_intake,omitempty"`
+	AutoTargets            bool            `bson:"auto_targets" json:"auto_targets"` // keep the daily targets at the calculator's recommendation
 	FoodsToAvoid           []string        `bson:"foods_to_avoid" json:"foods_to_avoid"`
 	Exercises              []bson.ObjectID `bson:"exercises" json:"exercises"`
 	PreferredMealFrequency int             `bson:"preferred_meal_frequency" json:"preferred_meal_frequency" validate:"required,min=1,max=6"`
 	CurrentFitnessLevel    string          `bson:"current_fitness_level" json:"current_fitness_level" validate:"required,oneof=Beginner Intermediate Advanced"`
 	HealthConsiderations   []string        `bson:"health_considerations" json:"health_considerations"`
 	InterestedActivities   []string        `bson:"interested_activities" json:"interested_activities"`
+	Equipment              []string        `bson:"equipment,omitempty" json:"equipment"` // available, as named in the catalog; any when empty
 	DaysPerWeek            int             `bson:"days_per_week" json:"days_per_week" validate:"required,min=1,max=7"`
 	MedicalConditions      []string        `bson:"medical_conditions" json:"medical_conditions"`
 	FoodAllergies          []string        `bson:"food_allergies" json:"food_allergies"`
@@ -36,24 +65,32 @@ type User struct {
 }
 
 type UserRegister struct {
-	Name                   string          `bson:"name" json:"name" validate:"required"`
-	Email                  string          `bson:"email" json:"email" validate:"required,email"`
-	Password               string          `bson:"password" json:"password,omitempty" validate:"required,min=8"`
-	DateOfBirth            time.Time       `bson:"dob" json:"dob" validate:"required"`
-	Gender                 string          `bson:"gender" json:"gender" validate:"required,oneof=Male Female Other"`
-	Height                 float64         `bson:"height" json:"height" validate:"required,gt=0"`
-	Weight                 float64         `bson:"weight" json:"weight" validate:"required,gt=0"`
-	Region                 string          `bson:"region" json:"region" validate:"required"`
-	Goals                  []string        `bson:"goals" json:"goals" validate:"required,min=1,dive,required"`
-	DietaryRestrictions    []string        `bson:"dietary_restrictions" json:"dietary_restrictions"`
-	DailyCalorieIntake     int             `bson:"daily_calorie_intake" json:"daily_calorie_intake" validate:"required,min=1000,max=5000"`
-	DailyProteinIntake     int             `bson:"daily_protein_intake" json:"daily_protein_intake" validate:"required,min=30,max=500"`
+	Name           string    `bson:"name" json:"name" validate:"required"`
+	Email          string    `bson:"email" json:"email" validate:"required,email"`
+	Password       string    `bson:"password" json:"password,omitempty" validate:"required,min=8"`
+	DateOfBirth    time.Time `bson:"dob" json:"dob" validate:"required"`
+	Gender         string    `bson:"gender" json:"gender" validate:"required,oneof=Male Female Other"`
+	Height         float64   `bson:"height" json:"height" validate:"required,gt=0" unit:"length"`
+	Weight         float64   `bson:"weight" json:"weight" validate:"required,gt=0" unit:"body_weight"`
+	BodyFatPercent float64   `bson:"body_fat_percent" json:"body_fat_percent" validate:"omitempty,gt=2,lt=70"`
+	// Units is the preferred unit system, which height and weight are given in
+	Units               string   `bson:"units" json:"units" validate:"omitempty,oneof=metric imperial"`
+	Region              string   `bson:"region" json:"region" validate:"required"`
+	Timezone            string   `bson:"timezone" json:"timezone" validate:"omitempty,timezone"`
+	Locale              string   `bson:"locale" json:"locale" validate:"omitempty,bcp47_language_tag"`
+	Goals               []string `bson:"goals" json:"goals" validate:"required,min=1,dive,required"`
+	DietaryRestrictions []string `bson:"dietary_restrictions" json:"dietary_restrictions"`
+	DailyCalorieIntake  int      `bson:"daily_calorie_intake" json:"daily_calorie_intake" validate:"required_unless=AutoTargets true,omitempty,min=1000,max=5000"`
+	DailyProteinIntake  int      `bson:"daily_protein_intake" json:"daily_protein_intake" validate:"required_unless=AutoTargets true,omitempty,min=30,max=500"`
+	// AutoTargets fills in the daily targets from the calculator instead
+	AutoTargets            bool            `bson:"auto_targets" json:"auto_targets"`
 	FoodsToAvoid           []string        `bson:"foods_to_avoid" json:"foods_to_avoid"`
 	Exercises              []bson.ObjectID `bson:"exercises" json:"exercises"`
 	PreferredMealFrequency int             `bson:"preferred_meal_frequency" json:"preferred_meal_frequency" validate:"required,min=1,max=6"`
 	CurrentFitnessLevel    string          `bson:"current_fitness_level" json:"current_fitness_level" validate:"required,oneof=Beginner Intermediate Advanced"`
 	HealthConsiderations   []string        `bson:"health_considerations" json:"health_considerations"`
 	InterestedActivities   []string        `bson:"interested_activities" json:"interested_activities"`
+	Equipment              []string        `bson:"equipment" json:"equipment" validate:"dive,min=1"`
 	DaysPerWeek            int             `bson:"days_per_week" json:"days_per_week" validate:"required,min=1,max=7"`
 	MedicalConditions      []string        `bson:"medical_conditions" json:"medical_conditions"`
 	FoodAllergies          []string        `bson:"food_allergies" json:"food_allergies"`
@@ -68,10 +105,11 @@ type DietPlanData struct {
 	ID                     bson.ObjectID `bson:"_id,omitempty" json:"id"`
 	Name                   string        `json:"name"`
 	Email                  string        `json:"email"`
-	Age                    int     `json:"Age"`
+	Age                    int           `json:"Age"`
 	Gender                 string        `json:"gender"`
-	Height                 float64       `json:"height"`
-	Weight                 float64       `json:"weight"`
+	Height                 float64       `json:"height" unit:"length"`
+	Weight                 float64       `json:"weight" unit:"body_weight"`
+	Units                  string        `json:"units"`
 	Goals                  []string      `json:"goals"`
 	DietaryRestrictions    []string      `json:"dietary_restrictions"`
 	DailyCalorieIntake     int           `json:"daily_calorie_intake"`
@@ -86,6 +124,94 @@ type DietPlanData struct {
 	PreferredMealFrequency int           `json:"preferred_meal_frequency"`
 }
 
+// NormalizeEmail is the form emails are stored and looked up in, so addresses
+// that differ only in case or surrounding spaces are the same account
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// Age returns the user's age in whole years at now
+func (u *User) Age(now time.Time) int {
+	age := now.Year() - u.DOB.Year()
+	if now.Month() < u.DOB.Month() || now.Month() == u.DOB.Month() && now.Day() < u.DOB.Day() {
+		age--
+	}
+	return age
+}
+
+// UnitSystem returns the user's preferred unit system, defaulting to metric
+func (u *User) UnitSystem() string {
+	if u.Units == units.Imperial {
+		return units.Imperial
+	}
+	return units.Metric
+}
+
+// Location returns the zone the user's days are cut in: their timezone, else
+// the usual zone of their region, else UTC
+func (u *User) Location() *time.Location {
+	if u.Timezone != "" {
+		if loc, err := time.LoadLocation(u.Timezone); err == nil {
+			return loc
+		}
+	}
+	if loc, ok := timeutil.RegionLocation(u.Region); ok {
+		return loc
+	}
+	return time.UTC
+}
+
+// WeekStart returns the day the user's weeks start on, by the country of
+// their locale, else their region
+func (u *User) WeekStart() time.Weekday {
+	code := timeutil.LocaleRegion(u.Locale)
+	if code == "" {
+		code = timeutil.RegionCode(u.Region)
+	}
+	return timeutil.FirstWeekday(code)
+}
+
+// Preferences returns how the user's data is presented
+func (u *User) Preferences() Preferences {
+	return Preferences{Units: u.UnitSystem(), Location: u.Location(), WeekStart: u.WeekStart()}
+}
+
+// Preferences are the settings that shape how a user's values and dates are
+// read and reported
+type Preferences struct {
+	Units     string
+	Location  *time.Location
+	WeekStart time.Weekday
+}
+
+// EffectiveRole returns the user's role, defaulting to RoleUser
+func (u *User) EffectiveRole() string {
+	if u.Role == "" {
+		return RoleUser
+	}
+	return u.Role
+}
+
+// UserSearchCriteria defines the parameters for searching users
+type UserSearchCriteria struct {
+	Query    string // matches name or email
+	Role     string
+	Disabled *bool
+	Limit    int
+	Offset   int
+}
+
+// RoleUpdateRequest changes a user's role
+type RoleUpdateRequest struct {
+	Role string `json:"role" binding:"required,oneof=user coach admin"`
+}
+
+// Validate checks a registration, which must be in metric units by now
+func (r *UserRegister) Validate() error {
+	validate := validator.New()
+	return validate.Struct(r)
+}
+
 func (u *User) Validate() error {
 	validate := validator.New()
 	return validate.Struct(u)
@@ -22,6 +22,7 @@ type Workout struct {
 
 // Create a response structure that converts ObjectID to string
 type WorkoutResponse struct {
+	ObjectID         string   `json:"_id"` // what workout sessions reference
 	ID               string   `json:"id"`
 	Name             string   `json:"name"`
 	Force            string   `json:"force"`
@@ -37,13 +38,28 @@ type WorkoutResponse struct {
 
 // WorkoutSearchCriteria defines the parameters for searching workouts
 type WorkoutSearchCriteria struct {
-	Name       string
-	Category   string
-	Level      string
-	Equipment  string
-	Force      string
-	Mechanic   string
-	Muscle     string
-	Limit      int
-	Offset     int
+	Name      string
+	Category  string
+	Level     string
+	Equipment string
+	Force     string
+	Mechanic  string
+	Muscle    string
+	Limit     int
+	Offset    int
+}
+
+// WorkoutInput creates or replaces a catalog workout
+type WorkoutInput struct {
+	Slug             string   `json:"id"` // defaults to the name with spaces replaced by underscores
+	Name             string   `json:"name" binding:"required"`
+	Force            string   `json:"force"`
+	Level            string   `json:"level" binding:"required"`
+	Mechanic         string   `json:"mechanic"`
+	Equipment        string   `json:"equipment"`
+	PrimaryMuscles   []string `json:"primaryMuscles" binding:"required,min=1,dive,required"`
+	SecondaryMuscles []string `json:"secondaryMuscles"`
+	Instructions     []string `json:"instructions"`
+	Category         string   `json:"category" binding:"required"`
+	Images           []string `json:"images"`
 }
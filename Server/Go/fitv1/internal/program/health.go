@@ -0,0 +1,114 @@
+package program
+
+import (
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// healthRule excludes the exercises that load an injured or sensitive area.
+// It applies to health considerations mentioning any of its keywords.
+type healthRule struct {
+	keywords   []string
+	muscles    []string // primary muscles to leave alone
+	categories []string
+	names      []string // words in exercise names
+}
+
+// healthRules are deliberately conservative; a program is no substitute for
+// medical advice
+var healthRules = []healthRule{
+	{
+		keywords:   []string{"knee", "acl", "meniscus", "patella"},
+		categories: []string{"plyometrics"},
+		names:      []string{"jump", "lunge", "pistol", "step-up", "box"},
+	},
+	{
+		keywords:   []string{"back", "backache", "spine", "spinal", "disc", "sciatica", "lumbar"},
+		muscles:    []string{"lower back"},
+		categories: []string{"olympic weightlifting", "strongman"},
+		names:      []string{"deadlift", "good morning", "barbell squat", "bent over"},
+	},
+	{
+		keywords:   []string{"shoulder", "rotator", "impingement"},
+		categories: []string{"olympic weightlifting"},
+		names:      []string{"overhead", "military press", "upright row", "behind the neck", "dip"},
+	},
+	{
+		keywords: []string{"wrist", "carpal"},
+		names:    []string{"front squat", "clean", "handstand", "push-up", "pushup"},
+	},
+	{
+		keywords:   []string{"blood pressure", "hypertension", "heart", "cardiac"},
+		categories: []string{"olympic weightlifting", "strongman", "powerlifting"},
+	},
+	{
+		keywords:   []string{"pregnant", "pregnancy", "postpartum", "diastasis"},
+		muscles:    []string{"abdominals"},
+		categories: []string{"plyometrics", "olympic weightlifting", "strongman"},
+	},
+}
+
+// healthRulesFor returns the rules for some health considerations and the
+// considerations none covers
+func healthRulesFor(considerations []string) (rules []healthRule, unmatched []string) {
+	for _, consideration := range considerations {
+		text := strings.ToLower(consideration)
+		if strings.TrimSpace(text) == "" || mentions(text, "none", "no known") {
+			continue
+		}
+		matched := false
+		for _, rule := range healthRules {
+			if mentions(text, rule.keywords...) {
+				rules = append(rules, rule)
+				matched = true
+			}
+		}
+		if !matched {
+			unmatched = append(unmatched, consideration)
+		}
+	}
+	return rules, unmatched
+}
+
+// excluded reports whether any rule excludes an exercise
+func excluded(rules []healthRule, e Exercise) bool {
+	name := strings.ToLower(e.Name)
+	for _, rule := range rules {
+		if slices.Contains(rule.categories, e.Category) ||
+			slices.ContainsFunc(rule.muscles, func(m string) bool { return slices.Contains(e.Primary, m) }) ||
+			containsAny(name, rule.names...) {
+			return true
+		}
+	}
+	return false
+}
+
+// mentions reports whether text holds any of the keywords as whole words, so
+// "disc" is not found in "discomfort". The last word of a keyword may take a
+// plural ending: "knees" mentions "knee".
+func mentions(text string, keywords ...string) bool {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for _, keyword := range keywords {
+		want := strings.Fields(keyword)
+		for i := 0; i+len(want) <= len(words); i++ {
+			if wordsMatch(words[i:i+len(want)], want) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// wordsMatch reports whether words are want, allowing a plural last word
+func wordsMatch(words, want []string) bool {
+	for i, w := range want {
+		last := i == len(want)-1
+		if words[i] != w && !(last && (words[i] == w+"s" || words[i] == w+"es")) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,372 @@
+// Package program builds a weekly training split from a user's profile and
+// the workout catalog. It is rule based and deterministic: the same profile
+// and catalog always give the same program.
+//
+// The split follows the training days: full body up to three days, upper and
+// lower on four, and push, pull and legs on five or six. Each day is a list
+// of slots, one per muscle group, filled with the best catalog exercise the
+// user's level, equipment and health allow. Sets, reps and rest follow the
+// goal.
+package program
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/AyushIIITU/virtualfit/internal/goals"
+)
+
+// Splits
+const (
+	FullBody     = "full_body"
+	UpperLower   = "upper_lower"
+	PushPullLegs = "push_pull_legs"
+)
+
+// Goals a program is tuned for
+const (
+	GoalStrength    = "strength"
+	GoalHypertrophy = "hypertrophy"
+	GoalEndurance   = "endurance"
+	GoalFatLoss     = "fat_loss"
+	GoalRecomp      = "recomposition" // fat loss and muscle gain at once
+	GoalGeneral     = "general"
+)
+
+// MaxTrainingDays leaves at least one rest day in the week
+const MaxTrainingDays = 6
+
+// Profile is the input to the generator
+type Profile struct {
+	DaysPerWeek          int
+	FitnessLevel         string   // Beginner, Intermediate or Advanced
+	Goals                []string // free text, such as "build muscle"
+	InterestedActivities []string
+	HealthConsiderations []string // free text, such as "knee pain"
+	Equipment            []string // available, as named in the catalog; any when empty
+}
+
+// Exercise is a catalog entry
+type Exercise struct {
+	ID        string
+	Name      string
+	Level     string // beginner, intermediate or expert
+	Mechanic  string // compound or isolation
+	Equipment string
+	Category  string
+	Primary   []string // muscles
+}
+
+// Prescription is one exercise of a day. Timed exercises have a duration
+// instead of reps.
+type Prescription struct {
+	ExerciseID string
+	Sets       int
+	RepsMin    int
+	RepsMax    int
+	Duration   int // seconds
+	Rest       int // seconds between sets
+}
+
+// Day is one training day of the week
+type Day struct {
+	Name      string
+	Focus     []string // muscle groups
+	Exercises []Prescription
+}
+
+// Plan is a generated program
+type Plan struct {
+	Split string
+	Goal  string
+	Days  []Day
+	Notes []string // health considerations no rule covers, empty slots
+}
+
+// dose is the sets, reps and rest of one kind of exercise
+type dose struct {
+	sets, repsMin, repsMax, rest int
+}
+
+// goalDoses are the doses of compound and isolation exercises for each goal
+var goalDoses = map[string][2]dose{
+	GoalStrength:    {{5, 3, 5, 180}, {3, 6, 8, 90}},
+	GoalHypertrophy: {{4, 6, 10, 120}, {3, 10, 15, 60}},
+	GoalEndurance:   {{3, 12, 20, 45}, {2, 15, 20, 30}},
+	GoalFatLoss:     {{3, 10, 12, 60}, {3, 12, 15, 45}},
+	GoalRecomp:      {{4, 8, 12, 90}, {3, 10, 15, 45}},
+	GoalGeneral:     {{3, 8, 12, 90}, {3, 10, 15, 60}},
+}
+
+const (
+	holdSeconds   = 30      // per set of a static exercise such as a plank
+	cardioSeconds = 20 * 60 // of a conditioning finisher
+)
+
+// slot is a place in a day for an exercise working muscle, compound or
+// isolation when mechanic is set
+type slot struct {
+	muscle   string
+	mechanic string
+}
+
+// dayTemplates are the slots of each kind of day, most important first.
+// Beginners do the first five.
+var dayTemplates = map[string][]slot{
+	"Full Body": {{"quadriceps", "compound"}, {"chest", "compound"}, {"lats", "compound"}, {"hamstrings", ""}, {"shoulders", ""}, {"abdominals", ""}},
+	"Upper":     {{"chest", "compound"}, {"lats", "compound"}, {"shoulders", "compound"}, {"middle back", ""}, {"biceps", "isolation"}, {"triceps", "isolation"}},
+	"Lower":     {{"quadriceps", "compound"}, {"hamstrings", "compound"}, {"glutes", ""}, {"quadriceps", "isolation"}, {"calves", ""}, {"abdominals", ""}},
+	"Push":      {{"chest", "compound"}, {"shoulders", "compound"}, {"chest", ""}, {"triceps", "isolation"}, {"shoulders", "isolation"}, {"triceps", ""}},
+	"Pull":      {{"lats", "compound"}, {"middle back", "compound"}, {"lats", ""}, {"biceps", "isolation"}, {"traps", ""}, {"biceps", ""}},
+	"Legs":      {{"quadriceps", "compound"}, {"hamstrings", "compound"}, {"quadriceps", ""}, {"hamstrings", "isolation"}, {"calves", ""}, {"abdominals", ""}},
+}
+
+// beginnerSlots is how many slots of a day beginners do
+const beginnerSlots = 5
+
+// SplitOf picks the split and the names of the days for a number of
+// training days, capped at MaxTrainingDays
+func SplitOf(daysPerWeek int) (string, []string) {
+	days := max(1, min(daysPerWeek, MaxTrainingDays))
+	switch {
+	case days <= 3:
+		return FullBody, lettered("Full Body", days)
+	case days == 4:
+		return UpperLower, []string{"Upper A", "Lower A", "Upper B", "Lower B"}
+	case days == 5:
+		return PushPullLegs, []string{"Push A", "Pull A", "Legs A", "Push B", "Pull B"}
+	default:
+		return PushPullLegs, []string{"Push A", "Pull A", "Legs A", "Push B", "Pull B", "Legs B"}
+	}
+}
+
+// lettered names n days of a kind A, B, C...
+func lettered(kind string, n int) []string {
+	names := make([]string, n)
+	for i := range names {
+		names[i] = fmt.Sprintf("%s %c", kind, 'A'+i)
+	}
+	return names
+}
+
+// GoalOf reads free-text goals such as "get stronger" or "lose weight". The
+// first goal that matches, in the order strength, recomposition, hypertrophy,
+// endurance, fat loss, sets the program.
+func GoalOf(list []string) string {
+	intent := goals.Parse(list)
+	switch {
+	case intent.Strength:
+		return GoalStrength
+	case intent.Recomposition():
+		return GoalRecomp
+	case intent.Gain:
+		return GoalHypertrophy
+	case intent.Endurance:
+		return GoalEndurance
+	case intent.Lose:
+		return GoalFatLoss
+	}
+	return GoalGeneral
+}
+
+// ValidGoal reports whether goal is one the generator knows
+func ValidGoal(goal string) bool {
+	_, ok := goalDoses[goal]
+	return ok
+}
+
+// Generate builds a program for p from catalog with goal, or the goal read
+// from the profile when it is empty
+func Generate(p Profile, catalog []Exercise, goal string) Plan {
+	if goal == "" {
+		goal = GoalOf(p.Goals)
+	}
+	split, names := SplitOf(p.DaysPerWeek)
+	plan := Plan{Split: split, Goal: goal}
+	if p.DaysPerWeek > MaxTrainingDays {
+		plan.Notes = append(plan.Notes, fmt.Sprintf("training days are capped at %d to leave a rest day", MaxTrainingDays))
+	}
+
+	rules, unmatched := healthRulesFor(p.HealthConsiderations)
+	for _, consideration := range unmatched {
+		plan.Notes = append(plan.Notes, fmt.Sprintf("no rule covers the health consideration %q; check the exercises with a professional", consideration))
+	}
+	var allowed []Exercise
+	for _, e := range catalog {
+		if levelAllowed(p.FitnessLevel, e) && equipmentAllowed(p.Equipment, e) && !excluded(rules, e) {
+			allowed = append(allowed, e)
+		}
+	}
+	// A stable order makes the choice independent of how the catalog was read
+	slices.SortFunc(allowed, func(a, b Exercise) int {
+		return cmp.Or(strings.Compare(a.Name, b.Name), strings.Compare(a.ID, b.ID))
+	})
+
+	used := make(map[string]int) // times each exercise is already in the program
+	var missing []string         // muscles without a suitable exercise, in order
+	missingOn := make(map[string][]string)
+	for _, name := range names {
+		slots := dayTemplates[name[:strings.LastIndex(name, " ")]] // without the letter
+		if p.FitnessLevel == "Beginner" {
+			slots = slots[:beginnerSlots]
+		}
+
+		day := Day{Name: name}
+		inDay := make(map[string]bool)
+		for _, s := range slots {
+			if !slices.Contains(day.Focus, s.muscle) {
+				day.Focus = append(day.Focus, s.muscle)
+			}
+			e, ok := pick(allowed, s, p, inDay, used)
+			if !ok {
+				if _, seen := missingOn[s.muscle]; !seen {
+					missing = append(missing, s.muscle)
+				}
+				if days := missingOn[s.muscle]; len(days) == 0 || days[len(days)-1] != name {
+					missingOn[s.muscle] = append(days, name)
+				}
+				continue
+			}
+			inDay[e.ID] = true
+			used[e.ID]++
+			day.Exercises = append(day.Exercises, prescribe(e, goal, p.FitnessLevel))
+		}
+
+		// Conditioning goals end each day with cardio
+		if goal == GoalFatLoss || goal == GoalEndurance {
+			if e, ok := pick(allowed, slot{}, p, inDay, used); ok {
+				used[e.ID]++
+				day.Exercises = append(day.Exercises, Prescription{ExerciseID: e.ID, Sets: 1, Duration: cardioSeconds})
+			}
+		}
+		plan.Days = append(plan.Days, day)
+	}
+	for _, muscle := range missing {
+		plan.Notes = append(plan.Notes, fmt.Sprintf("no suitable exercise for %s on %s", muscle, strings.Join(missingOn[muscle], ", ")))
+	}
+	return plan
+}
+
+// pick returns the best allowed exercise for a slot that is not already in
+// the day. An empty slot muscle asks for cardio. Exercises are ranked by how
+// well they fit the slot and the user, then those already in the program
+// come last so days vary, then by name.
+func pick(allowed []Exercise, s slot, p Profile, inDay map[string]bool, used map[string]int) (Exercise, bool) {
+	var best Exercise
+	bestScore, found := 0, false
+	for _, e := range allowed {
+		if inDay[e.ID] {
+			continue
+		}
+		cardio := e.Category == "cardio"
+		if s.muscle == "" {
+			if !cardio {
+				continue
+			}
+		} else if cardio || !slices.Contains(e.Primary, s.muscle) {
+			continue
+		}
+
+		score := -10 * used[e.ID]
+		if s.mechanic != "" && e.Mechanic == s.mechanic {
+			score += 4
+		}
+		if e.Level == catalogLevel(p.FitnessLevel) {
+			score += 2
+		}
+		if interested(p.InterestedActivities, e) {
+			score += 3
+		}
+		if !found || score > bestScore {
+			best, bestScore, found = e, score, true
+		}
+	}
+	return best, found
+}
+
+// prescribe doses an exercise for the goal. Compound exercises get the
+// heavier dose; beginners do a set fewer and advanced lifters a set more.
+func prescribe(e Exercise, goal, level string) Prescription {
+	doses := goalDoses[goal]
+	d := doses[1]
+	if e.Mechanic == "compound" {
+		d = doses[0]
+	}
+	switch level {
+	case "Beginner":
+		d.sets = max(2, d.sets-1)
+	case "Advanced":
+		d.sets++
+	}
+
+	pr := Prescription{ExerciseID: e.ID, Sets: d.sets, RepsMin: d.repsMin, RepsMax: d.repsMax, Rest: d.rest}
+	if e.Category == "stretching" || strings.Contains(strings.ToLower(e.Name), "plank") {
+		pr.RepsMin, pr.RepsMax, pr.Duration = 0, 0, holdSeconds
+	}
+	return pr
+}
+
+// catalogLevel maps a fitness level to the catalog's level names
+func catalogLevel(fitnessLevel string) string {
+	switch fitnessLevel {
+	case "Advanced":
+		return "expert"
+	case "Intermediate":
+		return "intermediate"
+	}
+	return "beginner"
+}
+
+// levelAllowed keeps exercises at or below the user's level. Olympic lifts,
+// strongman and plyometrics need some experience.
+func levelAllowed(fitnessLevel string, e Exercise) bool {
+	rank := map[string]int{"beginner": 0, "intermediate": 1, "expert": 2}
+	user := rank[catalogLevel(fitnessLevel)]
+	if level, ok := rank[strings.ToLower(e.Level)]; ok && level > user {
+		return false
+	}
+	switch e.Category {
+	case "olympic weightlifting", "strongman", "plyometrics":
+		return user > 0
+	}
+	return true
+}
+
+// equipmentAllowed keeps exercises that need no equipment or only what the
+// user has. With no equipment declared everything is allowed.
+func equipmentAllowed(available []string, e Exercise) bool {
+	equipment := strings.ToLower(e.Equipment)
+	if len(available) == 0 || equipment == "" || equipment == "body only" {
+		return true
+	}
+	return slices.ContainsFunc(available, func(a string) bool { return strings.EqualFold(a, equipment) })
+}
+
+// interested reports whether an exercise matches one of the user's interests
+// by its category, equipment or name
+func interested(activities []string, e Exercise) bool {
+	for _, activity := range activities {
+		activity = strings.ToLower(strings.TrimSpace(activity))
+		if activity == "" {
+			continue
+		}
+		for _, field := range []string{e.Category, e.Equipment, e.Name} {
+			field = strings.ToLower(field)
+			if field != "" && (strings.Contains(field, activity) || strings.Contains(activity, field)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func containsAny(s string, words ...string) bool {
+	for _, word := range words {
+		if strings.Contains(s, word) {
+			return true
+		}
+	}
+	return false
+}
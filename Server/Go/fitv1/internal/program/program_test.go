@@ -0,0 +1,197 @@
+package program
+
+import (
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+var (
+	barbellSquat = Exercise{ID: "squat", Name: "Barbell Squat", Level: "beginner", Mechanic: "compound", Equipment: "barbell", Category: "strength", Primary: []string{"quadriceps"}}
+	gobletSquat  = Exercise{ID: "goblet", Name: "Goblet Squat", Level: "beginner", Mechanic: "compound", Equipment: "dumbbell", Category: "strength", Primary: []string{"quadriceps"}}
+	lunge        = Exercise{ID: "lunge", Name: "Dumbbell Lunge", Level: "beginner", Mechanic: "compound", Equipment: "dumbbell", Category: "strength", Primary: []string{"quadriceps"}}
+	boxJump      = Exercise{ID: "jump", Name: "Box Jump", Level: "intermediate", Mechanic: "compound", Equipment: "body only", Category: "plyometrics", Primary: []string{"quadriceps"}}
+	bench        = Exercise{ID: "bench", Name: "Dumbbell Bench Press", Level: "beginner", Mechanic: "compound", Equipment: "dumbbell", Category: "strength", Primary: []string{"chest"}}
+	row          = Exercise{ID: "row", Name: "One-Arm Dumbbell Row", Level: "beginner", Mechanic: "compound", Equipment: "dumbbell", Category: "strength", Primary: []string{"lats"}}
+	deadlift     = Exercise{ID: "rdl", Name: "Romanian Deadlift", Level: "beginner", Mechanic: "compound", Equipment: "barbell", Category: "strength", Primary: []string{"hamstrings"}}
+	legCurl      = Exercise{ID: "curl", Name: "Lying Leg Curl", Level: "beginner", Mechanic: "isolation", Equipment: "machine", Category: "strength", Primary: []string{"hamstrings"}}
+	press        = Exercise{ID: "press", Name: "Dumbbell Shoulder Press", Level: "beginner", Mechanic: "compound", Equipment: "dumbbell", Category: "strength", Primary: []string{"shoulders"}}
+	powerClean   = Exercise{ID: "clean", Name: "Power Clean", Level: "intermediate", Mechanic: "compound", Equipment: "barbell", Category: "olympic weightlifting", Primary: []string{"hamstrings"}}
+	plank        = Exercise{ID: "plank", Name: "Plank", Level: "beginner", Mechanic: "isolation", Equipment: "body only", Category: "strength", Primary: []string{"abdominals"}}
+	jog          = Exercise{ID: "jog", Name: "Jogging", Level: "beginner", Equipment: "body only", Category: "cardio", Primary: []string{"quadriceps"}}
+
+	catalog = []Exercise{barbellSquat, gobletSquat, lunge, boxJump, bench, row, deadlift, legCurl, press, powerClean, plank, jog}
+)
+
+func TestSplitOf(t *testing.T) {
+	tests := []struct {
+		days  int
+		split string
+		names []string
+	}{
+		{0, FullBody, []string{"Full Body A"}},
+		{1, FullBody, []string{"Full Body A"}},
+		{3, FullBody, []string{"Full Body A", "Full Body B", "Full Body C"}},
+		{4, UpperLower, []string{"Upper A", "Lower A", "Upper B", "Lower B"}},
+		{5, PushPullLegs, []string{"Push A", "Pull A", "Legs A", "Push B", "Pull B"}},
+		{6, PushPullLegs, []string{"Push A", "Pull A", "Legs A", "Push B", "Pull B", "Legs B"}},
+		{7, PushPullLegs, []string{"Push A", "Pull A", "Legs A", "Push B", "Pull B", "Legs B"}}, // capped
+	}
+	for _, tt := range tests {
+		split, names := SplitOf(tt.days)
+		if split != tt.split || !reflect.DeepEqual(names, tt.names) {
+			t.Errorf("SplitOf(%d) = %s %v, want %s %v", tt.days, split, names, tt.split, tt.names)
+		}
+	}
+}
+
+func TestGoalOf(t *testing.T) {
+	tests := []struct {
+		goals []string
+		want  string
+	}{
+		{nil, GoalGeneral},
+		{[]string{"stay healthy"}, GoalGeneral},
+		{[]string{"get stronger"}, GoalStrength},
+		{[]string{"get stronger", "lose fat"}, GoalStrength},
+		{[]string{"build muscle"}, GoalHypertrophy},
+		{[]string{"run a marathon"}, GoalEndurance},
+		{[]string{"lose weight"}, GoalFatLoss},
+		{[]string{"lose fat, build muscle"}, GoalRecomp},
+		{[]string{"lose fat", "build muscle"}, GoalRecomp},
+		{[]string{"body recomposition"}, GoalRecomp},
+	}
+	for _, tt := range tests {
+		if got := GoalOf(tt.goals); got != tt.want {
+			t.Errorf("GoalOf(%q) = %s, want %s", tt.goals, got, tt.want)
+		}
+	}
+}
+
+func TestPrescribe(t *testing.T) {
+	tests := []struct {
+		name     string
+		exercise Exercise
+		goal     string
+		level    string
+		want     Prescription
+	}{
+		{"strength compound", bench, GoalStrength, "Intermediate", Prescription{Sets: 5, RepsMin: 3, RepsMax: 5, Rest: 180}},
+		{"strength isolation", legCurl, GoalStrength, "Intermediate", Prescription{Sets: 3, RepsMin: 6, RepsMax: 8, Rest: 90}},
+		{"hypertrophy compound", bench, GoalHypertrophy, "Intermediate", Prescription{Sets: 4, RepsMin: 6, RepsMax: 10, Rest: 120}},
+		{"hypertrophy isolation", legCurl, GoalHypertrophy, "Intermediate", Prescription{Sets: 3, RepsMin: 10, RepsMax: 15, Rest: 60}},
+		{"endurance compound", bench, GoalEndurance, "Intermediate", Prescription{Sets: 3, RepsMin: 12, RepsMax: 20, Rest: 45}},
+		{"fat loss compound", bench, GoalFatLoss, "Intermediate", Prescription{Sets: 3, RepsMin: 10, RepsMax: 12, Rest: 60}},
+		{"recomposition compound", bench, GoalRecomp, "Intermediate", Prescription{Sets: 4, RepsMin: 8, RepsMax: 12, Rest: 90}},
+		{"general isolation", legCurl, GoalGeneral, "Intermediate", Prescription{Sets: 3, RepsMin: 10, RepsMax: 15, Rest: 60}},
+		{"beginners do a set fewer", bench, GoalHypertrophy, "Beginner", Prescription{Sets: 3, RepsMin: 6, RepsMax: 10, Rest: 120}},
+		{"beginners do at least two sets", legCurl, GoalEndurance, "Beginner", Prescription{Sets: 2, RepsMin: 15, RepsMax: 20, Rest: 30}},
+		{"advanced lifters do a set more", bench, GoalStrength, "Advanced", Prescription{Sets: 6, RepsMin: 3, RepsMax: 5, Rest: 180}},
+		{"planks are held", plank, GoalHypertrophy, "Intermediate", Prescription{Sets: 3, Duration: holdSeconds, Rest: 60}},
+	}
+	for _, tt := range tests {
+		tt.want.ExerciseID = tt.exercise.ID
+		if got := prescribe(tt.exercise, tt.goal, tt.level); got != tt.want {
+			t.Errorf("%s: got %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestEquipmentAllowed(t *testing.T) {
+	tests := []struct {
+		available []string
+		exercise  Exercise
+		want      bool
+	}{
+		{nil, barbellSquat, true}, // none declared
+		{[]string{"dumbbell"}, barbellSquat, false},
+		{[]string{"dumbbell"}, gobletSquat, true},
+		{[]string{"Barbell"}, barbellSquat, true},
+		{[]string{"dumbbell"}, plank, true}, // body only
+		{[]string{"dumbbell"}, Exercise{Name: "Anything"}, true},
+	}
+	for _, tt := range tests {
+		if got := equipmentAllowed(tt.available, tt.exercise); got != tt.want {
+			t.Errorf("equipmentAllowed(%q, %s) = %v, want %v", tt.available, tt.exercise.Name, got, tt.want)
+		}
+	}
+}
+
+func TestHealthRules(t *testing.T) {
+	tests := []struct {
+		consideration string
+		excludes      []Exercise
+		keeps         []Exercise
+		unmatched     bool
+	}{
+		{"knee pain", []Exercise{lunge, boxJump}, []Exercise{gobletSquat, bench}, false},
+		{"bad knees", []Exercise{lunge}, []Exercise{gobletSquat}, false},
+		{"herniated disc", []Exercise{barbellSquat, deadlift, powerClean}, []Exercise{gobletSquat}, false},
+		{"lower back discomfort", []Exercise{deadlift}, []Exercise{bench}, false},
+		{"shoulder impingement", []Exercise{powerClean}, []Exercise{press, bench}, false},
+		{"high blood pressure", []Exercise{powerClean}, []Exercise{bench}, false},
+		{"pregnancy", []Exercise{plank, boxJump}, []Exercise{bench}, false},
+		// Whole words only
+		{"discomfort after meals", nil, []Exercise{deadlift, barbellSquat}, true},
+		{"heartburn", nil, []Exercise{powerClean}, true},
+		{"backpacking trip next month", nil, []Exercise{deadlift}, true},
+		{"None", nil, catalog, false},
+		{"no known conditions", nil, catalog, false},
+	}
+	for _, tt := range tests {
+		rules, unmatched := healthRulesFor([]string{tt.consideration})
+		if (len(unmatched) > 0) != tt.unmatched {
+			t.Errorf("%q: unmatched %q", tt.consideration, unmatched)
+		}
+		for _, e := range tt.excludes {
+			if !excluded(rules, e) {
+				t.Errorf("%q keeps %s", tt.consideration, e.Name)
+			}
+		}
+		for _, e := range tt.keeps {
+			if excluded(rules, e) {
+				t.Errorf("%q excludes %s", tt.consideration, e.Name)
+			}
+		}
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	p := Profile{
+		DaysPerWeek:          3,
+		FitnessLevel:         "Intermediate",
+		Goals:                []string{"lose fat, build muscle"},
+		HealthConsiderations: []string{"knee pain", "heartburn"},
+		Equipment:            []string{"dumbbell"},
+	}
+	plan := Generate(p, catalog, "")
+	if plan.Split != FullBody || plan.Goal != GoalRecomp || len(plan.Days) != 3 {
+		t.Fatalf("got a %s %s plan of %d days", plan.Goal, plan.Split, len(plan.Days))
+	}
+
+	// Barbell and machine work, and what loads the knee, are left out
+	for _, day := range plan.Days {
+		for _, pr := range day.Exercises {
+			if slices.Contains([]string{"squat", "rdl", "curl", "clean", "lunge", "jump"}, pr.ExerciseID) {
+				t.Errorf("%s has %s", day.Name, pr.ExerciseID)
+			}
+		}
+		if len(day.Exercises) == 0 || day.Exercises[0].ExerciseID != "goblet" {
+			t.Errorf("%s starts with %+v, want the goblet squat", day.Name, day.Exercises)
+		}
+	}
+
+	var heartburn, hamstrings bool
+	for _, note := range plan.Notes {
+		heartburn = heartburn || strings.Contains(note, `"heartburn"`)
+		hamstrings = hamstrings || strings.HasPrefix(note, "no suitable exercise for hamstrings")
+	}
+	if !heartburn || !hamstrings {
+		t.Errorf("notes %q should name heartburn and the hamstrings", plan.Notes)
+	}
+
+	if again := Generate(p, catalog, ""); !reflect.DeepEqual(again, plan) {
+		t.Error("the same profile gave another plan")
+	}
+}
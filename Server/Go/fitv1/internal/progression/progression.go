@@ -0,0 +1,281 @@
+// Package progression recommends the load and reps of an exercise's next
+// session from the sessions logged before it, by one of three strategies:
+//
+//   - double progression adds reps within a range at one load, then load once
+//     every set reaches the top of the range
+//   - linear progression adds load whenever every set reaches a fixed rep target
+//   - RPE autoregulation moves the load by how hard the last session felt
+//     against a target rate of perceived exertion
+//
+// Whatever the strategy, an exercise that has stalled for a number of
+// sessions in a row is deloaded. Recommendations depend only on their
+// inputs. Loads are in any one unit; the results are in the same unit.
+package progression
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// Strategies
+const (
+	Double = "double"
+	Linear = "linear"
+	RPE    = "rpe"
+)
+
+// Actions a recommendation can take
+const (
+	Start        = "start" // no history yet
+	IncreaseLoad = "increase_load"
+	AddReps      = "add_reps"
+	Repeat       = "repeat"
+	DecreaseLoad = "decrease_load"
+	Deload       = "deload"
+)
+
+// Defaults for settings a program leaves out
+const (
+	DefaultDeloadAfter   = 3
+	DefaultDeloadPercent = 10
+	DefaultTargetRPE     = 8
+)
+
+// rpeStep is the share of the load one point of RPE is worth, roughly what
+// RPE charts give for sets of 3 to 10 reps
+const rpeStep = 0.03
+
+// loadPrecision is how close two loads must be to count as the same, so
+// loads converted from other units still match
+const loadPrecision = 0.01
+
+// ValidStrategy reports whether strategy is a supported strategy
+func ValidStrategy(strategy string) bool {
+	return strategy == Double || strategy == Linear || strategy == RPE
+}
+
+// Settings are how an exercise progresses
+type Settings struct {
+	Strategy      string
+	Increment     float64 // load added at a time, and the step loads are rounded to
+	DeloadAfter   int     // stalled sessions in a row before a deload
+	DeloadPercent float64 // load taken off for a deload
+	TargetRPE     float64 // for RPE
+}
+
+// Target is the prescribed dose of the exercise
+type Target struct {
+	Sets    int
+	RepsMin int
+	RepsMax int
+}
+
+// Set is one logged set. Warmup sets never count; RPE is 0 when not logged.
+type Set struct {
+	Load   float64
+	Reps   int
+	RPE    float64
+	Warmup bool
+}
+
+// Session is the sets of the exercise done in one session
+type Session struct {
+	At   time.Time
+	Sets []Set
+}
+
+// Performance is what the heaviest working sets of a session amounted to
+type Performance struct {
+	At   time.Time
+	Load float64 // heaviest working load
+	Sets int     // working sets at that load
+	Reps int     // fewest reps of those sets
+	RPE  float64 // highest RPE of those sets, 0 when none was logged
+	// total is the reps of those sets together
+	total int
+}
+
+// Recommendation is the dose of the next session. Load is meaningless for
+// Start, where there is nothing to go by.
+type Recommendation struct {
+	Action    string
+	Sets      int
+	Reps      int
+	Load      float64
+	TargetRPE float64 // for RPE
+	Stalls    int     // sessions in a row without progress
+	Last      *Performance
+	Reason    string
+}
+
+// Recommend returns the next session's dose after history, which must be
+// oldest first. Sessions without working sets are ignored.
+func Recommend(settings Settings, target Target, history []Session) Recommendation {
+	reps := target.RepsMax
+	if settings.Strategy == Double {
+		reps = target.RepsMin
+	}
+	rec := Recommendation{Sets: target.Sets, Reps: reps}
+	if settings.Strategy == RPE {
+		rec.TargetRPE = settings.TargetRPE
+	}
+
+	var performances []Performance
+	for _, s := range history {
+		if p, ok := perform(s); ok {
+			performances = append(performances, p)
+		}
+	}
+	if len(performances) == 0 {
+		rec.Action = Start
+		rec.Reason = fmt.Sprintf("no sets logged yet: pick a load you could lift for %d reps with two or three to spare", rec.Reps)
+		return rec
+	}
+	last := performances[len(performances)-1]
+	rec.Last = &last
+	rec.Load = last.Load
+	rec.Stalls = stalls(performances, settings.DeloadAfter)
+
+	if rec.Stalls >= settings.DeloadAfter {
+		rec.Action = Deload
+		rec.Load = roundTo(last.Load*(1-settings.DeloadPercent/100), settings.Increment, math.Floor)
+		if rec.Load >= last.Load-loadPrecision {
+			rec.Load = math.Max(last.Load-settings.Increment, 0)
+		}
+		rec.Reason = fmt.Sprintf("no progress in %d sessions: take %g%% off and build back up", rec.Stalls, settings.DeloadPercent)
+		return rec
+	}
+
+	switch {
+	case settings.Strategy == Double:
+		double(&rec, settings, target, last)
+	case settings.Strategy == RPE && last.RPE > 0 && last.Load > 0:
+		autoregulate(&rec, settings, last)
+	default:
+		linear(&rec, settings, last)
+		if settings.Strategy == RPE {
+			rec.Reason += " (no RPE logged, so progressing linearly)"
+		}
+	}
+	return rec
+}
+
+// double adds a rep until every set reaches the top of the range, then load
+func double(rec *Recommendation, settings Settings, target Target, last Performance) {
+	switch {
+	case last.Sets >= target.Sets && last.Reps >= target.RepsMax:
+		rec.Action = IncreaseLoad
+		rec.Load = last.Load + settings.Increment
+		rec.Reps = target.RepsMin
+		rec.Reason = fmt.Sprintf("every set reached %d reps: add load and start again at %d", target.RepsMax, target.RepsMin)
+	case last.Reps >= target.RepsMax:
+		rec.Action = Repeat
+		rec.Reps = target.RepsMax
+		rec.Reason = fmt.Sprintf("do all %d sets at %d reps before adding load", target.Sets, target.RepsMax)
+	case last.Reps >= target.RepsMin:
+		rec.Action = AddReps
+		rec.Reps = last.Reps + 1
+		rec.Reason = fmt.Sprintf("add a rep to every set on the way to %d", target.RepsMax)
+	default:
+		rec.Action = Repeat
+		rec.Reason = fmt.Sprintf("reach %d reps on every set at this load", target.RepsMin)
+	}
+}
+
+// linear adds load whenever every set reaches the rep target
+func linear(rec *Recommendation, settings Settings, last Performance) {
+	if last.Sets >= rec.Sets && last.Reps >= rec.Reps {
+		rec.Action = IncreaseLoad
+		rec.Load = last.Load + settings.Increment
+		rec.Reason = fmt.Sprintf("every set reached %d reps: add load", rec.Reps)
+		return
+	}
+	rec.Action = Repeat
+	rec.Reason = fmt.Sprintf("complete %d sets of %d reps at this load", rec.Sets, rec.Reps)
+}
+
+// autoregulate moves the load by how far the last session's RPE was from the
+// target. A missed rep counts as a point of RPE above 10.
+func autoregulate(rec *Recommendation, settings Settings, last Performance) {
+	effort := last.RPE
+	if last.Reps < rec.Reps {
+		effort = 10 + float64(rec.Reps-last.Reps)
+	}
+	change := settings.TargetRPE - effort
+	rec.Load = roundTo(last.Load*(1+change*rpeStep), settings.Increment, math.Round)
+	// Rounding an off-step load must not move it against the change
+	switch {
+	case change > 0 && rec.Load > last.Load+loadPrecision:
+		rec.Action = IncreaseLoad
+	case change < 0 && rec.Load < last.Load-loadPrecision:
+		rec.Action = DecreaseLoad
+	default:
+		rec.Action = Repeat
+		rec.Load = last.Load
+	}
+	if last.Reps < rec.Reps {
+		rec.Reason = fmt.Sprintf("missed %d of %d reps last session", rec.Reps-last.Reps, rec.Reps)
+	} else {
+		rec.Reason = fmt.Sprintf("last session felt like RPE %g against a target of %g", effort, settings.TargetRPE)
+	}
+}
+
+// perform sums up the heaviest working sets of a session
+func perform(s Session) (Performance, bool) {
+	var p Performance
+	found := false
+	for _, set := range s.Sets {
+		if set.Warmup || set.Reps < 1 {
+			continue
+		}
+		if !found || set.Load > p.Load+loadPrecision {
+			p = Performance{At: s.At, Load: set.Load, Reps: set.Reps}
+			found = true
+		} else if set.Load < p.Load-loadPrecision {
+			continue
+		}
+		p.Sets++
+		p.Reps = min(p.Reps, set.Reps)
+		p.RPE = math.Max(p.RPE, set.RPE)
+		p.total += set.Reps
+	}
+	return p, found
+}
+
+// better reports whether a beats b: a heavier load, more reps at the same
+// load, or the same reps at a lower RPE
+func better(a, b Performance) bool {
+	switch {
+	case math.Abs(a.Load-b.Load) > loadPrecision:
+		return a.Load > b.Load
+	case a.total != b.total:
+		return a.total > b.total
+	default:
+		return a.RPE > 0 && b.RPE > 0 && a.RPE < b.RPE
+	}
+}
+
+// stalls counts the latest sessions in a row that did not beat the best since
+// the last deload. The session after a deload was due restarts the count.
+func stalls(performances []Performance, deloadAfter int) int {
+	var best Performance
+	n := 0
+	for i, p := range performances {
+		if i == 0 || n >= deloadAfter || better(p, best) {
+			best, n = p, 0
+			continue
+		}
+		n++
+	}
+	return n
+}
+
+// roundTo rounds a load to a multiple of step, by round
+func roundTo(load, step float64, round func(float64) float64) float64 {
+	if step <= 0 {
+		return load
+	}
+	// Nudge the quotient so float noise cannot floor an exact multiple
+	return math.Round(round(load/step+1e-9)*step*1000) / 1000
+}
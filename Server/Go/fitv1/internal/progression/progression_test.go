@@ -0,0 +1,134 @@
+package progression
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+// poundStep is 5 lb in kg, the increment of a user on imperial units
+const poundStep = 5 * 0.45359237
+
+var (
+	kg     = Settings{Increment: 2.5, DeloadAfter: DefaultDeloadAfter, DeloadPercent: DefaultDeloadPercent, TargetRPE: DefaultTargetRPE}
+	pounds = Settings{Increment: poundStep, DeloadAfter: DefaultDeloadAfter, DeloadPercent: DefaultDeloadPercent, TargetRPE: DefaultTargetRPE}
+
+	fives  = Target{Sets: 3, RepsMin: 5, RepsMax: 5}
+	range8 = Target{Sets: 3, RepsMin: 8, RepsMax: 12}
+)
+
+func strategy(s Settings, name string) Settings {
+	s.Strategy = name
+	return s
+}
+
+// session is one session of working sets at load, one per rep count
+func session(load float64, reps ...int) Session {
+	return rated(load, 0, reps...)
+}
+
+// rated is a session whose sets were all logged at rpe
+func rated(load, rpe float64, reps ...int) Session {
+	s := Session{At: time.Date(2024, 3, 1, 7, 0, 0, 0, time.UTC)}
+	for _, r := range reps {
+		s.Sets = append(s.Sets, Set{Load: load, Reps: r, RPE: rpe})
+	}
+	return s
+}
+
+// repeated is n copies of s, as n sessions in a row
+func repeated(n int, s Session) []Session {
+	var history []Session
+	for i := 0; i < n; i++ {
+		history = append(history, s)
+	}
+	return history
+}
+
+func TestRecommend(t *testing.T) {
+	warmup := Session{Sets: []Set{{Load: 60, Reps: 5, Warmup: true}}}
+	deloaded := append(repeated(4, session(100, 5, 5, 4)), session(90, 5, 5, 5))
+
+	tests := []struct {
+		name     string
+		settings Settings
+		target   Target
+		history  []Session
+		action   string
+		load     float64
+		reps     int
+		stalls   int
+		reason   string // part of the reason, if set
+	}{
+		{"empty history starts", strategy(kg, Double), range8, nil, Start, 0, 8, 0, "no sets logged"},
+		{"warm-ups only starts", strategy(kg, Linear), fives, []Session{warmup}, Start, 0, 5, 0, ""},
+
+		{"double adds a rep", strategy(kg, Double), range8, []Session{session(100, 9, 9, 9)}, AddReps, 100, 10, 0, ""},
+		{"double adds load at the top", strategy(kg, Double), range8, []Session{session(100, 12, 12, 12)}, IncreaseLoad, 102.5, 8, 0, ""},
+		{"double needs every set at the top", strategy(kg, Double), range8, []Session{session(100, 12, 12)}, Repeat, 100, 12, 0, ""},
+		{"double below the range repeats", strategy(kg, Double), range8, []Session{session(100, 7, 6, 6)}, Repeat, 100, 8, 0, ""},
+
+		{"linear adds load", strategy(kg, Linear), fives, []Session{session(100, 5, 5, 5)}, IncreaseLoad, 102.5, 5, 0, ""},
+		{"linear repeats a missed rep", strategy(kg, Linear), fives, []Session{session(100, 5, 5, 4)}, Repeat, 100, 5, 0, ""},
+
+		{"rpe below target adds load", strategy(kg, RPE), fives, []Session{rated(100, 6, 5, 5, 5)}, IncreaseLoad, 105, 5, 0, "RPE 6"},
+		{"rpe on target repeats", strategy(kg, RPE), fives, []Session{rated(100, 8, 5, 5, 5)}, Repeat, 100, 5, 0, ""},
+		{"rpe above target takes load off", strategy(kg, RPE), fives, []Session{rated(100, 9.5, 5, 5, 5)}, DecreaseLoad, 95, 5, 0, ""},
+		{"rpe counts missed reps above 10", strategy(kg, RPE), fives, []Session{rated(100, 10, 5, 5, 3)}, DecreaseLoad, 87.5, 5, 0, "missed 2 of 5"},
+		{"rpe without RPE progresses linearly", strategy(kg, RPE), fives, []Session{session(100, 5, 5, 5)}, IncreaseLoad, 102.5, 5, 0, "no RPE logged"},
+
+		{"stalls short of a deload", strategy(kg, Linear), fives, repeated(3, session(100, 5, 5, 4)), Repeat, 100, 5, 2, ""},
+		{"deloads after stalling", strategy(kg, Linear), fives, repeated(4, session(100, 5, 5, 4)), Deload, 90, 5, 3, "no progress in 3 sessions"},
+		{"deload takes at least one increment", Settings{Strategy: Linear, Increment: 2.5, DeloadAfter: 3, DeloadPercent: 1}, fives, repeated(4, session(100, 5, 5, 4)), Deload, 97.5, 5, 3, ""},
+		{"session after a deload resets", strategy(kg, Linear), fives, deloaded, IncreaseLoad, 92.5, 5, 0, ""},
+		{"progress resets the stalls", strategy(kg, Linear), fives, append(repeated(3, session(100, 5, 5, 4)), session(100, 5, 5, 5)), IncreaseLoad, 102.5, 5, 0, ""},
+
+		// 5 lb steps converted to kg
+		{"imperial increment", strategy(pounds, Linear), fives, []Session{session(45*poundStep, 5, 5, 5)}, IncreaseLoad, 46 * poundStep, 5, 0, ""},
+		{"imperial rpe rounds to 5 lb", strategy(pounds, RPE), fives, []Session{rated(45*poundStep, 6, 5, 5, 5)}, IncreaseLoad, 108.862, 5, 0, ""},
+		{"imperial deload floors to 5 lb", strategy(pounds, Linear), fives, repeated(4, session(100, 5, 5, 4)), Deload, 88.451, 5, 3, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := Recommend(tt.settings, tt.target, tt.history)
+			if rec.Action != tt.action || math.Abs(rec.Load-tt.load) > 0.001 || rec.Reps != tt.reps || rec.Stalls != tt.stalls {
+				t.Errorf("got %s %v x %d after %d stalls, want %s %v x %d after %d (%s)",
+					rec.Action, rec.Load, rec.Reps, rec.Stalls, tt.action, tt.load, tt.reps, tt.stalls, rec.Reason)
+			}
+			if rec.Sets != tt.target.Sets {
+				t.Errorf("sets = %d, want %d", rec.Sets, tt.target.Sets)
+			}
+			if !strings.Contains(rec.Reason, tt.reason) {
+				t.Errorf("reason %q does not mention %q", rec.Reason, tt.reason)
+			}
+			if (rec.Last == nil) != (tt.action == Start) {
+				t.Errorf("last performance %v for %s", rec.Last, rec.Action)
+			}
+		})
+	}
+}
+
+func TestRoundTo(t *testing.T) {
+	tests := []struct {
+		name  string
+		load  float64
+		step  float64
+		round func(float64) float64
+		want  float64
+	}{
+		{"nearest kg step", 106, 2.5, math.Round, 105},
+		{"floor kg step", 89.9, 2.5, math.Floor, 87.5},
+		{"no step", 101.23, 0, math.Round, 101.23},
+		{"nearest 5 lb", 108.18, poundStep, math.Round, 108.862},
+		{"floor 5 lb", 90, poundStep, math.Floor, 88.451},
+		// Float noise must not floor an exact multiple a step lower
+		{"exact multiple of 5 lb", 45 * poundStep, poundStep, math.Floor, 102.058},
+		{"exact multiple of 0.1", 0.3, 0.1, math.Floor, 0.3},
+	}
+	for _, tt := range tests {
+		if got := roundTo(tt.load, tt.step, tt.round); got != tt.want {
+			t.Errorf("%s: roundTo(%v, %v) = %v, want %v", tt.name, tt.load, tt.step, got, tt.want)
+		}
+	}
+}
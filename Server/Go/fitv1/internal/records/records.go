@@ -0,0 +1,200 @@
+// Package records finds personal records in the logged sets of one exercise:
+// the heaviest load, the most reps at each load, the best estimated one-rep
+// max and the highest session volume.
+//
+// It works on plain values so the rules do not depend on storage. Loads are
+// in any one unit; the results are in the same unit.
+package records
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"time"
+)
+
+// One-rep max formulas
+const (
+	Epley    = "epley"
+	Brzycki  = "brzycki"
+	Lombardi = "lombardi"
+)
+
+// Kinds of personal record
+const (
+	HeaviestLoad  = "heaviest_load"
+	MostReps      = "most_reps" // at one load
+	Estimated1RM  = "estimated_1rm"
+	SessionVolume = "session_volume"
+)
+
+// MaxEstimateReps is the most reps a one-rep max is estimated from. The
+// formulas drift apart, and from reality, beyond it.
+const MaxEstimateReps = 12
+
+var ErrUnknownFormula = errors.New("formula must be epley, brzycki or lombardi")
+
+// ValidFormula reports whether formula is a supported one-rep max formula
+func ValidFormula(formula string) bool {
+	return formula == Epley || formula == Brzycki || formula == Lombardi
+}
+
+// Estimate returns the one-rep max estimated from lifting load for reps, or 0
+// when the set cannot be estimated from
+func Estimate(formula string, load float64, reps int) float64 {
+	if load <= 0 || reps < 1 || reps > MaxEstimateReps {
+		return 0
+	}
+	if reps == 1 {
+		return load
+	}
+	r := float64(reps)
+	switch formula {
+	case Brzycki:
+		return load * 36 / (37 - r)
+	case Lombardi:
+		return load * math.Pow(r, 0.1)
+	default:
+		return load * (1 + r/30)
+	}
+}
+
+// Set is one logged set. Warmup sets never count.
+type Set struct {
+	ID     string
+	Load   float64
+	Reps   int
+	Warmup bool
+}
+
+// Session is the sets of the exercise done in one session, in order
+type Session struct {
+	ID   string
+	At   time.Time
+	Sets []Set
+}
+
+// Record is a best value and where it was set. Value is a load for
+// HeaviestLoad and Estimated1RM, reps for MostReps and load times reps for
+// SessionVolume. Session volume records have no set.
+type Record struct {
+	Kind      string
+	Value     float64
+	Load      float64
+	Reps      int
+	SessionID string
+	SetID     string
+	At        time.Time
+}
+
+// Summary is what one session of the exercise amounted to
+type Summary struct {
+	Sets         int     // working sets
+	Reps         int     // over the working sets
+	TopLoad      float64 // heaviest working set
+	Estimated1RM float64 // best estimate of the session
+	Volume       float64 // load times reps over the working sets
+}
+
+// Summarize totals the working sets of a session
+func Summarize(s Session, formula string) Summary {
+	var summary Summary
+	for _, set := range s.Sets {
+		if set.Warmup {
+			continue
+		}
+		summary.Sets++
+		summary.Reps += set.Reps
+		summary.TopLoad = math.Max(summary.TopLoad, set.Load)
+		summary.Estimated1RM = math.Max(summary.Estimated1RM, Estimate(formula, set.Load, set.Reps))
+		if set.Load > 0 {
+			summary.Volume += set.Load * float64(set.Reps)
+		}
+	}
+	return summary
+}
+
+// Tracker follows the records of one exercise through its sessions, which
+// must be added oldest first
+type Tracker struct {
+	formula  string
+	heaviest *Record
+	estimate *Record
+	volume   *Record
+	mostReps map[float64]*Record // by load rounded to loadPrecision
+}
+
+// loadPrecision is how close two loads must be to count as the same for
+// MostReps, so loads converted from other units still match
+const loadPrecision = 0.01
+
+// NewTracker starts tracking with no records
+func NewTracker(formula string) *Tracker {
+	return &Tracker{formula: formula, mostReps: make(map[float64]*Record)}
+}
+
+// Add takes the next session and returns the records it set, in the order
+// they were set. A value must beat the previous one of its kind; the first
+// value of a kind is only remembered, so nothing is a record until it has
+// been done before.
+func (t *Tracker) Add(s Session) []Record {
+	var set []Record
+	beat := func(best **Record, r Record) {
+		if *best == nil {
+			*best = &r
+		} else if r.Value > (*best).Value {
+			*best = &r
+			set = append(set, r)
+		}
+	}
+
+	for _, x := range s.Sets {
+		if x.Warmup {
+			continue
+		}
+		at := Record{Load: x.Load, Reps: x.Reps, SessionID: s.ID, SetID: x.ID, At: s.At}
+		if x.Load > 0 {
+			r := at
+			r.Kind, r.Value = HeaviestLoad, x.Load
+			beat(&t.heaviest, r)
+		}
+		if e := Estimate(t.formula, x.Load, x.Reps); e > 0 {
+			r := at
+			r.Kind, r.Value = Estimated1RM, e
+			beat(&t.estimate, r)
+		}
+		if x.Reps > 0 {
+			r := at
+			r.Kind, r.Value = MostReps, float64(x.Reps)
+			key := math.Round(x.Load/loadPrecision) * loadPrecision
+			if best, ok := t.mostReps[key]; !ok {
+				t.mostReps[key] = &r
+			} else if r.Value > best.Value {
+				t.mostReps[key] = &r
+				set = append(set, r)
+			}
+		}
+	}
+
+	if volume := Summarize(s, t.formula).Volume; volume > 0 {
+		beat(&t.volume, Record{Kind: SessionVolume, Value: volume, SessionID: s.ID, At: s.At})
+	}
+	return set
+}
+
+// Best returns the current records: heaviest load, estimated one-rep max and
+// session volume, then the most reps at each load, heaviest first
+func (t *Tracker) Best() []Record {
+	var best []Record
+	for _, r := range []*Record{t.heaviest, t.estimate, t.volume} {
+		if r != nil {
+			best = append(best, *r)
+		}
+	}
+	var reps []Record
+	for _, r := range t.mostReps {
+		reps = append(reps, *r)
+	}
+	sort.Slice(reps, func(i, j int) bool { return reps[i].Load > reps[j].Load })
+	return append(best, reps...)
+}
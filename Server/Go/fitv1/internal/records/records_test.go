@@ -0,0 +1,161 @@
+package records
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"reflect"
+	"testing"
+	"testing/quick"
+	"time"
+)
+
+var formulas = []string{Epley, Brzycki, Lombardi}
+
+// lift is a load and a rep count the one-rep max can be estimated from
+type lift struct {
+	Load float64
+	Reps int
+}
+
+func (lift) Generate(r *rand.Rand, _ int) reflect.Value {
+	return reflect.ValueOf(lift{Load: 1 + r.Float64()*300, Reps: 1 + r.Intn(MaxEstimateReps)})
+}
+
+func TestEstimateRisesWithLoad(t *testing.T) {
+	for _, formula := range formulas {
+		rises := func(l lift, more float64) bool {
+			heavier := l.Load + math.Abs(more) + 0.5
+			return Estimate(formula, heavier, l.Reps) > Estimate(formula, l.Load, l.Reps)
+		}
+		if err := quick.Check(rises, nil); err != nil {
+			t.Errorf("%s: %v", formula, err)
+		}
+	}
+}
+
+func TestEstimateRisesWithReps(t *testing.T) {
+	for _, formula := range formulas {
+		rises := func(l lift) bool {
+			if l.Reps == MaxEstimateReps {
+				return Estimate(formula, l.Load, l.Reps+1) == 0
+			}
+			return Estimate(formula, l.Load, l.Reps+1) > Estimate(formula, l.Load, l.Reps)
+		}
+		if err := quick.Check(rises, nil); err != nil {
+			t.Errorf("%s: %v", formula, err)
+		}
+	}
+}
+
+func TestEstimateOfOneRep(t *testing.T) {
+	for _, formula := range formulas {
+		single := func(l lift) bool { return Estimate(formula, l.Load, 1) == l.Load }
+		if err := quick.Check(single, nil); err != nil {
+			t.Errorf("%s: %v", formula, err)
+		}
+	}
+}
+
+// history is a run of sessions, oldest first, with some warm-up sets
+type history []Session
+
+func (history) Generate(r *rand.Rand, _ int) reflect.Value {
+	start := time.Date(2024, 1, 1, 7, 0, 0, 0, time.UTC)
+	h := make(history, 1+r.Intn(12))
+	for i := range h {
+		s := Session{ID: fmt.Sprint("s", i), At: start.AddDate(0, 0, i)}
+		for j := r.Intn(6); j >= 0; j-- {
+			// A few loads repeat, so rep records at one load come up
+			s.Sets = append(s.Sets, Set{
+				ID:     fmt.Sprint(s.ID, "-", j),
+				Load:   float64(r.Intn(8)) * 12.5,
+				Reps:   r.Intn(15),
+				Warmup: r.Intn(4) == 0,
+			})
+		}
+		h[i] = s
+	}
+	return reflect.ValueOf(h)
+}
+
+// key tells records apart: one per kind, and one per load for MostReps
+func key(r Record) string {
+	if r.Kind == MostReps {
+		return fmt.Sprintf("%s@%.2f", r.Kind, r.Load)
+	}
+	return r.Kind
+}
+
+func TestAddNeverLowersBest(t *testing.T) {
+	for _, formula := range formulas {
+		neverLower := func(h history) bool {
+			tracker := NewTracker(formula)
+			best := make(map[string]float64)
+			for _, s := range h {
+				tracker.Add(s)
+				now := make(map[string]float64)
+				for _, r := range tracker.Best() {
+					now[key(r)] = r.Value
+				}
+				for k, v := range best {
+					if now[k] < v {
+						return false
+					}
+				}
+				best = now
+			}
+			return true
+		}
+		if err := quick.Check(neverLower, nil); err != nil {
+			t.Errorf("%s: %v", formula, err)
+		}
+	}
+}
+
+func TestWarmupsNeverCount(t *testing.T) {
+	for _, formula := range formulas {
+		ignored := func(h history) bool {
+			with, without := NewTracker(formula), NewTracker(formula)
+			for _, s := range h {
+				working := s
+				working.Sets = nil
+				for _, set := range s.Sets {
+					if !set.Warmup {
+						working.Sets = append(working.Sets, set)
+					}
+				}
+				if !reflect.DeepEqual(with.Add(s), without.Add(working)) {
+					return false
+				}
+			}
+			return reflect.DeepEqual(with.Best(), without.Best())
+		}
+		if err := quick.Check(ignored, nil); err != nil {
+			t.Errorf("%s: %v", formula, err)
+		}
+	}
+}
+
+func TestFirstValueIsNotARecord(t *testing.T) {
+	at := time.Date(2024, 1, 1, 7, 0, 0, 0, time.UTC)
+	tracker := NewTracker(Epley)
+	first := Session{ID: "s1", At: at, Sets: []Set{{ID: "a", Load: 100, Reps: 5}}}
+	if got := tracker.Add(first); len(got) != 0 {
+		t.Errorf("first session set %v", got)
+	}
+	if got := len(tracker.Best()); got != 4 {
+		t.Errorf("%d records remembered, want 4", got)
+	}
+
+	second := Session{ID: "s2", At: at.AddDate(0, 0, 2), Sets: []Set{{ID: "b", Load: 105, Reps: 6}}}
+	var kinds []string
+	for _, r := range tracker.Add(second) {
+		kinds = append(kinds, r.Kind)
+	}
+	// 105 kg has not been lifted before, so it is no rep record
+	want := []string{HeaviestLoad, Estimated1RM, SessionVolume}
+	if !reflect.DeepEqual(kinds, want) {
+		t.Errorf("second session set %v, want %v", kinds, want)
+	}
+}
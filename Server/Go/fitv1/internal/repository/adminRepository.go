@@ -0,0 +1,285 @@
+package repository
+
+import (
+	"context"
+	"regexp"
+	"slices"
+
+	"github.com/AyushIIITU/virtualfit/internal/models"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// userSearchFilter builds the Mongo filter for a user search. The query is
+// matched literally against name and email.
+func userSearchFilter(criteria models.UserSearchCriteria) bson.M {
+	filter := bson.M{}
+	if criteria.Query != "" {
+		pattern := regexp.QuoteMeta(criteria.Query)
+		filter["$or"] = []bson.M{
+			{"name": bson.M{"$regex": pattern, "$options": "i"}},
+			{"email": bson.M{"$regex": pattern, "$options": "i"}},
+		}
+	}
+	switch criteria.Role {
+	case "":
+	case models.RoleUser:
+		// Accounts created before roles existed have no role field
+		filter["role"] = bson.M{"$in": []any{models.RoleUser, "", nil}}
+	default:
+		filter["role"] = criteria.Role
+	}
+	if criteria.Disabled != nil {
+		if *criteria.Disabled {
+			filter["disabled"] = true
+		} else {
+			filter["disabled"] = bson.M{"$ne": true}
+		}
+	}
+	return filter
+}
+
+// SearchUsers returns a page of users matching the criteria, newest first, and the total count
+func (m *MongoDB) SearchUsers(ctx context.Context, criteria models.UserSearchCriteria) ([]*models.User, int64, error) {
+	collection := m.db.Collection("users")
+	filter := userSearchFilter(criteria)
+
+	totalCount, err := collection.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "created_at", Value: -1}}).
+		SetLimit(int64(criteria.Limit)).
+		SetSkip(int64(criteria.Offset))
+	cursor, err := collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer cursor.Close(ctx)
+
+	var users []*models.User
+	if err := cursor.All(ctx, &users); err != nil {
+		return nil, 0, err
+	}
+	return users, totalCount, nil
+}
+
+// GetJobByID returns a job by ID
+func (m *MongoDB) GetJobByID(ctx context.Context, id bson.ObjectID) (*models.Job, error) {
+	job := &models.Job{}
+	err := m.db.Collection("jobs").FindOne(ctx, bson.M{"_id": id}).Decode(job)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return job, nil
+}
+
+// ListJobs returns a page of jobs, most recently updated first, and the total count
+func (m *MongoDB) ListJobs(ctx context.Context, query models.JobQuery) ([]*models.Job, int64, error) {
+	collection := m.db.Collection("jobs")
+	filter := bson.M{}
+	if len(query.States) > 0 {
+		filter["state"] = bson.M{"$in": query.States}
+	}
+	if query.Type != "" {
+		filter["type"] = query.Type
+	}
+
+	totalCount, err := collection.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "updated_at", Value: -1}}).
+		SetLimit(int64(query.Limit)).
+		SetSkip(int64(query.Offset))
+	cursor, err := collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer cursor.Close(ctx)
+
+	var jobs []*models.Job
+	if err := cursor.All(ctx, &jobs); err != nil {
+		return nil, 0, err
+	}
+	return jobs, totalCount, nil
+}
+
+// CreateWorkout adds a workout to the catalog
+func (m *MongoDB) CreateWorkout(ctx context.Context, workout *models.Workout) (*models.Workout, error) {
+	if workout.ID.IsZero() {
+		workout.ID = bson.NewObjectID()
+	}
+	if _, err := m.db.Collection("workouts").InsertOne(ctx, workout); err != nil {
+		return nil, err
+	}
+	return workout, nil
+}
+
+// UpdateWorkout replaces a catalog workout
+func (m *MongoDB) UpdateWorkout(ctx context.Context, workout *models.Workout) error {
+	result, err := m.db.Collection("workouts").ReplaceOne(ctx, bson.M{"_id": workout.ID}, workout)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// DeleteWorkout removes a workout from the catalog
+func (m *MongoDB) DeleteWorkout(ctx context.Context, id bson.ObjectID) error {
+	result, err := m.db.Collection("workouts").DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// RecordAudit appends an entry to the audit log
+func (m *MongoDB) RecordAudit(ctx context.Context, entry *models.AuditEntry) error {
+	if entry.ID.IsZero() {
+		entry.ID = bson.NewObjectID()
+	}
+	_, err := m.db.Collection("audit_log").InsertOne(ctx, entry)
+	return err
+}
+
+// ListAudit returns a page of audit entries, newest first, and the total count
+func (m *MongoDB) ListAudit(ctx context.Context, query models.AuditQuery) ([]*models.AuditEntry, int64, error) {
+	collection := m.db.Collection("audit_log")
+	filter := bson.M{}
+	if !query.ActorID.IsZero() {
+		filter["actor_id"] = query.ActorID
+	}
+	if query.Action != "" {
+		filter["action"] = query.Action
+	}
+	if query.TargetID != "" {
+		filter["target_id"] = query.TargetID
+	}
+
+	totalCount, err := collection.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "created_at", Value: -1}, {Key: "_id", Value: -1}}).
+		SetLimit(int64(query.Limit)).
+		SetSkip(int64(query.Offset))
+	cursor, err := collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer cursor.Close(ctx)
+
+	var entries []*models.AuditEntry
+	if err := cursor.All(ctx, &entries); err != nil {
+		return nil, 0, err
+	}
+	return entries, totalCount, nil
+}
+
+// In-memory implementation
+
+func (m *Memory) SearchUsers(ctx context.Context, criteria models.UserSearchCriteria) ([]*models.User, int64, error) {
+	re, err := regexFilter(regexp.QuoteMeta(criteria.Query))
+	if err != nil {
+		return nil, 0, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	matched := filterRows(m.users, func(u *models.User) bool {
+		return (re == nil || re.MatchString(u.Name) || re.MatchString(u.Email)) &&
+			(criteria.Role == "" || u.EffectiveRole() == criteria.Role) &&
+			(criteria.Disabled == nil || u.Disabled == *criteria.Disabled)
+	})
+	slices.Reverse(matched)
+	return cloneAll(paginate(matched, criteria.Limit, criteria.Offset)), int64(len(matched)), nil
+}
+
+func (m *Memory) GetJobByID(ctx context.Context, id bson.ObjectID) (*models.Job, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, job := range m.jobs {
+		if job.ID == id {
+			return clone(job), nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (m *Memory) ListJobs(ctx context.Context, query models.JobQuery) ([]*models.Job, int64, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	matched := filterRows(m.jobs, func(j *models.Job) bool {
+		return (len(query.States) == 0 || slices.Contains(query.States, j.State)) &&
+			(query.Type == "" || j.Type == query.Type)
+	})
+	slices.SortStableFunc(matched, func(a, b *models.Job) int {
+		return b.UpdatedAt.Compare(a.UpdatedAt)
+	})
+	return cloneAll(paginate(matched, query.Limit, query.Offset)), int64(len(matched)), nil
+}
+
+func (m *Memory) CreateWorkout(ctx context.Context, workout *models.Workout) (*models.Workout, error) {
+	m.SeedWorkouts(workout)
+	return workout, nil
+}
+
+func (m *Memory) UpdateWorkout(ctx context.Context, workout *models.Workout) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i, existing := range m.workouts {
+		if existing.ID == workout.ID {
+			m.workouts[i] = clone(workout)
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+func (m *Memory) DeleteWorkout(ctx context.Context, id bson.ObjectID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i, workout := range m.workouts {
+		if workout.ID == id {
+			m.workouts = append(m.workouts[:i], m.workouts[i+1:]...)
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+func (m *Memory) RecordAudit(ctx context.Context, entry *models.AuditEntry) error {
+	if entry.ID.IsZero() {
+		entry.ID = bson.NewObjectID()
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.auditLog = append(m.auditLog, clone(entry))
+	return nil
+}
+
+func (m *Memory) ListAudit(ctx context.Context, query models.AuditQuery) ([]*models.AuditEntry, int64, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	matched := filterRows(m.auditLog, func(e *models.AuditEntry) bool {
+		return (query.ActorID.IsZero() || e.ActorID == query.ActorID) &&
+			(query.Action == "" || e.Action == query.Action) &&
+			(query.TargetID == "" || e.TargetID == query.TargetID)
+	})
+	slices.Reverse(matched)
+	return cloneAll(paginate(matched, query.Limit, query.Offset)), int64(len(matched)), nil
+}
@@ -2,7 +2,6 @@ package repository
 
 import (
 	"context"
-	"errors"
 
 	"github.com/AyushIIITU/virtualfit/internal/models"
 	"go.mongodb.org/mongo-driver/v2/bson"
@@ -32,16 +31,16 @@ func (m *MongoDB) FindChatsByUserID(ctx context.Context, userID bson.ObjectID) (
 	return chats, nil
 }
 
-// DeleteChatBySocketID removes a chat record by socket ID
-func (m *MongoDB) DeleteChatBySocketID(ctx context.Context, socketID string) error {
+// DeleteChatBySocketID removes one of a user's chat records by socket ID
+func (m *MongoDB) DeleteChatBySocketID(ctx context.Context, userID bson.ObjectID, socketID string) error {
 	collection := m.db.Collection("chats")
-	result, err := collection.DeleteOne(ctx, bson.M{"socket_id": socketID})
+	result, err := collection.DeleteOne(ctx, bson.M{"user_id": userID, "socket_id": socketID})
 	if err != nil {
 		return err
 	}
 
 	if result.DeletedCount == 0 {
-		return errors.New("socket ID not found")
+		return ErrSocketNotFound
 	}
 
 	return nil
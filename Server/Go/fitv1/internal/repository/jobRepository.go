@@ -0,0 +1,161 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/AyushIIITU/virtualfit/internal/models"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// EnqueueJob stores a new job in the queued state
+func (m *MongoDB) EnqueueJob(ctx context.Context, job *models.Job) (*models.Job, error) {
+	collection := m.db.Collection("jobs")
+	result, err := collection.InsertOne(ctx, job)
+	if err != nil {
+		return nil, err
+	}
+	job.ID = result.InsertedID.(bson.ObjectID)
+	return job, nil
+}
+
+// claimableFilter matches jobs that are due, or running jobs whose lease has expired
+func claimableFilter(now time.Time) bson.M {
+	return bson.M{"$or": []bson.M{
+		{"state": bson.M{"$in": []models.JobState{models.JobQueued, models.JobFailed}}, "run_at": bson.M{"$lte": now}},
+		{"state": models.JobRunning, "lease_expires_at": bson.M{"$lte": now}},
+	}}
+}
+
+// ClaimJob atomically leases the next due job for the given duration.
+// It returns ErrNotFound when no job is ready to run.
+func (m *MongoDB) ClaimJob(ctx context.Context, now time.Time, lease time.Duration) (*models.Job, error) {
+	collection := m.db.Collection("jobs")
+	opts := options.FindOneAndUpdate().
+		SetSort(bson.D{{Key: "run_at", Value: 1}}).
+		SetReturnDocument(options.After)
+
+	job := &models.Job{}
+	err := collection.FindOneAndUpdate(ctx, claimableFilter(now), bson.M{
+		"$set": bson.M{
+			"state":            models.JobRunning,
+			"lease_expires_at": now.Add(lease),
+			"updated_at":       now,
+		},
+		"$inc": bson.M{"attempts": 1},
+	}, opts).Decode(job)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return job, nil
+}
+
+// UpdateJob replaces a job the caller has claimed. The write only applies while
+// the stored attempt count still matches, so a worker whose lease expired and
+// was re-claimed elsewhere gets ErrNotFound instead of clobbering the new run.
+func (m *MongoDB) UpdateJob(ctx context.Context, job *models.Job) error {
+	job.UpdatedAt = time.Now()
+	result, err := m.db.Collection("jobs").ReplaceOne(
+		ctx,
+		bson.M{"_id": job.ID, "attempts": job.Attempts},
+		job,
+	)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// RecoverJobs puts running jobs whose lease has expired back in the queue
+func (m *MongoDB) RecoverJobs(ctx context.Context, now time.Time) (int64, error) {
+	result, err := m.db.Collection("jobs").UpdateMany(
+		ctx,
+		bson.M{"state": models.JobRunning, "lease_expires_at": bson.M{"$lte": now}},
+		bson.M{"$set": bson.M{"state": models.JobQueued, "run_at": now, "updated_at": now}},
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.ModifiedCount, nil
+}
+
+// In-memory implementation
+
+func (m *Memory) EnqueueJob(ctx context.Context, job *models.Job) (*models.Job, error) {
+	if job.ID.IsZero() {
+		job.ID = bson.NewObjectID()
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.jobs = append(m.jobs, clone(job))
+	return job, nil
+}
+
+func jobClaimable(job *models.Job, now time.Time) bool {
+	switch job.State {
+	case models.JobQueued, models.JobFailed:
+		return !job.RunAt.After(now)
+	case models.JobRunning:
+		return !job.LeaseExpiresAt.After(now)
+	}
+	return false
+}
+
+// ClaimJob leases the due job with the earliest run_at
+func (m *Memory) ClaimJob(ctx context.Context, now time.Time, lease time.Duration) (*models.Job, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var next *models.Job
+	for _, job := range m.jobs {
+		if jobClaimable(job, now) && (next == nil || job.RunAt.Before(next.RunAt)) {
+			next = job
+		}
+	}
+	if next == nil {
+		return nil, ErrNotFound
+	}
+
+	next.State = models.JobRunning
+	next.LeaseExpiresAt = now.Add(lease)
+	next.UpdatedAt = now
+	next.Attempts++
+	return clone(next), nil
+}
+
+// UpdateJob replaces a claimed job while the caller still holds its current attempt
+func (m *Memory) UpdateJob(ctx context.Context, job *models.Job) error {
+	job.UpdatedAt = time.Now()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i, existing := range m.jobs {
+		if existing.ID == job.ID && existing.Attempts == job.Attempts {
+			m.jobs[i] = clone(job)
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+// RecoverJobs puts running jobs whose lease has expired back in the queue
+func (m *Memory) RecoverJobs(ctx context.Context, now time.Time) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var recovered int64
+	for _, job := range m.jobs {
+		if job.State == models.JobRunning && !job.LeaseExpiresAt.After(now) {
+			job.State = models.JobQueued
+			job.RunAt = now
+			job.UpdatedAt = now
+			recovered++
+		}
+	}
+	return recovered, nil
+}
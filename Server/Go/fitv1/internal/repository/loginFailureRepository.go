@@ -0,0 +1,133 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/AyushIIITU/virtualfit/internal/models"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// GetLoginFailures returns the live failure record for a key, or ErrNotFound
+func (m *MongoDB) GetLoginFailures(ctx context.Context, key string, now time.Time) (*models.LoginFailures, error) {
+	failures := &models.LoginFailures{}
+	err := m.db.Collection("login_failures").FindOne(
+		ctx,
+		bson.M{"_id": key, "expires_at": bson.M{"$gt": now}},
+	).Decode(failures)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return failures, nil
+}
+
+// RecordLoginFailure atomically counts a failed login and returns the updated
+// record. An expired record starts over, as if the TTL monitor had removed it.
+func (m *MongoDB) RecordLoginFailure(ctx context.Context, key string, now, expiresAt time.Time) (*models.LoginFailures, error) {
+	live := bson.M{"$gt": bson.A{"$expires_at", now}}
+	update := bson.A{bson.M{"$set": bson.M{
+		"count":        bson.M{"$cond": bson.A{live, bson.M{"$add": bson.A{"$count", 1}}, 1}},
+		"lockouts":     bson.M{"$cond": bson.A{live, "$lockouts", 0}},
+		"locked_until": bson.M{"$cond": bson.A{live, "$locked_until", nil}},
+		"last_at":      now,
+		// A lockout may already keep the record alive for longer
+		"expires_at": bson.M{"$max": bson.A{"$expires_at", expiresAt}},
+	}}}
+
+	failures := &models.LoginFailures{}
+	err := m.db.Collection("login_failures").FindOneAndUpdate(
+		ctx,
+		bson.M{"_id": key},
+		update,
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+	).Decode(failures)
+	if err != nil {
+		return nil, err
+	}
+	return failures, nil
+}
+
+// LockLogin locks a key that has reached minCount failures and restarts its
+// count. It reports false if another caller locked it first.
+func (m *MongoDB) LockLogin(ctx context.Context, key string, minCount int, until, expiresAt time.Time) (bool, error) {
+	result, err := m.db.Collection("login_failures").UpdateOne(
+		ctx,
+		bson.M{"_id": key, "count": bson.M{"$gte": minCount}},
+		bson.M{
+			"$set": bson.M{"count": 0, "locked_until": until, "expires_at": expiresAt},
+			"$inc": bson.M{"lockouts": 1},
+		},
+	)
+	if err != nil {
+		return false, err
+	}
+	return result.MatchedCount > 0, nil
+}
+
+// ClearLoginFailures forgets the failures of a key, lifting any lockout
+func (m *MongoDB) ClearLoginFailures(ctx context.Context, key string) error {
+	_, err := m.db.Collection("login_failures").DeleteOne(ctx, bson.M{"_id": key})
+	return err
+}
+
+// In-memory implementation
+
+func (m *Memory) GetLoginFailures(ctx context.Context, key string, now time.Time) (*models.LoginFailures, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, failures := range m.loginFailures {
+		if failures.Key == key && failures.ExpiresAt.After(now) {
+			return clone(failures), nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (m *Memory) RecordLoginFailure(ctx context.Context, key string, now, expiresAt time.Time) (*models.LoginFailures, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i, failures := range m.loginFailures {
+		if failures.Key != key {
+			continue
+		}
+		if !failures.ExpiresAt.After(now) {
+			failures = &models.LoginFailures{Key: key}
+			m.loginFailures[i] = failures
+		}
+		failures.Count++
+		failures.LastAt = now
+		if expiresAt.After(failures.ExpiresAt) {
+			failures.ExpiresAt = expiresAt
+		}
+		return clone(failures), nil
+	}
+
+	failures := &models.LoginFailures{Key: key, Count: 1, LastAt: now, ExpiresAt: expiresAt}
+	m.loginFailures = append(m.loginFailures, failures)
+	return clone(failures), nil
+}
+
+func (m *Memory) LockLogin(ctx context.Context, key string, minCount int, until, expiresAt time.Time) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, failures := range m.loginFailures {
+		if failures.Key == key && failures.Count >= minCount {
+			failures.Count = 0
+			failures.Lockouts++
+			failures.LockedUntil = &until
+			failures.ExpiresAt = expiresAt
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (m *Memory) ClearLoginFailures(ctx context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.loginFailures = filterRows(m.loginFailures, func(f *models.LoginFailures) bool {
+		return f.Key != key
+	})
+	return nil
+}
@@ -0,0 +1,157 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"slices"
+
+	"github.com/AyushIIITU/virtualfit/internal/models"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+func (m *MongoDB) CreateBodyMeasurement(ctx context.Context, measurement *models.BodyMeasurement) (*models.BodyMeasurement, error) {
+	result, err := m.db.Collection("body_measurements").InsertOne(ctx, measurement)
+	if err != nil {
+		return nil, err
+	}
+	measurement.ID = result.InsertedID.(bson.ObjectID)
+	return measurement, nil
+}
+
+func (m *MongoDB) GetBodyMeasurementByID(ctx context.Context, id bson.ObjectID) (*models.BodyMeasurement, error) {
+	measurement := &models.BodyMeasurement{}
+	err := m.db.Collection("body_measurements").FindOne(ctx, bson.M{"_id": id}).Decode(measurement)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return measurement, nil
+}
+
+func (m *MongoDB) UpdateBodyMeasurement(ctx context.Context, measurement *models.BodyMeasurement) error {
+	result, err := m.db.Collection("body_measurements").ReplaceOne(ctx, bson.M{"_id": measurement.ID}, measurement)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (m *MongoDB) DeleteBodyMeasurement(ctx context.Context, id bson.ObjectID) error {
+	result, err := m.db.Collection("body_measurements").DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// ListBodyMeasurements returns a page of a user's measurements, newest first, and the total count
+func (m *MongoDB) ListBodyMeasurements(ctx context.Context, query models.MeasurementQuery) ([]*models.BodyMeasurement, int64, error) {
+	collection := m.db.Collection("body_measurements")
+	filter := bson.M{"user_id": query.UserID}
+	if query.Metric != "" {
+		filter[query.Metric] = bson.M{"$exists": true}
+	}
+	measuredAt := bson.M{}
+	if !query.From.IsZero() {
+		measuredAt["$gte"] = query.From
+	}
+	if !query.To.IsZero() {
+		measuredAt["$lt"] = query.To
+	}
+	if len(measuredAt) > 0 {
+		filter["measured_at"] = measuredAt
+	}
+
+	totalCount, err := collection.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "measured_at", Value: -1}, {Key: "_id", Value: -1}}).
+		SetLimit(int64(query.Limit)).
+		SetSkip(int64(query.Offset))
+	cursor, err := collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer cursor.Close(ctx)
+
+	var measurements []*models.BodyMeasurement
+	if err := cursor.All(ctx, &measurements); err != nil {
+		return nil, 0, err
+	}
+	return measurements, totalCount, nil
+}
+
+// In-memory implementation
+
+func (m *Memory) CreateBodyMeasurement(ctx context.Context, measurement *models.BodyMeasurement) (*models.BodyMeasurement, error) {
+	if measurement.ID.IsZero() {
+		measurement.ID = bson.NewObjectID()
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.bodyMeasurements = append(m.bodyMeasurements, clone(measurement))
+	return measurement, nil
+}
+
+func (m *Memory) GetBodyMeasurementByID(ctx context.Context, id bson.ObjectID) (*models.BodyMeasurement, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, measurement := range m.bodyMeasurements {
+		if measurement.ID == id {
+			return clone(measurement), nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (m *Memory) UpdateBodyMeasurement(ctx context.Context, measurement *models.BodyMeasurement) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i, existing := range m.bodyMeasurements {
+		if existing.ID == measurement.ID {
+			m.bodyMeasurements[i] = clone(measurement)
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+func (m *Memory) DeleteBodyMeasurement(ctx context.Context, id bson.ObjectID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i, measurement := range m.bodyMeasurements {
+		if measurement.ID == id {
+			m.bodyMeasurements = append(m.bodyMeasurements[:i], m.bodyMeasurements[i+1:]...)
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+func (m *Memory) ListBodyMeasurements(ctx context.Context, query models.MeasurementQuery) ([]*models.BodyMeasurement, int64, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	matched := filterRows(m.bodyMeasurements, func(b *models.BodyMeasurement) bool {
+		return b.UserID == query.UserID &&
+			(query.Metric == "" || b.Value(query.Metric) != nil) &&
+			(query.From.IsZero() || !b.MeasuredAt.Before(query.From)) &&
+			(query.To.IsZero() || b.MeasuredAt.Before(query.To))
+	})
+	slices.SortStableFunc(matched, func(a, b *models.BodyMeasurement) int {
+		if c := b.MeasuredAt.Compare(a.MeasuredAt); c != 0 {
+			return c
+		}
+		return bytes.Compare(b.ID[:], a.ID[:])
+	})
+	return cloneAll(paginate(matched, query.Limit, query.Offset)), int64(len(matched)), nil
+}
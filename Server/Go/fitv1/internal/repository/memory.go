@@ -0,0 +1,400 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"regexp"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/AyushIIITU/virtualfit/internal/models"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// Memory is an in-process Store with the same semantics as MongoDB.
+// Records are round-tripped through BSON on every read and write so callers
+// never share state with the store, exactly as with a real database.
+type Memory struct {
+	mu          sync.RWMutex
+	users       []*models.User
+	foodIntakes []*models.FoodIntake
+	workouts    []*models.Workout
+	chats       []*models.Chat
+	jobs        []*models.Job
+
+	bodyMeasurements []*models.BodyMeasurement
+	workoutSessions  []*models.WorkoutSession
+	programs         []*models.Program
+
+	refreshTokens []*models.RefreshToken
+	revokedTokens []*models.RevokedToken
+	oneTimeTokens []*models.OneTimeToken
+	loginFailures []*models.LoginFailures
+	// Buckets are looked up on every request, so they are kept by key
+	rateBuckets         map[string]*models.RateBucket
+	rateBucketsPrunedAt time.Time
+	auditLog            []*models.AuditEntry
+}
+
+func NewMemory() *Memory {
+	return &Memory{rateBuckets: make(map[string]*models.RateBucket)}
+}
+
+// clone copies a record through its BSON representation
+func clone[T any](v *T) *T {
+	data, err := bson.Marshal(v)
+	if err != nil {
+		panic(err)
+	}
+	out := new(T)
+	if err := bson.Unmarshal(data, out); err != nil {
+		panic(err)
+	}
+	return out
+}
+
+// cloneAll copies every record in a result set
+func cloneAll[T any](rows []*T) []*T {
+	var out []*T
+	for _, row := range rows {
+		out = append(out, clone(row))
+	}
+	return out
+}
+
+// filterRows returns the rows matching keep, in insertion order
+func filterRows[T any](rows []*T, keep func(*T) bool) []*T {
+	var out []*T
+	for _, row := range rows {
+		if keep(row) {
+			out = append(out, row)
+		}
+	}
+	return out
+}
+
+// paginate applies Mongo's skip/limit semantics, where a zero limit means no limit
+func paginate[T any](rows []*T, limit, offset int) []*T {
+	if offset >= len(rows) {
+		return nil
+	}
+	rows = rows[offset:]
+	if limit > 0 && limit < len(rows) {
+		rows = rows[:limit]
+	}
+	return rows
+}
+
+// regexFilter compiles a case-insensitive pattern like {"$regex": p, "$options": "i"}.
+// An empty pattern matches everything.
+func regexFilter(pattern string) (*regexp.Regexp, error) {
+	if pattern == "" {
+		return nil, nil
+	}
+	return regexp.Compile("(?i)" + pattern)
+}
+
+func matchString(re *regexp.Regexp, value string) bool {
+	return re == nil || re.MatchString(value)
+}
+
+func matchAny(re *regexp.Regexp, values []string) bool {
+	for _, value := range values {
+		if re.MatchString(value) {
+			return true
+		}
+	}
+	return false
+}
+
+// SeedWorkouts loads catalog entries into the store
+func (m *Memory) SeedWorkouts(workouts ...*models.Workout) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, workout := range workouts {
+		if workout.ID.IsZero() {
+			workout.ID = bson.NewObjectID()
+		}
+		m.workouts = append(m.workouts, clone(workout))
+	}
+}
+
+// LoadWorkouts seeds the catalog from a JSON array of workouts
+func (m *Memory) LoadWorkouts(r io.Reader) error {
+	var workouts []*models.Workout
+	if err := json.NewDecoder(r).Decode(&workouts); err != nil {
+		return err
+	}
+	m.SeedWorkouts(workouts...)
+	return nil
+}
+
+// User Repository
+func (m *Memory) CreateUser(ctx context.Context, user *models.User) (*models.User, error) {
+	user.CreatedAt = time.Now()
+	user.UpdatedAt = time.Now()
+	if user.ID.IsZero() {
+		user.ID = bson.NewObjectID()
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.emailTaken(user) {
+		return nil, ErrEmailTaken
+	}
+	m.users = append(m.users, clone(user))
+	return user, nil
+}
+
+func (m *Memory) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
+	email = models.NormalizeEmail(email)
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, user := range m.users {
+		if user.Email == email {
+			return clone(user), nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (m *Memory) GetUserByID(ctx context.Context, id bson.ObjectID) (*models.User, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, user := range m.users {
+		if user.ID == id {
+			return clone(user), nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (m *Memory) UpdateUser(ctx context.Context, user *models.User) error {
+	user.UpdatedAt = time.Now()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.emailTaken(user) {
+		return ErrEmailTaken
+	}
+	for i, existing := range m.users {
+		if existing.ID == user.ID {
+			m.users[i] = clone(user)
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+func (m *Memory) ConsumeMFAStep(ctx context.Context, userID bson.ObjectID, step int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, user := range m.users {
+		if user.ID == userID && user.MFALastStep < step {
+			user.MFALastStep = step
+			user.UpdatedAt = time.Now()
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+func (m *Memory) ConsumeRecoveryCode(ctx context.Context, userID bson.ObjectID, hash string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, user := range m.users {
+		if user.ID != userID {
+			continue
+		}
+		if i := slices.Index(user.MFARecoveryCodes, hash); i >= 0 {
+			user.MFARecoveryCodes = slices.Delete(user.MFARecoveryCodes, i, i+1)
+			user.UpdatedAt = time.Now()
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+// emailTaken mirrors the unique index on users.email. Callers hold m.mu.
+func (m *Memory) emailTaken(user *models.User) bool {
+	for _, existing := range m.users {
+		if existing.Email == user.Email && existing.ID != user.ID {
+			return true
+		}
+	}
+	return false
+}
+
+// Food Intake Repository
+func (m *Memory) CreateFoodIntake(ctx context.Context, foodIntake *models.FoodIntake) (*models.FoodIntake, error) {
+	if foodIntake.ID.IsZero() {
+		foodIntake.ID = bson.NewObjectID()
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.foodIntakes = append(m.foodIntakes, clone(foodIntake))
+	return foodIntake, nil
+}
+
+func (m *Memory) UpdateFoodIntake(ctx context.Context, foodIntake *models.FoodIntake) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i, existing := range m.foodIntakes {
+		if existing.ID == foodIntake.ID {
+			m.foodIntakes[i] = clone(foodIntake)
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+func (m *Memory) DeleteFoodIntake(ctx context.Context, id bson.ObjectID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i, foodIntake := range m.foodIntakes {
+		if foodIntake.ID == id {
+			m.foodIntakes = append(m.foodIntakes[:i], m.foodIntakes[i+1:]...)
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+func (m *Memory) GetFoodIntakeByID(ctx context.Context, id bson.ObjectID) (*models.FoodIntake, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, foodIntake := range m.foodIntakes {
+		if foodIntake.ID == id {
+			return clone(foodIntake), nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (m *Memory) GetFoodIntakeByImage(ctx context.Context, userID bson.ObjectID, imagePath string) (*models.FoodIntake, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, foodIntake := range m.foodIntakes {
+		if foodIntake.UserID == userID && foodIntake.ImagePath == imagePath {
+			return clone(foodIntake), nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (m *Memory) ListUserFoodIntake(ctx context.Context, userID bson.ObjectID) ([]*models.FoodIntake, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return cloneAll(filterRows(m.foodIntakes, func(f *models.FoodIntake) bool {
+		return f.UserID == userID
+	})), nil
+}
+
+// Workout Repository
+func (m *Memory) GetWorkout(ctx context.Context, nameFilter string) ([]*models.Workout, error) {
+	workouts, _, err := m.GetWorkoutPaginated(ctx, nameFilter, 0, 0)
+	return workouts, err
+}
+
+// GetWorkoutPaginated returns a paginated list of workouts and the total count
+func (m *Memory) GetWorkoutPaginated(ctx context.Context, nameFilter string, limit, offset int) ([]*models.Workout, int64, error) {
+	return m.SearchWorkouts(ctx, models.WorkoutSearchCriteria{
+		Name:   nameFilter,
+		Limit:  limit,
+		Offset: offset,
+	})
+}
+
+// SearchWorkouts searches for workouts based on the provided criteria
+func (m *Memory) SearchWorkouts(ctx context.Context, criteria models.WorkoutSearchCriteria) ([]*models.Workout, int64, error) {
+	var patterns [7]*regexp.Regexp
+	for i, pattern := range []string{
+		criteria.Name,
+		criteria.Category,
+		criteria.Level,
+		criteria.Equipment,
+		criteria.Force,
+		criteria.Mechanic,
+		criteria.Muscle,
+	} {
+		re, err := regexFilter(pattern)
+		if err != nil {
+			return nil, 0, err
+		}
+		patterns[i] = re
+	}
+	name, category, level, equipment, force, mechanic, muscle :=
+		patterns[0], patterns[1], patterns[2], patterns[3], patterns[4], patterns[5], patterns[6]
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	matched := filterRows(m.workouts, func(w *models.Workout) bool {
+		return matchString(name, w.Name) &&
+			matchString(category, w.Category) &&
+			matchString(level, w.Level) &&
+			matchString(equipment, w.Equipment) &&
+			matchString(force, w.Force) &&
+			matchString(mechanic, w.Mechanic) &&
+			(muscle == nil || matchAny(muscle, w.PrimaryMuscles) || matchAny(muscle, w.SecondaryMuscles))
+	})
+
+	return cloneAll(paginate(matched, criteria.Limit, criteria.Offset)), int64(len(matched)), nil
+}
+
+func (m *Memory) GetWorkoutByID(ctx context.Context, id bson.ObjectID) (*models.Workout, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, workout := range m.workouts {
+		if workout.ID == id {
+			return clone(workout), nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (m *Memory) ListMuscles(ctx context.Context) ([]string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var muscles []string
+	for _, workout := range m.workouts {
+		muscles = append(muscles, workout.PrimaryMuscles...)
+		muscles = append(muscles, workout.SecondaryMuscles...)
+	}
+	slices.Sort(muscles)
+	return slices.Compact(muscles), nil
+}
+
+// Chat Repository
+func (m *Memory) SaveChat(ctx context.Context, chat *models.Chat) error {
+	if chat.ID.IsZero() {
+		chat.ID = bson.NewObjectID()
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.chats = append(m.chats, clone(chat))
+	return nil
+}
+
+func (m *Memory) FindChatsByUserID(ctx context.Context, userID bson.ObjectID) ([]*models.Chat, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return cloneAll(filterRows(m.chats, func(c *models.Chat) bool {
+		return c.UserID == userID
+	})), nil
+}
+
+func (m *Memory) DeleteChatBySocketID(ctx context.Context, userID bson.ObjectID, socketID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i, chat := range m.chats {
+		if chat.UserID == userID && chat.SocketID == socketID {
+			m.chats = append(m.chats[:i], m.chats[i+1:]...)
+			return nil
+		}
+	}
+	return ErrSocketNotFound
+}
@@ -0,0 +1,298 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/AyushIIITU/virtualfit/internal/models"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// catalog seeds a store with workouts named "Workout 0" to "Workout n-1"
+func catalog(t *testing.T, n int) *Memory {
+	t.Helper()
+	m := NewMemory()
+	for i := 0; i < n; i++ {
+		m.SeedWorkouts(&models.Workout{Name: fmt.Sprintf("Workout %d", i), Level: "beginner"})
+	}
+	return m
+}
+
+func names(workouts []*models.Workout) []string {
+	var out []string
+	for _, w := range workouts {
+		out = append(out, w.Name)
+	}
+	return out
+}
+
+func TestMemoryPagination(t *testing.T) {
+	m := catalog(t, 5)
+	tests := []struct {
+		limit, offset int
+		want          []string
+	}{
+		{0, 0, []string{"Workout 0", "Workout 1", "Workout 2", "Workout 3", "Workout 4"}}, // no limit
+		{2, 0, []string{"Workout 0", "Workout 1"}},
+		{2, 2, []string{"Workout 2", "Workout 3"}},
+		{2, 4, []string{"Workout 4"}},
+		{10, 1, []string{"Workout 1", "Workout 2", "Workout 3", "Workout 4"}},
+		{2, 5, nil}, // past the end
+		{0, 9, nil},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("limit=%d,offset=%d", tt.limit, tt.offset), func(t *testing.T) {
+			got, total, err := m.GetWorkoutPaginated(context.Background(), "", tt.limit, tt.offset)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if total != 5 {
+				t.Errorf("total = %d, want 5 whatever the page", total)
+			}
+			if !slices.Equal(names(got), tt.want) {
+				t.Errorf("got %v, want %v", names(got), tt.want)
+			}
+		})
+	}
+}
+
+func TestMemorySessionsNewestFirst(t *testing.T) {
+	m := NewMemory()
+	ctx := context.Background()
+	userID := bson.NewObjectID()
+	start := time.Date(2024, 3, 1, 8, 0, 0, 0, time.UTC)
+	for i := 0; i < 4; i++ {
+		if _, err := m.CreateWorkoutSession(ctx, &models.WorkoutSession{
+			UserID:    userID,
+			Name:      fmt.Sprintf("day %d", i),
+			StartedAt: start.AddDate(0, 0, i),
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Another user's session is never counted
+	if _, err := m.CreateWorkoutSession(ctx, &models.WorkoutSession{UserID: bson.NewObjectID(), StartedAt: start}); err != nil {
+		t.Fatal(err)
+	}
+
+	got, total, err := m.ListWorkoutSessions(ctx, models.WorkoutSessionQuery{UserID: userID, Limit: 2, Offset: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 4 {
+		t.Errorf("total = %d, want 4", total)
+	}
+	var gotNames []string
+	for _, s := range got {
+		gotNames = append(gotNames, s.Name)
+	}
+	if want := []string{"day 2", "day 1"}; !slices.Equal(gotNames, want) {
+		t.Errorf("got %v, want %v", gotNames, want)
+	}
+}
+
+func TestMemoryRegexSearch(t *testing.T) {
+	m := NewMemory()
+	m.SeedWorkouts(
+		&models.Workout{Name: "Barbell Squat", Equipment: "barbell", PrimaryMuscles: []string{"quadriceps"}},
+		&models.Workout{Name: "Barbell Bench Press", Equipment: "barbell", PrimaryMuscles: []string{"chest"}, SecondaryMuscles: []string{"triceps"}},
+		&models.Workout{Name: "Triceps Pushdown", Equipment: "cable", PrimaryMuscles: []string{"triceps"}},
+		&models.Workout{Name: "Goblet Squat", Equipment: "kettlebells", PrimaryMuscles: []string{"quadriceps"}},
+	)
+	tests := []struct {
+		name     string
+		criteria models.WorkoutSearchCriteria
+		want     []string
+	}{
+		{"empty matches all", models.WorkoutSearchCriteria{}, []string{"Barbell Squat", "Barbell Bench Press", "Triceps Pushdown", "Goblet Squat"}},
+		{"case-insensitive", models.WorkoutSearchCriteria{Name: "SQUAT"}, []string{"Barbell Squat", "Goblet Squat"}},
+		{"substring", models.WorkoutSearchCriteria{Name: "bell"}, []string{"Barbell Squat", "Barbell Bench Press"}},
+		{"anchored", models.WorkoutSearchCriteria{Name: "^squat"}, nil},
+		{"alternation", models.WorkoutSearchCriteria{Name: "bench|goblet"}, []string{"Barbell Bench Press", "Goblet Squat"}},
+		{"fields combine", models.WorkoutSearchCriteria{Name: "squat", Equipment: "barbell"}, []string{"Barbell Squat"}},
+		{"secondary muscles", models.WorkoutSearchCriteria{Muscle: "tricep"}, []string{"Barbell Bench Press", "Triceps Pushdown"}},
+		{"page of matches", models.WorkoutSearchCriteria{Name: "a", Limit: 1, Offset: 1}, []string{"Barbell Bench Press"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, _, err := m.SearchWorkouts(context.Background(), tt.criteria)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(names(got), tt.want) {
+				t.Errorf("got %v, want %v", names(got), tt.want)
+			}
+		})
+	}
+
+	if _, _, err := m.SearchWorkouts(context.Background(), models.WorkoutSearchCriteria{Name: "("}); err == nil {
+		t.Error("an invalid pattern should fail, as it does in MongoDB")
+	}
+}
+
+func TestMemoryNotFound(t *testing.T) {
+	m := NewMemory()
+	ctx := context.Background()
+	missing := bson.NewObjectID()
+	tests := []struct {
+		name string
+		call func() error
+	}{
+		{"GetUserByID", func() error { _, err := m.GetUserByID(ctx, missing); return err }},
+		{"GetUserByEmail", func() error { _, err := m.GetUserByEmail(ctx, "nobody@example.com"); return err }},
+		{"UpdateUser", func() error { return m.UpdateUser(ctx, &models.User{ID: missing, Email: "a@example.com"}) }},
+		{"GetFoodIntakeByID", func() error { _, err := m.GetFoodIntakeByID(ctx, missing); return err }},
+		{"UpdateFoodIntake", func() error { return m.UpdateFoodIntake(ctx, &models.FoodIntake{ID: missing}) }},
+		{"DeleteFoodIntake", func() error { return m.DeleteFoodIntake(ctx, missing) }},
+		{"GetWorkoutByID", func() error { _, err := m.GetWorkoutByID(ctx, missing); return err }},
+		{"DeleteChatBySocketID", func() error { return m.DeleteChatBySocketID(ctx, missing, "socket") }},
+		{"GetWorkoutSessionByID", func() error { _, err := m.GetWorkoutSessionByID(ctx, missing); return err }},
+		{"UpdateWorkoutSession", func() error { return m.UpdateWorkoutSession(ctx, &models.WorkoutSession{ID: missing}, time.Time{}) }},
+		{"DeleteWorkoutSession", func() error { return m.DeleteWorkoutSession(ctx, missing) }},
+		{"GetBodyMeasurementByID", func() error { _, err := m.GetBodyMeasurementByID(ctx, missing); return err }},
+		{"GetProgramByID", func() error { _, err := m.GetProgramByID(ctx, missing); return err }},
+		{"DeleteProgram", func() error { return m.DeleteProgram(ctx, missing) }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.call(); !errors.Is(err, ErrNotFound) {
+				t.Errorf("err = %v, want ErrNotFound", err)
+			}
+		})
+	}
+}
+
+func TestMemoryCopiesRecords(t *testing.T) {
+	m := NewMemory()
+	ctx := context.Background()
+	user, err := m.CreateUser(ctx, &models.User{Email: "a@example.com", Goals: []string{"strength"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	user.Goals[0] = "changed"
+
+	got, err := m.GetUserByID(ctx, user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Goals[0] != "strength" {
+		t.Error("changing a saved record changed the store")
+	}
+	got.Goals[0] = "changed again"
+	if again, _ := m.GetUserByID(ctx, user.ID); again.Goals[0] != "strength" {
+		t.Error("changing a read record changed the store")
+	}
+
+	if _, err := m.CreateUser(ctx, &models.User{Email: "a@example.com"}); !errors.Is(err, ErrEmailTaken) {
+		t.Errorf("err = %v, want ErrEmailTaken", err)
+	}
+	if got, err := m.GetUserByEmail(ctx, " A@Example.COM "); err != nil || got.ID != user.ID {
+		t.Errorf("lookup ignoring case = %v, %v", got, err)
+	}
+}
+
+func TestMemoryConsumeSecondFactor(t *testing.T) {
+	m := NewMemory()
+	ctx := context.Background()
+	user, err := m.CreateUser(ctx, &models.User{Email: "a@example.com", MFALastStep: 10, MFARecoveryCodes: []string{"one", "two"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		step int64
+		want error
+	}{{10, ErrNotFound}, {9, ErrNotFound}, {11, nil}, {11, ErrNotFound}} {
+		if err := m.ConsumeMFAStep(ctx, user.ID, tt.step); !errors.Is(err, tt.want) {
+			t.Errorf("ConsumeMFAStep(%d) = %v, want %v", tt.step, err, tt.want)
+		}
+	}
+	for _, tt := range []struct {
+		hash string
+		want error
+	}{{"two", nil}, {"two", ErrNotFound}, {"three", ErrNotFound}} {
+		if err := m.ConsumeRecoveryCode(ctx, user.ID, tt.hash); !errors.Is(err, tt.want) {
+			t.Errorf("ConsumeRecoveryCode(%q) = %v, want %v", tt.hash, err, tt.want)
+		}
+	}
+	if err := m.ConsumeMFAStep(ctx, bson.NewObjectID(), 100); !errors.Is(err, ErrNotFound) {
+		t.Errorf("unknown user: %v, want ErrNotFound", err)
+	}
+
+	got, err := m.GetUserByID(ctx, user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.MFALastStep != 11 || !slices.Equal(got.MFARecoveryCodes, []string{"one"}) {
+		t.Errorf("stored step %d and codes %v, want 11 and [one]", got.MFALastStep, got.MFARecoveryCodes)
+	}
+}
+
+func TestMemoryUpdateWorkoutSessionConflict(t *testing.T) {
+	m := NewMemory()
+	ctx := context.Background()
+	read := time.Date(2024, 3, 1, 7, 0, 0, 0, time.UTC)
+	session, err := m.CreateWorkoutSession(ctx, &models.WorkoutSession{Name: "Legs", UpdatedAt: read})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Two edits from the same read: the first is saved, the second conflicts
+	first, second := *session, *session
+	first.Name, first.UpdatedAt = "First", read.Add(time.Millisecond)
+	second.Name, second.UpdatedAt = "Second", read.Add(2*time.Millisecond)
+	if err := m.UpdateWorkoutSession(ctx, &first, read); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.UpdateWorkoutSession(ctx, &second, read); !errors.Is(err, ErrConflict) {
+		t.Errorf("stale update: %v, want ErrConflict", err)
+	}
+	if got, _ := m.GetWorkoutSessionByID(ctx, session.ID); got.Name != "First" {
+		t.Errorf("stored %q, want the first edit", got.Name)
+	}
+
+	// Redone on what is stored now, it goes through
+	if err := m.UpdateWorkoutSession(ctx, &second, first.UpdatedAt); err != nil {
+		t.Errorf("update of the current version: %v", err)
+	}
+}
+
+func TestMemoryTakeRateToken(t *testing.T) {
+	m := NewMemory()
+	ctx := context.Background()
+	start := time.Date(2024, 3, 1, 7, 0, 0, 0, time.UTC)
+
+	// Three requests per 30 seconds: one token back every 10 seconds
+	tests := []struct {
+		name    string
+		key     string
+		after   time.Duration
+		allowed bool
+		tokens  float64
+		full    time.Duration // until the bucket is full again
+	}{
+		{"first request", "a", 0, true, 2, 10 * time.Second},
+		{"second request", "a", 0, true, 1, 20 * time.Second},
+		{"third request", "a", 0, true, 0, 30 * time.Second},
+		{"bucket empty", "a", 0, false, 0, 30 * time.Second},
+		{"half a token back", "a", 5 * time.Second, false, 0.5, 25 * time.Second},
+		{"a whole token back", "a", 10 * time.Second, true, 0, 30 * time.Second},
+		{"other callers have their own bucket", "b", 10 * time.Second, true, 2, 10 * time.Second},
+		{"refills no further than capacity", "a", time.Hour, true, 2, 10 * time.Second},
+	}
+	for _, tt := range tests {
+		now := start.Add(tt.after)
+		bucket, err := m.TakeRateToken(ctx, tt.key, 3, 30*time.Second, now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if bucket.Allowed != tt.allowed || bucket.Tokens != tt.tokens || !bucket.ExpiresAt.Equal(now.Add(tt.full)) {
+			t.Errorf("%s: allowed %v with %g tokens, full in %s; want %v, %g, %s",
+				tt.name, bucket.Allowed, bucket.Tokens, bucket.ExpiresAt.Sub(now), tt.allowed, tt.tokens, tt.full)
+		}
+	}
+}
@@ -2,6 +2,9 @@ package repository
 
 import (
 	"context"
+	"fmt"
+	"slices"
+	"strings"
 	"time"
 
 	// "github.com/AyushIIITU/virtualfit/internal/models"
@@ -33,7 +36,7 @@ func (m *MongoDB) CreateUser(ctx context.Context, user *models.User) (*models.Us
 	collection := m.db.Collection("users")
 	result, err := collection.InsertOne(ctx, user)
 	if err != nil {
-		return nil, err
+		return nil, emailTaken(err)
 	}
 	user.ID = result.InsertedID.(bson.ObjectID)
 	return user, nil
@@ -42,9 +45,9 @@ func (m *MongoDB) CreateUser(ctx context.Context, user *models.User) (*models.Us
 func (m *MongoDB) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
 	collection := m.db.Collection("users")
 	user := &models.User{}
-	err := collection.FindOne(ctx, bson.M{"email": email}).Decode(user)
+	err := collection.FindOne(ctx, bson.M{"email": models.NormalizeEmail(email)}).Decode(user)
 	if err != nil {
-		return nil, err
+		return nil, notFound(err)
 	}
 	return user, nil
 }
@@ -54,57 +57,101 @@ func (m *MongoDB) GetUserByID(ctx context.Context, id bson.ObjectID) (*models.Us
 	user := &models.User{}
 	err := collection.FindOne(ctx, bson.M{"_id": id}).Decode(user)
 	if err != nil {
-		return nil, err
+		return nil, notFound(err)
 	}
 	return user, nil
 }
 
 func (m *MongoDB) UpdateUser(ctx context.Context, user *models.User) error {
 	user.UpdatedAt = time.Now()
-	_, err := m.db.Collection("users").UpdateOne(
+	result, err := m.db.Collection("users").UpdateOne(
 		ctx,
 		bson.M{"_id": user.ID},
 		bson.M{"$set": user},
 	)
-	return err
+	if err != nil {
+		return emailTaken(err)
+	}
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
 }
 
-// Exercise Repository
-func (m *MongoDB) CreateExercise(ctx context.Context, exercise *models.Exercise) (*models.Exercise, error) {
-	exercise.CreatedAt = time.Now()
-
-	collection := m.db.Collection("exercises")
-	result, err := collection.InsertOne(ctx, exercise)
+// ConsumeMFAStep records a TOTP time step as used, unless it or a later one
+// already was
+func (m *MongoDB) ConsumeMFAStep(ctx context.Context, userID bson.ObjectID, step int64) error {
+	result, err := m.db.Collection("users").UpdateOne(
+		ctx,
+		bson.M{"_id": userID, "mfa_last_step": bson.M{"$lt": step}},
+		bson.M{"$set": bson.M{"mfa_last_step": step, "updated_at": time.Now()}},
+	)
 	if err != nil {
-		return nil, err
+		return err
 	}
-	exercise.ID = result.InsertedID.(bson.ObjectID)
-	return exercise, nil
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
 }
 
-func (m *MongoDB) GetExerciseByID(ctx context.Context, id bson.ObjectID) (*models.Exercise, error) {
-	collection := m.db.Collection("exercises")
-	exercise := &models.Exercise{}
-	err := collection.FindOne(ctx, bson.M{"_id": id}).Decode(exercise)
+// ConsumeRecoveryCode removes a recovery code the user still holds
+func (m *MongoDB) ConsumeRecoveryCode(ctx context.Context, userID bson.ObjectID, hash string) error {
+	result, err := m.db.Collection("users").UpdateOne(
+		ctx,
+		bson.M{"_id": userID, "mfa_recovery_codes": hash},
+		bson.M{
+			"$pull": bson.M{"mfa_recovery_codes": hash},
+			"$set":  bson.M{"updated_at": time.Now()},
+		},
+	)
 	if err != nil {
-		return nil, err
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrNotFound
 	}
-	return exercise, nil
+	return nil
 }
 
-func (m *MongoDB) ListExercises(ctx context.Context, filter bson.M) ([]*models.Exercise, error) {
-	collection := m.db.Collection("exercises")
-	cursor, err := collection.Find(ctx, filter)
+// normalizedEmail is models.NormalizeEmail as an aggregation expression
+var normalizedEmail = bson.M{"$toLower": bson.M{"$trim": bson.M{"input": "$email"}}}
+
+// NormalizeEmails brings stored addresses into the form of
+// models.NormalizeEmail and returns how many changed. Addresses stored before
+// emails were normalized may differ only in case; then nothing is changed and
+// the accounts have to be merged by hand first.
+func (m *MongoDB) NormalizeEmails(ctx context.Context) (int64, error) {
+	collection := m.db.Collection("users")
+	cursor, err := collection.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$group", Value: bson.M{"_id": normalizedEmail, "emails": bson.M{"$push": "$email"}, "count": bson.M{"$sum": 1}}}},
+		{{Key: "$match", Value: bson.M{"count": bson.M{"$gt": 1}}}},
+	})
 	if err != nil {
-		return nil, err
+		return 0, err
+	}
+	var duplicates []struct {
+		Emails []string `bson:"emails"`
+	}
+	if err := cursor.All(ctx, &duplicates); err != nil {
+		return 0, err
+	}
+	if len(duplicates) > 0 {
+		groups := make([]string, len(duplicates))
+		for i, d := range duplicates {
+			groups[i] = strings.Join(d.Emails, ", ")
+		}
+		return 0, fmt.Errorf("accounts whose emails differ only in case: %s", strings.Join(groups, "; "))
 	}
-	defer cursor.Close(ctx)
 
-	var exercises []*models.Exercise
-	if err = cursor.All(ctx, &exercises); err != nil {
-		return nil, err
+	result, err := collection.UpdateMany(ctx,
+		bson.M{"$expr": bson.M{"$ne": bson.A{"$email", normalizedEmail}}},
+		mongo.Pipeline{{{Key: "$set", Value: bson.M{"email": normalizedEmail}}}},
+	)
+	if err != nil {
+		return 0, err
 	}
-	return exercises, nil
+	return result.ModifiedCount, nil
 }
 
 // Food Intake Repository
@@ -114,19 +161,35 @@ func (m *MongoDB) CreateFoodIntake(ctx context.Context, foodIntake *models.FoodI
 	if err != nil {
 		return nil, err
 	}
-	// Convert ObjectID to string if foodIntake.ID is a string type
-	foodIntake.ID = result.InsertedID.(bson.ObjectID).Hex()
+	foodIntake.ID = result.InsertedID.(bson.ObjectID)
 	return foodIntake, nil
 }
 
 func (m *MongoDB) UpdateFoodIntake(ctx context.Context, foodIntake *models.FoodIntake) error {
 	collection := m.db.Collection("food_intakes")
-	_, err := collection.UpdateOne(
+	result, err := collection.UpdateOne(
 		ctx,
 		bson.M{"_id": foodIntake.ID},
 		bson.M{"$set": foodIntake},
 	)
-	return err
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (m *MongoDB) DeleteFoodIntake(ctx context.Context, id bson.ObjectID) error {
+	result, err := m.db.Collection("food_intakes").DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
 }
 
 func (m *MongoDB) GetFoodIntakeByID(ctx context.Context, id bson.ObjectID) (*models.FoodIntake, error) {
@@ -134,14 +197,24 @@ func (m *MongoDB) GetFoodIntakeByID(ctx context.Context, id bson.ObjectID) (*mod
 	foodIntake := &models.FoodIntake{}
 	err := collection.FindOne(ctx, bson.M{"_id": id}).Decode(foodIntake)
 	if err != nil {
-		return nil, err
+		return nil, notFound(err)
+	}
+	return foodIntake, nil
+}
+
+// GetFoodIntakeByImage returns one of a user's entries stored with the image
+func (m *MongoDB) GetFoodIntakeByImage(ctx context.Context, userID bson.ObjectID, imagePath string) (*models.FoodIntake, error) {
+	foodIntake := &models.FoodIntake{}
+	err := m.db.Collection("food_intakes").FindOne(ctx, bson.M{"users": userID, "imagePath": imagePath}).Decode(foodIntake)
+	if err != nil {
+		return nil, notFound(err)
 	}
 	return foodIntake, nil
 }
 
 func (m *MongoDB) ListUserFoodIntake(ctx context.Context, userID bson.ObjectID) ([]*models.FoodIntake, error) {
 	collection := m.db.Collection("food_intakes")
-	cursor, err := collection.Find(ctx, bson.M{"user_id": userID})
+	cursor, err := collection.Find(ctx, bson.M{"users": userID})
 	if err != nil {
 		return nil, err
 	}
@@ -288,12 +361,24 @@ func (m *MongoDB) SearchWorkouts(ctx context.Context, criteria models.WorkoutSea
 func (m *MongoDB) GetWorkoutByID(ctx context.Context, id bson.ObjectID) (*models.Workout, error) {
 	collection := m.db.Collection("workouts")
 	workout := &models.Workout{}
-	err := collection.FindOne(ctx, bson.M{"id": id}).Decode(workout)
+	err := collection.FindOne(ctx, bson.M{"_id": id}).Decode(workout)
 	if err != nil {
-		if err == mongo.ErrNoDocuments {
-			return nil, nil
-		}
-		return nil, err
+		return nil, notFound(err)
 	}
 	return workout, nil
 }
+
+// ListMuscles returns every primary or secondary muscle in the catalog, sorted
+func (m *MongoDB) ListMuscles(ctx context.Context) ([]string, error) {
+	collection := m.db.Collection("workouts")
+	var muscles []string
+	for _, field := range []string{"primaryMuscles", "secondaryMuscles"} {
+		var values []string
+		if err := collection.Distinct(ctx, field, bson.M{}).Decode(&values); err != nil {
+			return nil, err
+		}
+		muscles = append(muscles, values...)
+	}
+	slices.Sort(muscles)
+	return slices.Compact(muscles), nil
+}
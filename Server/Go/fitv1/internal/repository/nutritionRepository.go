@@ -0,0 +1,122 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/AyushIIITU/virtualfit/internal/models"
+	"github.com/AyushIIITU/virtualfit/internal/timeutil"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// AggregateNutrition sums every nutrient per period and meal type. Periods are
+// cut in the query's timezone, with weeks starting on the query's week start.
+func (m *MongoDB) AggregateNutrition(ctx context.Context, query models.NutritionQuery) ([]models.NutritionBucket, error) {
+	dateTrunc := bson.M{
+		"date":     "$date",
+		"unit":     query.Granularity,
+		"timezone": query.Timezone,
+	}
+	if query.Granularity == timeutil.Week {
+		dateTrunc["startOfWeek"] = strings.ToLower(query.WeekStart.String())
+	}
+
+	pipeline := []bson.M{
+		{"$match": bson.M{
+			"users": query.UserID,
+			"date":  bson.M{"$gte": query.From, "$lt": query.To},
+		}},
+		{"$unwind": "$nutrients"},
+		{"$group": bson.M{
+			"_id": bson.M{
+				"period":    bson.M{"$dateTrunc": dateTrunc},
+				"meal_type": "$mealType",
+				"nutrient":  "$nutrients.name",
+				"unit":      "$nutrients.unit",
+			},
+			"amount":  bson.M{"$sum": "$nutrients.amount"},
+			"entries": bson.M{"$addToSet": "$_id"},
+		}},
+		{"$project": bson.M{
+			"_id":       0,
+			"period":    "$_id.period",
+			"meal_type": "$_id.meal_type",
+			"nutrient":  "$_id.nutrient",
+			"unit":      "$_id.unit",
+			"amount":    1,
+			"entries":   bson.M{"$size": "$entries"},
+		}},
+		{"$sort": bson.D{
+			{Key: "period", Value: 1},
+			{Key: "meal_type", Value: 1},
+			{Key: "nutrient", Value: 1},
+		}},
+	}
+
+	cursor, err := m.db.Collection("food_intakes").Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var buckets []models.NutritionBucket
+	if err := cursor.All(ctx, &buckets); err != nil {
+		return nil, err
+	}
+	return buckets, nil
+}
+
+// In-memory implementation
+
+func (m *Memory) AggregateNutrition(ctx context.Context, query models.NutritionQuery) ([]models.NutritionBucket, error) {
+	loc, err := time.LoadLocation(query.Timezone)
+	if err != nil {
+		return nil, err
+	}
+
+	type key struct {
+		period                   time.Time
+		mealType, nutrient, unit string
+	}
+	totals := make(map[key]*models.NutritionBucket)
+	entries := make(map[key]map[bson.ObjectID]bool)
+
+	m.mu.RLock()
+	for _, foodIntake := range m.foodIntakes {
+		if foodIntake.UserID != query.UserID || foodIntake.Date.Before(query.From) || !foodIntake.Date.Before(query.To) {
+			continue
+		}
+		period := timeutil.Truncate(foodIntake.Date, query.Granularity, loc, query.WeekStart).UTC()
+		for _, nutrient := range foodIntake.Nutrients {
+			k := key{period, foodIntake.MealType, nutrient.Name, nutrient.Unit}
+			bucket, ok := totals[k]
+			if !ok {
+				bucket = &models.NutritionBucket{Period: period, MealType: k.mealType, Nutrient: k.nutrient, Unit: k.unit}
+				totals[k] = bucket
+				entries[k] = make(map[bson.ObjectID]bool)
+			}
+			bucket.Amount += nutrient.Amount
+			entries[k][foodIntake.ID] = true
+		}
+	}
+	m.mu.RUnlock()
+
+	var buckets []models.NutritionBucket
+	for k, bucket := range totals {
+		bucket.Entries = len(entries[k])
+		buckets = append(buckets, *bucket)
+	}
+	sort.Slice(buckets, func(i, j int) bool {
+		a, b := buckets[i], buckets[j]
+		if !a.Period.Equal(b.Period) {
+			return a.Period.Before(b.Period)
+		}
+		if a.MealType != b.MealType {
+			return a.MealType < b.MealType
+		}
+		return a.Nutrient < b.Nutrient
+	})
+	return buckets, nil
+}
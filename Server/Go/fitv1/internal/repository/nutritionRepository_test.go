@@ -0,0 +1,129 @@
+package repository
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/AyushIIITU/virtualfit/internal/models"
+	"github.com/AyushIIITU/virtualfit/internal/timeutil"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// nutritionStores returns the stores whose aggregations must agree: the
+// in-memory one, and MongoDB when TEST_MONGO_URI names a server to run
+// $dateTrunc on. Each MongoDB run gets its own database, dropped afterwards.
+func nutritionStores(t *testing.T) map[string]Store {
+	t.Helper()
+	stores := map[string]Store{"memory": NewMemory()}
+	uri := os.Getenv("TEST_MONGO_URI")
+	if uri == "" {
+		return stores
+	}
+
+	client, err := mongo.Connect(options.Client().ApplyURI(uri))
+	if err != nil {
+		t.Fatal(err)
+	}
+	db := NewMongoDB(client, "fitv1_test_"+bson.NewObjectID().Hex())
+	t.Cleanup(func() {
+		ctx := context.Background()
+		db.db.Drop(ctx)
+		client.Disconnect(ctx)
+	})
+	stores["mongodb"] = db
+	return stores
+}
+
+func TestAggregateNutritionAcrossTimezoneBoundaries(t *testing.T) {
+	ctx := context.Background()
+	userID := bson.NewObjectID()
+	utc := func(s string) time.Time {
+		t.Helper()
+		v, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return v
+	}
+
+	// New York moves to daylight time at 2am on Sunday 10 March 2024, so its
+	// midnights are 05:00 UTC until then and 04:00 UTC after
+	logged := []string{
+		"2024-03-09T04:30:00Z", // Friday 23:30 EST
+		"2024-03-09T05:30:00Z", // Saturday 00:30 EST
+		"2024-03-10T04:59:00Z", // Saturday 23:59 EST, Sunday in UTC
+		"2024-03-10T05:00:00Z", // Sunday 00:00 EST
+		"2024-03-11T03:30:00Z", // Sunday 23:30 EDT
+		"2024-03-11T04:00:00Z", // Monday 00:00 EDT
+	}
+	tests := []struct {
+		granularity string
+		weekStart   time.Weekday
+		want        map[string]int // period start in UTC: entries
+	}{
+		{timeutil.Day, time.Monday, map[string]int{
+			"2024-03-08T05:00:00Z": 1,
+			"2024-03-09T05:00:00Z": 2,
+			"2024-03-10T05:00:00Z": 2,
+			"2024-03-11T04:00:00Z": 1,
+		}},
+		{timeutil.Week, time.Monday, map[string]int{
+			"2024-03-04T05:00:00Z": 5,
+			"2024-03-11T04:00:00Z": 1,
+		}},
+		{timeutil.Week, time.Sunday, map[string]int{
+			"2024-03-03T05:00:00Z": 3,
+			"2024-03-10T05:00:00Z": 3,
+		}},
+	}
+
+	for name, store := range nutritionStores(t) {
+		for _, date := range logged {
+			_, err := store.CreateFoodIntake(ctx, &models.FoodIntake{
+				UserID:    userID,
+				FoodName:  "Oats",
+				Date:      utc(date),
+				MealType:  "breakfast",
+				Nutrients: []models.Nutrient{{Name: models.NutrientCalories, Amount: 100, Unit: "kcal"}},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		for _, tt := range tests {
+			buckets, err := store.AggregateNutrition(ctx, models.NutritionQuery{
+				UserID:      userID,
+				From:        utc("2024-03-01T00:00:00Z"),
+				To:          utc("2024-03-15T00:00:00Z"),
+				Granularity: tt.granularity,
+				Timezone:    "America/New_York",
+				WeekStart:   tt.weekStart,
+			})
+			if err != nil {
+				t.Fatalf("%s: %v", name, err)
+			}
+			got := make(map[string]int)
+			for _, bucket := range buckets {
+				got[bucket.Period.UTC().Format(time.RFC3339)] = bucket.Entries
+				if bucket.Amount != float64(100*bucket.Entries) {
+					t.Errorf("%s %s: %g kcal in %d entries", name, tt.granularity, bucket.Amount, bucket.Entries)
+				}
+			}
+			if len(got) != len(tt.want) {
+				t.Errorf("%s %s from %s: periods %v, want %v", name, tt.granularity, tt.weekStart, got, tt.want)
+				continue
+			}
+			for period, entries := range tt.want {
+				if got[period] != entries {
+					t.Errorf("%s %s from %s: periods %v, want %v", name, tt.granularity, tt.weekStart, got, tt.want)
+					break
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/AyushIIITU/virtualfit/internal/models"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// CreateOneTimeToken stores a newly issued one-time token
+func (m *MongoDB) CreateOneTimeToken(ctx context.Context, token *models.OneTimeToken) error {
+	if token.ID.IsZero() {
+		token.ID = bson.NewObjectID()
+	}
+	_, err := m.db.Collection("one_time_tokens").InsertOne(ctx, token)
+	return err
+}
+
+// ConsumeOneTimeToken atomically marks an unused, unexpired token as used and
+// returns it. It returns ErrNotFound for unknown, used or expired tokens.
+func (m *MongoDB) ConsumeOneTimeToken(ctx context.Context, hash, purpose string, now time.Time) (*models.OneTimeToken, error) {
+	token := &models.OneTimeToken{}
+	err := m.db.Collection("one_time_tokens").FindOneAndUpdate(
+		ctx,
+		bson.M{
+			"token_hash": hash,
+			"purpose":    purpose,
+			"used_at":    nil,
+			"expires_at": bson.M{"$gt": now},
+		},
+		bson.M{"$set": bson.M{"used_at": now}},
+	).Decode(token)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	token.UsedAt = &now
+	return token, nil
+}
+
+// InvalidateOneTimeTokens marks a user's outstanding tokens for a purpose as used
+func (m *MongoDB) InvalidateOneTimeTokens(ctx context.Context, userID bson.ObjectID, purpose string, now time.Time) error {
+	_, err := m.db.Collection("one_time_tokens").UpdateMany(
+		ctx,
+		bson.M{"user_id": userID, "purpose": purpose, "used_at": nil},
+		bson.M{"$set": bson.M{"used_at": now}},
+	)
+	return err
+}
+
+// In-memory implementation
+
+func (m *Memory) CreateOneTimeToken(ctx context.Context, token *models.OneTimeToken) error {
+	if token.ID.IsZero() {
+		token.ID = bson.NewObjectID()
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.oneTimeTokens = append(m.oneTimeTokens, clone(token))
+	return nil
+}
+
+func (m *Memory) ConsumeOneTimeToken(ctx context.Context, hash, purpose string, now time.Time) (*models.OneTimeToken, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, token := range m.oneTimeTokens {
+		if token.TokenHash == hash && token.Purpose == purpose && token.UsedAt == nil && token.ExpiresAt.After(now) {
+			token.UsedAt = &now
+			return clone(token), nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (m *Memory) InvalidateOneTimeTokens(ctx context.Context, userID bson.ObjectID, purpose string, now time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, token := range m.oneTimeTokens {
+		if token.UserID == userID && token.Purpose == purpose && token.UsedAt == nil {
+			token.UsedAt = &now
+		}
+	}
+	return nil
+}
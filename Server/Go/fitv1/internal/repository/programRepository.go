@@ -0,0 +1,141 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"slices"
+
+	"github.com/AyushIIITU/virtualfit/internal/models"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+func (m *MongoDB) CreateProgram(ctx context.Context, program *models.Program) (*models.Program, error) {
+	result, err := m.db.Collection("programs").InsertOne(ctx, program)
+	if err != nil {
+		return nil, err
+	}
+	program.ID = result.InsertedID.(bson.ObjectID)
+	return program, nil
+}
+
+func (m *MongoDB) GetProgramByID(ctx context.Context, id bson.ObjectID) (*models.Program, error) {
+	program := &models.Program{}
+	err := m.db.Collection("programs").FindOne(ctx, bson.M{"_id": id}).Decode(program)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return program, nil
+}
+
+func (m *MongoDB) UpdateProgram(ctx context.Context, program *models.Program) error {
+	result, err := m.db.Collection("programs").ReplaceOne(ctx, bson.M{"_id": program.ID}, program)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (m *MongoDB) DeleteProgram(ctx context.Context, id bson.ObjectID) error {
+	result, err := m.db.Collection("programs").DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// ListPrograms returns a page of a user's programs, newest first, and the total count
+func (m *MongoDB) ListPrograms(ctx context.Context, query models.ProgramQuery) ([]*models.Program, int64, error) {
+	collection := m.db.Collection("programs")
+	filter := bson.M{"user_id": query.UserID}
+
+	totalCount, err := collection.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "created_at", Value: -1}, {Key: "_id", Value: -1}}).
+		SetLimit(int64(query.Limit)).
+		SetSkip(int64(query.Offset))
+	cursor, err := collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer cursor.Close(ctx)
+
+	var programs []*models.Program
+	if err := cursor.All(ctx, &programs); err != nil {
+		return nil, 0, err
+	}
+	return programs, totalCount, nil
+}
+
+// In-memory implementation
+
+func (m *Memory) CreateProgram(ctx context.Context, program *models.Program) (*models.Program, error) {
+	if program.ID.IsZero() {
+		program.ID = bson.NewObjectID()
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.programs = append(m.programs, clone(program))
+	return program, nil
+}
+
+func (m *Memory) GetProgramByID(ctx context.Context, id bson.ObjectID) (*models.Program, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, program := range m.programs {
+		if program.ID == id {
+			return clone(program), nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (m *Memory) UpdateProgram(ctx context.Context, program *models.Program) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i, existing := range m.programs {
+		if existing.ID == program.ID {
+			m.programs[i] = clone(program)
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+func (m *Memory) DeleteProgram(ctx context.Context, id bson.ObjectID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i, program := range m.programs {
+		if program.ID == id {
+			m.programs = append(m.programs[:i], m.programs[i+1:]...)
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+func (m *Memory) ListPrograms(ctx context.Context, query models.ProgramQuery) ([]*models.Program, int64, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	matched := filterRows(m.programs, func(p *models.Program) bool {
+		return p.UserID == query.UserID
+	})
+	slices.SortStableFunc(matched, func(a, b *models.Program) int {
+		if c := b.CreatedAt.Compare(a.CreatedAt); c != 0 {
+			return c
+		}
+		return bytes.Compare(b.ID[:], a.ID[:])
+	})
+	return cloneAll(paginate(matched, query.Limit, query.Offset)), int64(len(matched)), nil
+}
@@ -0,0 +1,98 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/AyushIIITU/virtualfit/internal/models"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// rateBucketPruneInterval is how often the in-memory store drops full buckets
+const rateBucketPruneInterval = time.Minute
+
+// TakeRateToken refills a bucket holding up to capacity tokens at capacity
+// per period, then takes one token if there is one. The returned bucket
+// reports whether a token was taken. New buckets start full. The whole update
+// runs server-side so concurrent instances share one bucket.
+func (m *MongoDB) TakeRateToken(ctx context.Context, key string, capacity int, period time.Duration, now time.Time) (*models.RateBucket, error) {
+	size := float64(capacity)
+	perMilli := size / float64(period.Milliseconds())
+	elapsed := bson.M{"$max": bson.A{0, bson.M{"$subtract": bson.A{now, bson.M{"$ifNull": bson.A{"$updated_at", now}}}}}}
+	hasToken := bson.M{"$gte": bson.A{"$tokens", 1}}
+
+	update := bson.A{
+		bson.M{"$set": bson.M{
+			"tokens": bson.M{"$min": bson.A{size, bson.M{"$add": bson.A{
+				bson.M{"$ifNull": bson.A{"$tokens", size}},
+				bson.M{"$multiply": bson.A{elapsed, perMilli}},
+			}}}},
+			"updated_at": now,
+		}},
+		bson.M{"$set": bson.M{
+			"allowed": hasToken,
+			"tokens":  bson.M{"$cond": bson.A{hasToken, bson.M{"$subtract": bson.A{"$tokens", 1}}, "$tokens"}},
+		}},
+		bson.M{"$set": bson.M{
+			"expires_at": bson.M{"$add": bson.A{now, bson.M{"$divide": bson.A{
+				bson.M{"$subtract": bson.A{size, "$tokens"}}, perMilli,
+			}}}},
+		}},
+	}
+
+	bucket := &models.RateBucket{}
+	err := m.db.Collection("rate_limits").FindOneAndUpdate(
+		ctx,
+		bson.M{"_id": key},
+		update,
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+	).Decode(bucket)
+	if err != nil {
+		return nil, err
+	}
+	return bucket, nil
+}
+
+// In-memory implementation
+
+func (m *Memory) TakeRateToken(ctx context.Context, key string, capacity int, period time.Duration, now time.Time) (*models.RateBucket, error) {
+	size := float64(capacity)
+	perSecond := size / period.Seconds()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.pruneRateBuckets(now)
+
+	bucket, ok := m.rateBuckets[key]
+	if !ok {
+		bucket = &models.RateBucket{Key: key, Tokens: size, UpdatedAt: now}
+		m.rateBuckets[key] = bucket
+	}
+	elapsed := max(0, now.Sub(bucket.UpdatedAt).Seconds())
+	bucket.Tokens = min(size, bucket.Tokens+elapsed*perSecond)
+	bucket.UpdatedAt = now
+	bucket.Allowed = bucket.Tokens >= 1
+	if bucket.Allowed {
+		bucket.Tokens--
+	}
+	bucket.ExpiresAt = now.Add(time.Duration((size - bucket.Tokens) / perSecond * float64(time.Second)))
+
+	// The bucket holds no pointers, so a shallow copy is enough
+	out := *bucket
+	return &out, nil
+}
+
+// pruneRateBuckets drops buckets that have refilled, which behave exactly
+// like missing ones. Callers hold m.mu.
+func (m *Memory) pruneRateBuckets(now time.Time) {
+	if now.Sub(m.rateBucketsPrunedAt) < rateBucketPruneInterval {
+		return
+	}
+	for key, bucket := range m.rateBuckets {
+		if !bucket.ExpiresAt.After(now) {
+			delete(m.rateBuckets, key)
+		}
+	}
+	m.rateBucketsPrunedAt = now
+}
@@ -0,0 +1,283 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"slices"
+	"time"
+
+	"github.com/AyushIIITU/virtualfit/internal/models"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+func (m *MongoDB) CreateWorkoutSession(ctx context.Context, session *models.WorkoutSession) (*models.WorkoutSession, error) {
+	result, err := m.db.Collection("workout_sessions").InsertOne(ctx, session)
+	if err != nil {
+		return nil, err
+	}
+	session.ID = result.InsertedID.(bson.ObjectID)
+	return session, nil
+}
+
+func (m *MongoDB) GetWorkoutSessionByID(ctx context.Context, id bson.ObjectID) (*models.WorkoutSession, error) {
+	session := &models.WorkoutSession{}
+	err := m.db.Collection("workout_sessions").FindOne(ctx, bson.M{"_id": id}).Decode(session)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return session, nil
+}
+
+// UpdateWorkoutSession replaces a session read at updatedAt. The write only
+// applies while the stored session still has that updated_at, so of two
+// edits made from the same read the second gets ErrConflict instead of
+// dropping the first.
+func (m *MongoDB) UpdateWorkoutSession(ctx context.Context, session *models.WorkoutSession, updatedAt time.Time) error {
+	collection := m.db.Collection("workout_sessions")
+	result, err := collection.ReplaceOne(ctx, bson.M{"_id": session.ID, "updated_at": updatedAt}, session)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount > 0 {
+		return nil
+	}
+	count, err := collection.CountDocuments(ctx, bson.M{"_id": session.ID})
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrNotFound
+	}
+	return ErrConflict
+}
+
+func (m *MongoDB) DeleteWorkoutSession(ctx context.Context, id bson.ObjectID) error {
+	result, err := m.db.Collection("workout_sessions").DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// ListWorkoutSessions returns a page of a user's sessions, newest first, and the total count
+func (m *MongoDB) ListWorkoutSessions(ctx context.Context, query models.WorkoutSessionQuery) ([]*models.WorkoutSession, int64, error) {
+	collection := m.db.Collection("workout_sessions")
+	filter := bson.M{"user_id": query.UserID}
+	if !query.WorkoutID.IsZero() {
+		filter["entries.workout_id"] = query.WorkoutID
+	}
+	startedAt := bson.M{}
+	if !query.From.IsZero() {
+		startedAt["$gte"] = query.From
+	}
+	if !query.To.IsZero() {
+		startedAt["$lt"] = query.To
+	}
+	if len(startedAt) > 0 {
+		filter["started_at"] = startedAt
+	}
+
+	totalCount, err := collection.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "started_at", Value: -1}, {Key: "_id", Value: -1}}).
+		SetLimit(int64(query.Limit)).
+		SetSkip(int64(query.Offset))
+	cursor, err := collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer cursor.Close(ctx)
+
+	var sessions []*models.WorkoutSession
+	if err := cursor.All(ctx, &sessions); err != nil {
+		return nil, 0, err
+	}
+	return sessions, totalCount, nil
+}
+
+// MigrateExercises copies legacy exercise log entries into workout sessions
+// with the same IDs and returns how many were new, and the entries it skipped
+// because no user owns them. Entries logged before exercises carried a user
+// are owned through the user's exercises list. Entries already migrated are
+// left alone, except that sessions an earlier run left without an owner are
+// given one, so it is safe to run on every start. The exercises collection
+// itself is kept.
+func (m *MongoDB) MigrateExercises(ctx context.Context) (int64, []bson.ObjectID, error) {
+	owners, err := m.exerciseOwners(ctx)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	cursor, err := m.db.Collection("exercises").Find(ctx, bson.M{})
+	if err != nil {
+		return 0, nil, err
+	}
+	defer cursor.Close(ctx)
+
+	sessions := m.db.Collection("workout_sessions")
+	var migrated int64
+	var orphaned []bson.ObjectID
+	for cursor.Next(ctx) {
+		var exercise models.Exercise
+		if err := cursor.Decode(&exercise); err != nil {
+			return migrated, orphaned, err
+		}
+		session, ok := legacySession(&exercise, owners)
+		if !ok {
+			orphaned = append(orphaned, exercise.ID)
+			continue
+		}
+
+		data, err := bson.Marshal(session)
+		if err != nil {
+			return migrated, orphaned, err
+		}
+		var doc bson.M
+		if err := bson.Unmarshal(data, &doc); err != nil {
+			return migrated, orphaned, err
+		}
+		delete(doc, "_id")
+
+		result, err := sessions.UpdateOne(ctx,
+			bson.M{"_id": exercise.ID},
+			bson.M{"$setOnInsert": doc},
+			options.UpdateOne().SetUpsert(true),
+		)
+		if err != nil {
+			return migrated, orphaned, err
+		}
+		if result.UpsertedCount == 0 {
+			result, err = sessions.UpdateOne(ctx,
+				bson.M{"_id": exercise.ID, "user_id": bson.NilObjectID},
+				bson.M{"$set": bson.M{"user_id": session.UserID}},
+			)
+			if err != nil {
+				return migrated, orphaned, err
+			}
+			migrated += result.ModifiedCount
+			continue
+		}
+		migrated += result.UpsertedCount
+	}
+	return migrated, orphaned, cursor.Err()
+}
+
+// exerciseOwners maps each legacy exercise in a user's exercises list to the user
+func (m *MongoDB) exerciseOwners(ctx context.Context) (map[bson.ObjectID]bson.ObjectID, error) {
+	cursor, err := m.db.Collection("users").Find(ctx,
+		bson.M{"exercises.0": bson.M{"$exists": true}},
+		options.Find().SetProjection(bson.M{"_id": 1, "exercises": 1}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	owners := make(map[bson.ObjectID]bson.ObjectID)
+	for cursor.Next(ctx) {
+		var user struct {
+			ID        bson.ObjectID   `bson:"_id"`
+			Exercises []bson.ObjectID `bson:"exercises"`
+		}
+		if err := cursor.Decode(&user); err != nil {
+			return nil, err
+		}
+		for _, id := range user.Exercises {
+			owners[id] = user.ID
+		}
+	}
+	return owners, cursor.Err()
+}
+
+// legacySession converts a legacy exercise into a session of its owner: the
+// user it names, or else the user listing it in owners. It reports false when
+// neither owns it.
+func legacySession(exercise *models.Exercise, owners map[bson.ObjectID]bson.ObjectID) (*models.WorkoutSession, bool) {
+	if exercise.UserID.IsZero() {
+		owner, ok := owners[exercise.ID]
+		if !ok {
+			return nil, false
+		}
+		exercise.UserID = owner
+	}
+	return exercise.Session(), true
+}
+
+// In-memory implementation
+
+func (m *Memory) CreateWorkoutSession(ctx context.Context, session *models.WorkoutSession) (*models.WorkoutSession, error) {
+	if session.ID.IsZero() {
+		session.ID = bson.NewObjectID()
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.workoutSessions = append(m.workoutSessions, clone(session))
+	return session, nil
+}
+
+func (m *Memory) GetWorkoutSessionByID(ctx context.Context, id bson.ObjectID) (*models.WorkoutSession, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, session := range m.workoutSessions {
+		if session.ID == id {
+			return clone(session), nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (m *Memory) UpdateWorkoutSession(ctx context.Context, session *models.WorkoutSession, updatedAt time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i, existing := range m.workoutSessions {
+		if existing.ID == session.ID {
+			if !existing.UpdatedAt.Equal(updatedAt) {
+				return ErrConflict
+			}
+			m.workoutSessions[i] = clone(session)
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+func (m *Memory) DeleteWorkoutSession(ctx context.Context, id bson.ObjectID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i, session := range m.workoutSessions {
+		if session.ID == id {
+			m.workoutSessions = append(m.workoutSessions[:i], m.workoutSessions[i+1:]...)
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+func (m *Memory) ListWorkoutSessions(ctx context.Context, query models.WorkoutSessionQuery) ([]*models.WorkoutSession, int64, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	matched := filterRows(m.workoutSessions, func(s *models.WorkoutSession) bool {
+		return s.UserID == query.UserID &&
+			(query.WorkoutID.IsZero() || slices.ContainsFunc(s.Entries, func(e models.SessionEntry) bool {
+				return e.WorkoutID == query.WorkoutID
+			})) &&
+			(query.From.IsZero() || !s.StartedAt.Before(query.From)) &&
+			(query.To.IsZero() || s.StartedAt.Before(query.To))
+	})
+	slices.SortStableFunc(matched, func(a, b *models.WorkoutSession) int {
+		if c := b.StartedAt.Compare(a.StartedAt); c != 0 {
+			return c
+		}
+		return bytes.Compare(b.ID[:], a.ID[:])
+	})
+	return cloneAll(paginate(matched, query.Limit, query.Offset)), int64(len(matched)), nil
+}
@@ -0,0 +1,58 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"github.com/AyushIIITU/virtualfit/internal/models"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// baselineExercise decodes an exercise shaped as the first release stored
+// it, before entries named their user
+func baselineExercise(t *testing.T, id bson.ObjectID) *models.Exercise {
+	t.Helper()
+	data, err := bson.Marshal(bson.D{
+		{Key: "_id", Value: id},
+		{Key: "workouts", Value: bson.NewObjectID()},
+		{Key: "rep_count", Value: "3x10"},
+		{Key: "time", Value: time.Date(2024, 3, 1, 7, 0, 0, 0, time.UTC)},
+		{Key: "created_at", Value: time.Date(2024, 3, 1, 8, 0, 0, 0, time.UTC)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var exercise models.Exercise
+	if err := bson.Unmarshal(data, &exercise); err != nil {
+		t.Fatal(err)
+	}
+	if !exercise.UserID.IsZero() {
+		t.Fatalf("baseline exercise has user %s", exercise.UserID.Hex())
+	}
+	return &exercise
+}
+
+func TestLegacySessionOwner(t *testing.T) {
+	owner, named := bson.NewObjectID(), bson.NewObjectID()
+	listed, orphan := bson.NewObjectID(), bson.NewObjectID()
+	owners := map[bson.ObjectID]bson.ObjectID{listed: owner}
+
+	session, ok := legacySession(baselineExercise(t, listed), owners)
+	if !ok || session.UserID != owner || session.ID != listed {
+		t.Fatalf("listed exercise: got %+v, %v; want session %s of %s", session, ok, listed.Hex(), owner.Hex())
+	}
+	if len(session.Entries) != 1 || len(session.Entries[0].Sets) != 3 {
+		t.Errorf("3x10 became %+v", session.Entries)
+	}
+
+	if session, ok := legacySession(baselineExercise(t, orphan), owners); ok {
+		t.Errorf("exercise no user owns became session of %s", session.UserID.Hex())
+	}
+
+	// An exercise naming its user keeps it, whatever the lists say
+	exercise := baselineExercise(t, listed)
+	exercise.UserID = named
+	if session, ok := legacySession(exercise, owners); !ok || session.UserID != named {
+		t.Errorf("exercise of %s became session of %v", named.Hex(), session)
+	}
+}
@@ -0,0 +1,182 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/AyushIIITU/virtualfit/internal/models"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// ErrNotFound is returned by every store when the requested record does not exist
+var ErrNotFound = errors.New("not found")
+
+// ErrEmailTaken is returned when saving a user whose email another account uses
+var ErrEmailTaken = errors.New("email address is already in use")
+
+// ErrConflict is returned when a record changed between reading and saving it
+var ErrConflict = errors.New("record was changed by another request")
+
+// ErrSocketNotFound is returned when deleting an unknown socket ID
+var ErrSocketNotFound = fmt.Errorf("socket ID %w", ErrNotFound)
+
+// UserStore persists user accounts
+type UserStore interface {
+	CreateUser(ctx context.Context, user *models.User) (*models.User, error)
+	GetUserByEmail(ctx context.Context, email string) (*models.User, error)
+	GetUserByID(ctx context.Context, id bson.ObjectID) (*models.User, error)
+	UpdateUser(ctx context.Context, user *models.User) error
+	// ConsumeMFAStep and ConsumeRecoveryCode use a second factor at most once.
+	// They return ErrNotFound when it was already used.
+	ConsumeMFAStep(ctx context.Context, userID bson.ObjectID, step int64) error
+	ConsumeRecoveryCode(ctx context.Context, userID bson.ObjectID, hash string) error
+	SearchUsers(ctx context.Context, criteria models.UserSearchCriteria) ([]*models.User, int64, error)
+}
+
+// WorkoutSessionStore persists logged training sessions
+type WorkoutSessionStore interface {
+	CreateWorkoutSession(ctx context.Context, session *models.WorkoutSession) (*models.WorkoutSession, error)
+	GetWorkoutSessionByID(ctx context.Context, id bson.ObjectID) (*models.WorkoutSession, error)
+	// UpdateWorkoutSession saves a session only while it was last updated at
+	// updatedAt, and returns ErrConflict when it has changed since
+	UpdateWorkoutSession(ctx context.Context, session *models.WorkoutSession, updatedAt time.Time) error
+	DeleteWorkoutSession(ctx context.Context, id bson.ObjectID) error
+	ListWorkoutSessions(ctx context.Context, query models.WorkoutSessionQuery) ([]*models.WorkoutSession, int64, error)
+}
+
+// ProgramStore persists users' training programs
+type ProgramStore interface {
+	CreateProgram(ctx context.Context, program *models.Program) (*models.Program, error)
+	GetProgramByID(ctx context.Context, id bson.ObjectID) (*models.Program, error)
+	UpdateProgram(ctx context.Context, program *models.Program) error
+	DeleteProgram(ctx context.Context, id bson.ObjectID) error
+	ListPrograms(ctx context.Context, query models.ProgramQuery) ([]*models.Program, int64, error)
+}
+
+// FoodIntakeStore persists food intake records
+type FoodIntakeStore interface {
+	CreateFoodIntake(ctx context.Context, foodIntake *models.FoodIntake) (*models.FoodIntake, error)
+	UpdateFoodIntake(ctx context.Context, foodIntake *models.FoodIntake) error
+	DeleteFoodIntake(ctx context.Context, id bson.ObjectID) error
+	GetFoodIntakeByID(ctx context.Context, id bson.ObjectID) (*models.FoodIntake, error)
+	GetFoodIntakeByImage(ctx context.Context, userID bson.ObjectID, imagePath string) (*models.FoodIntake, error)
+	ListUserFoodIntake(ctx context.Context, userID bson.ObjectID) ([]*models.FoodIntake, error)
+	AggregateNutrition(ctx context.Context, query models.NutritionQuery) ([]models.NutritionBucket, error)
+}
+
+// BodyMeasurementStore persists the body measurement log
+type BodyMeasurementStore interface {
+	CreateBodyMeasurement(ctx context.Context, measurement *models.BodyMeasurement) (*models.BodyMeasurement, error)
+	GetBodyMeasurementByID(ctx context.Context, id bson.ObjectID) (*models.BodyMeasurement, error)
+	UpdateBodyMeasurement(ctx context.Context, measurement *models.BodyMeasurement) error
+	DeleteBodyMeasurement(ctx context.Context, id bson.ObjectID) error
+	ListBodyMeasurements(ctx context.Context, query models.MeasurementQuery) ([]*models.BodyMeasurement, int64, error)
+}
+
+// WorkoutStore reads the workout catalog
+type WorkoutStore interface {
+	GetWorkout(ctx context.Context, nameFilter string) ([]*models.Workout, error)
+	GetWorkoutPaginated(ctx context.Context, nameFilter string, limit, offset int) ([]*models.Workout, int64, error)
+	SearchWorkouts(ctx context.Context, criteria models.WorkoutSearchCriteria) ([]*models.Workout, int64, error)
+	GetWorkoutByID(ctx context.Context, id bson.ObjectID) (*models.Workout, error)
+	ListMuscles(ctx context.Context) ([]string, error)
+	CreateWorkout(ctx context.Context, workout *models.Workout) (*models.Workout, error)
+	UpdateWorkout(ctx context.Context, workout *models.Workout) error
+	DeleteWorkout(ctx context.Context, id bson.ObjectID) error
+}
+
+// ChatStore persists chat socket registrations
+type ChatStore interface {
+	SaveChat(ctx context.Context, chat *models.Chat) error
+	FindChatsByUserID(ctx context.Context, userID bson.ObjectID) ([]*models.Chat, error)
+	DeleteChatBySocketID(ctx context.Context, userID bson.ObjectID, socketID string) error
+}
+
+// JobStore persists background jobs
+type JobStore interface {
+	EnqueueJob(ctx context.Context, job *models.Job) (*models.Job, error)
+	ClaimJob(ctx context.Context, now time.Time, lease time.Duration) (*models.Job, error)
+	UpdateJob(ctx context.Context, job *models.Job) error
+	RecoverJobs(ctx context.Context, now time.Time) (int64, error)
+	GetJobByID(ctx context.Context, id bson.ObjectID) (*models.Job, error)
+	ListJobs(ctx context.Context, query models.JobQuery) ([]*models.Job, int64, error)
+}
+
+// TokenStore persists refresh tokens and the access token denylist
+type TokenStore interface {
+	CreateRefreshToken(ctx context.Context, token *models.RefreshToken) error
+	GetRefreshTokenByHash(ctx context.Context, hash string) (*models.RefreshToken, error)
+	RotateRefreshToken(ctx context.Context, id bson.ObjectID, now time.Time) error
+	ListRefreshTokens(ctx context.Context, userID bson.ObjectID, now time.Time) ([]*models.RefreshToken, error)
+	RevokeRefreshTokens(ctx context.Context, ids []bson.ObjectID, now time.Time) error
+	RevokeAccessToken(ctx context.Context, token *models.RevokedToken) error
+	IsAccessTokenRevoked(ctx context.Context, jti string, now time.Time) (bool, error)
+}
+
+// OneTimeTokenStore persists password reset and email verification tokens
+type OneTimeTokenStore interface {
+	CreateOneTimeToken(ctx context.Context, token *models.OneTimeToken) error
+	ConsumeOneTimeToken(ctx context.Context, hash, purpose string, now time.Time) (*models.OneTimeToken, error)
+	InvalidateOneTimeTokens(ctx context.Context, userID bson.ObjectID, purpose string, now time.Time) error
+}
+
+// LoginFailureStore counts failed logins per account and per client IP
+type LoginFailureStore interface {
+	GetLoginFailures(ctx context.Context, key string, now time.Time) (*models.LoginFailures, error)
+	RecordLoginFailure(ctx context.Context, key string, now, expiresAt time.Time) (*models.LoginFailures, error)
+	LockLogin(ctx context.Context, key string, minCount int, until, expiresAt time.Time) (bool, error)
+	ClearLoginFailures(ctx context.Context, key string) error
+}
+
+// RateLimitStore keeps token buckets for request rate limits
+type RateLimitStore interface {
+	TakeRateToken(ctx context.Context, key string, capacity int, period time.Duration, now time.Time) (*models.RateBucket, error)
+}
+
+// AuditStore persists the audit log of privileged actions
+type AuditStore interface {
+	RecordAudit(ctx context.Context, entry *models.AuditEntry) error
+	ListAudit(ctx context.Context, query models.AuditQuery) ([]*models.AuditEntry, int64, error)
+}
+
+// Store is the full persistence layer used by the service
+type Store interface {
+	UserStore
+	WorkoutSessionStore
+	ProgramStore
+	FoodIntakeStore
+	BodyMeasurementStore
+	WorkoutStore
+	ChatStore
+	JobStore
+	TokenStore
+	OneTimeTokenStore
+	LoginFailureStore
+	RateLimitStore
+	AuditStore
+}
+
+var (
+	_ Store = (*MongoDB)(nil)
+	_ Store = (*Memory)(nil)
+)
+
+// emailTaken maps a duplicate key error on the users collection, whose only
+// unique index is on email, to ErrEmailTaken
+func emailTaken(err error) error {
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrEmailTaken
+	}
+	return err
+}
+
+// notFound maps the driver's no-documents error to ErrNotFound
+func notFound(err error) error {
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return ErrNotFound
+	}
+	return err
+}
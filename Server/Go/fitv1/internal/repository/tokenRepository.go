@@ -0,0 +1,187 @@
+package repository
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"github.com/AyushIIITU/virtualfit/internal/models"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// CreateRefreshToken stores a newly issued refresh token
+func (m *MongoDB) CreateRefreshToken(ctx context.Context, token *models.RefreshToken) error {
+	if token.ID.IsZero() {
+		token.ID = bson.NewObjectID()
+	}
+	_, err := m.db.Collection("refresh_tokens").InsertOne(ctx, token)
+	return err
+}
+
+// GetRefreshTokenByHash looks up a refresh token by the hash of its value
+func (m *MongoDB) GetRefreshTokenByHash(ctx context.Context, hash string) (*models.RefreshToken, error) {
+	token := &models.RefreshToken{}
+	err := m.db.Collection("refresh_tokens").FindOne(ctx, bson.M{"token_hash": hash}).Decode(token)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return token, nil
+}
+
+// RotateRefreshToken marks a token as used. Only one caller can rotate a given
+// token; everyone else gets ErrNotFound, as do callers presenting a revoked token.
+func (m *MongoDB) RotateRefreshToken(ctx context.Context, id bson.ObjectID, now time.Time) error {
+	result, err := m.db.Collection("refresh_tokens").UpdateOne(
+		ctx,
+		bson.M{"_id": id, "rotated_at": nil, "revoked_at": nil},
+		bson.M{"$set": bson.M{"rotated_at": now}},
+	)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// ListRefreshTokens returns a user's unrevoked tokens whose refresh token or
+// paired access token has not expired yet
+func (m *MongoDB) ListRefreshTokens(ctx context.Context, userID bson.ObjectID, now time.Time) ([]*models.RefreshToken, error) {
+	cursor, err := m.db.Collection("refresh_tokens").Find(ctx, bson.M{
+		"user_id":    userID,
+		"revoked_at": nil,
+		"$or": []bson.M{
+			{"expires_at": bson.M{"$gt": now}},
+			{"access_expires_at": bson.M{"$gt": now}},
+		},
+	}, options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var tokens []*models.RefreshToken
+	if err := cursor.All(ctx, &tokens); err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+// RevokeRefreshTokens revokes the given tokens
+func (m *MongoDB) RevokeRefreshTokens(ctx context.Context, ids []bson.ObjectID, now time.Time) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	_, err := m.db.Collection("refresh_tokens").UpdateMany(
+		ctx,
+		bson.M{"_id": bson.M{"$in": ids}, "revoked_at": nil},
+		bson.M{"$set": bson.M{"revoked_at": now}},
+	)
+	return err
+}
+
+// RevokeAccessToken adds an access token to the denylist
+func (m *MongoDB) RevokeAccessToken(ctx context.Context, token *models.RevokedToken) error {
+	_, err := m.db.Collection("revoked_tokens").ReplaceOne(
+		ctx,
+		bson.M{"_id": token.JTI},
+		token,
+		options.Replace().SetUpsert(true),
+	)
+	return err
+}
+
+// IsAccessTokenRevoked reports whether an access token is on the denylist.
+// Expired entries are ignored since the TTL monitor removes them lazily.
+func (m *MongoDB) IsAccessTokenRevoked(ctx context.Context, jti string, now time.Time) (bool, error) {
+	count, err := m.db.Collection("revoked_tokens").CountDocuments(
+		ctx,
+		bson.M{"_id": jti, "expires_at": bson.M{"$gt": now}},
+	)
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// In-memory implementation
+
+func (m *Memory) CreateRefreshToken(ctx context.Context, token *models.RefreshToken) error {
+	if token.ID.IsZero() {
+		token.ID = bson.NewObjectID()
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.refreshTokens = append(m.refreshTokens, clone(token))
+	return nil
+}
+
+func (m *Memory) GetRefreshTokenByHash(ctx context.Context, hash string) (*models.RefreshToken, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, token := range m.refreshTokens {
+		if token.TokenHash == hash {
+			return clone(token), nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (m *Memory) RotateRefreshToken(ctx context.Context, id bson.ObjectID, now time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, token := range m.refreshTokens {
+		if token.ID == id && token.RotatedAt == nil && token.RevokedAt == nil {
+			token.RotatedAt = &now
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+func (m *Memory) ListRefreshTokens(ctx context.Context, userID bson.ObjectID, now time.Time) ([]*models.RefreshToken, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return cloneAll(filterRows(m.refreshTokens, func(t *models.RefreshToken) bool {
+		return t.UserID == userID && t.RevokedAt == nil &&
+			(t.ExpiresAt.After(now) || t.AccessExpiresAt.After(now))
+	})), nil
+}
+
+func (m *Memory) RevokeRefreshTokens(ctx context.Context, ids []bson.ObjectID, now time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, token := range m.refreshTokens {
+		if token.RevokedAt == nil && slices.Contains(ids, token.ID) {
+			token.RevokedAt = &now
+		}
+	}
+	return nil
+}
+
+func (m *Memory) RevokeAccessToken(ctx context.Context, token *models.RevokedToken) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i, existing := range m.revokedTokens {
+		if existing.JTI == token.JTI {
+			m.revokedTokens[i] = clone(token)
+			return nil
+		}
+	}
+	m.revokedTokens = append(m.revokedTokens, clone(token))
+	return nil
+}
+
+func (m *Memory) IsAccessTokenRevoked(ctx context.Context, jti string, now time.Time) (bool, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, token := range m.revokedTokens {
+		if token.JTI == jti && token.ExpiresAt.After(now) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,321 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/AyushIIITU/virtualfit/internal/auth"
+	"github.com/AyushIIITU/virtualfit/internal/mailer"
+	"github.com/AyushIIITU/virtualfit/internal/models"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	ErrInvalidOneTimeToken  = errors.New("invalid or expired token")
+	ErrEmailAlreadyVerified = errors.New("email address is already verified")
+	ErrEmailUnchanged       = errors.New("that is already your email address")
+)
+
+// issueOneTimeToken replaces a user's outstanding tokens for purpose with a
+// new one and returns its raw value
+func (s *Service) issueOneTimeToken(ctx context.Context, user *models.User, purpose string, ttl time.Duration) (string, error) {
+	return s.storeOneTimeToken(ctx, &models.OneTimeToken{
+		UserID:  user.ID,
+		Purpose: purpose,
+		Email:   user.Email,
+	}, ttl)
+}
+
+// storeOneTimeToken fills in and saves a token, replacing the user's
+// outstanding tokens with the same purpose, and returns the raw token
+func (s *Service) storeOneTimeToken(ctx context.Context, stored *models.OneTimeToken, ttl time.Duration) (string, error) {
+	now := time.Now()
+	if err := s.repo.InvalidateOneTimeTokens(ctx, stored.UserID, stored.Purpose, now); err != nil {
+		return "", err
+	}
+
+	token, err := auth.NewOpaqueToken()
+	if err != nil {
+		return "", err
+	}
+	stored.TokenHash = s.tokens.HashToken(token)
+	stored.ExpiresAt = now.Add(ttl)
+	stored.CreatedAt = now
+	if err := s.repo.CreateOneTimeToken(ctx, stored); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// consumeOneTimeToken redeems a token and returns its user. Tokens issued for
+// an address the account no longer uses are rejected.
+func (s *Service) consumeOneTimeToken(ctx context.Context, token, purpose string) (*models.User, error) {
+	user, _, err := s.consumeOneTimeTokenRecord(ctx, token, purpose)
+	return user, err
+}
+
+// consumeOneTimeTokenRecord is consumeOneTimeToken that also returns the
+// stored token
+func (s *Service) consumeOneTimeTokenRecord(ctx context.Context, token, purpose string) (*models.User, *models.OneTimeToken, error) {
+	stored, err := s.repo.ConsumeOneTimeToken(ctx, s.tokens.HashToken(token), purpose, time.Now())
+	if errors.Is(err, ErrNotFound) {
+		return nil, nil, ErrInvalidOneTimeToken
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	user, err := s.repo.GetUserByID(ctx, stored.UserID)
+	if errors.Is(err, ErrNotFound) {
+		return nil, nil, ErrInvalidOneTimeToken
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	if user.Email != stored.Email {
+		return nil, nil, ErrInvalidOneTimeToken
+	}
+	return user, stored, nil
+}
+
+// sendVerificationEmail emails a link that verifies the user's address
+func (s *Service) sendVerificationEmail(ctx context.Context, user *models.User) error {
+	token, err := s.issueOneTimeToken(ctx, user, models.TokenPurposeEmailVerification, s.opts.EmailVerificationTTL)
+	if err != nil {
+		return err
+	}
+
+	link := s.opts.PublicURL + "/api/v1/auth/verify-email?token=" + url.QueryEscape(token)
+	return s.mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Verify your email address",
+		Body: fmt.Sprintf("Hi %s,\n\nConfirm your email address by opening this link:\n\n%s\n\nThe link expires in %s.\n",
+			user.Name, link, formatTTL(s.opts.EmailVerificationTTL)),
+	})
+}
+
+// ResendVerificationEmail sends a new verification link to the user
+func (s *Service) ResendVerificationEmail(ctx context.Context, userID bson.ObjectID) error {
+	user, err := s.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if user.EmailVerified {
+		return ErrEmailAlreadyVerified
+	}
+	return s.sendVerificationEmail(ctx, user)
+}
+
+// VerifyEmail marks the address a verification token was sent to as verified
+func (s *Service) VerifyEmail(ctx context.Context, token string) error {
+	user, err := s.consumeOneTimeToken(ctx, token, models.TokenPurposeEmailVerification)
+	if err != nil {
+		return err
+	}
+	if user.EmailVerified {
+		return nil
+	}
+	user.EmailVerified = true
+	return s.repo.UpdateUser(ctx, user)
+}
+
+// ForgotPassword emails a password reset link. Unknown addresses are ignored
+// so the response does not reveal which emails have accounts.
+func (s *Service) ForgotPassword(ctx context.Context, email string) error {
+	user, err := s.repo.GetUserByEmail(ctx, email)
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	return s.sendPasswordResetEmail(ctx, user)
+}
+
+// sendPasswordResetEmail emails a link that lets the user choose a new password
+func (s *Service) sendPasswordResetEmail(ctx context.Context, user *models.User) error {
+	token, err := s.issueOneTimeToken(ctx, user, models.TokenPurposePasswordReset, s.opts.PasswordResetTTL)
+	if err != nil {
+		return err
+	}
+
+	link := s.opts.PasswordResetURL + "?token=" + url.QueryEscape(token)
+	return s.mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Hi %s,\n\nReset your password by opening this link:\n\n%s\n\nThe link expires in %s. If you did not ask for a reset, ignore this email.\n",
+			user.Name, link, formatTTL(s.opts.PasswordResetTTL)),
+	})
+}
+
+// ResetPassword sets a new password and signs the user out everywhere
+func (s *Service) ResetPassword(ctx context.Context, token, password string) error {
+	user, err := s.consumeOneTimeToken(ctx, token, models.TokenPurposePasswordReset)
+	if err != nil {
+		return err
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	user.PasswordHash = string(hashedPassword)
+	user.PasswordResetRequired = false
+	// The link reached the user's inbox, which proves they own the address
+	user.EmailVerified = true
+	if err := s.repo.UpdateUser(ctx, user); err != nil {
+		return err
+	}
+	if err := s.clearAccountLockout(ctx, user.Email); err != nil {
+		return err
+	}
+
+	return s.revokeSessions(ctx, user.ID, func(*models.RefreshToken) bool { return true })
+}
+
+// ChangePassword sets a new password for a logged-in user after checking the
+// current one. Wrong passwords count as failed logins. Every other session is
+// ended; the caller stays signed in.
+func (s *Service) ChangePassword(ctx context.Context, claims *auth.Claims, current, password, ip string) error {
+	userID, err := claims.UserObjectID()
+	if err != nil {
+		return err
+	}
+	user, err := s.checkPassword(ctx, userID, current, ip)
+	if err != nil {
+		return err
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	user.PasswordHash = string(hashedPassword)
+	if err := s.repo.UpdateUser(ctx, user); err != nil {
+		return err
+	}
+
+	// A token without a session ID cannot be told apart, so end everything
+	sessionID, _ := claims.SessionObjectID()
+	if err := s.revokeSessions(ctx, userID, func(t *models.RefreshToken) bool {
+		return sessionID.IsZero() || t.FamilyID != sessionID
+	}); err != nil {
+		return err
+	}
+
+	err = s.mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Your password was changed",
+		Body: fmt.Sprintf("Hi %s,\n\nThe password of your account was just changed and your other sessions were signed out.\n\nIf you did not do this, reset your password right away.\n",
+			user.Name),
+	})
+	if err != nil {
+		log.Printf("Error sending password change notice to user %s: %v", user.ID.Hex(), err)
+	}
+	return nil
+}
+
+// RequestEmailChange emails a confirmation link to a new address. The
+// account keeps its current address until the link is opened.
+func (s *Service) RequestEmailChange(ctx context.Context, userID bson.ObjectID, newEmail, password, ip string) error {
+	user, err := s.checkPassword(ctx, userID, password, ip)
+	if err != nil {
+		return err
+	}
+	newEmail = models.NormalizeEmail(newEmail)
+	if newEmail == user.Email {
+		return ErrEmailUnchanged
+	}
+	// The unique index has the final say when the change is confirmed
+	if _, err := s.repo.GetUserByEmail(ctx, newEmail); err == nil {
+		return ErrEmailTaken
+	} else if !errors.Is(err, ErrNotFound) {
+		return err
+	}
+
+	token, err := s.storeOneTimeToken(ctx, &models.OneTimeToken{
+		UserID:   user.ID,
+		Purpose:  models.TokenPurposeEmailChange,
+		Email:    user.Email,
+		NewEmail: newEmail,
+	}, s.opts.EmailVerificationTTL)
+	if err != nil {
+		return err
+	}
+
+	link := s.opts.PublicURL + "/api/v1/account/email/confirm?token=" + url.QueryEscape(token)
+	return s.mailer.Send(ctx, mailer.Message{
+		To:      newEmail,
+		Subject: "Confirm your new email address",
+		Body: fmt.Sprintf("Hi %s,\n\nConfirm that you want to use this address for your account by opening this link:\n\n%s\n\nThe link expires in %s. Until then your account keeps using %s.\n",
+			user.Name, link, formatTTL(s.opts.EmailVerificationTTL), user.Email),
+	})
+}
+
+// ConfirmEmailChange switches the account to the address the token was sent
+// to and lets the previous address know
+func (s *Service) ConfirmEmailChange(ctx context.Context, token string) error {
+	user, stored, err := s.consumeOneTimeTokenRecord(ctx, token, models.TokenPurposeEmailChange)
+	if err != nil {
+		return err
+	}
+
+	previous := user.Email
+	user.Email = stored.NewEmail
+	// The link reached the new inbox, which proves the user owns it
+	user.EmailVerified = true
+	if err := s.repo.UpdateUser(ctx, user); err != nil {
+		return err
+	}
+
+	err = s.mailer.Send(ctx, mailer.Message{
+		To:      previous,
+		Subject: "Your email address was changed",
+		Body: fmt.Sprintf("Hi %s,\n\nYour account now uses %s instead of this address.\n\nIf you did not do this, contact support.\n",
+			user.Name, user.Email),
+	})
+	if err != nil {
+		log.Printf("Error sending email change notice to user %s: %v", user.ID.Hex(), err)
+	}
+	return nil
+}
+
+// checkPassword confirms a logged-in user's password before a sensitive
+// change. Wrong passwords count as failed logins, so a stolen access token
+// cannot be used to guess the password.
+func (s *Service) checkPassword(ctx context.Context, userID bson.ObjectID, password, ip string) (*models.User, error) {
+	user, err := s.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkLoginThrottle(ctx, user.Email, ip); err != nil {
+		return nil, err
+	}
+	if bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)) != nil {
+		if err := s.recordLoginFailure(ctx, user.Email, ip, user); err != nil {
+			return nil, err
+		}
+		return nil, ErrInvalidPassword
+	}
+	return user, nil
+}
+
+// formatTTL renders a link lifetime for an email, e.g. "2 days" or "1 hour"
+func formatTTL(d time.Duration) string {
+	unit, n := "minute", int(d/time.Minute)
+	switch {
+	case d >= 24*time.Hour && d%(24*time.Hour) == 0:
+		unit, n = "day", int(d/(24*time.Hour))
+	case d >= time.Hour && d%time.Hour == 0:
+		unit, n = "hour", int(d/time.Hour)
+	}
+	if n != 1 {
+		unit += "s"
+	}
+	return fmt.Sprintf("%d %s", n, unit)
+}
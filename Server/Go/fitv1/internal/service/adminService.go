@@ -0,0 +1,204 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/AyushIIITU/virtualfit/internal/models"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// ErrSelfAction is returned when an admin tries to lock themselves out
+var ErrSelfAction = errors.New("admins cannot perform this action on their own account")
+
+// audit records a privileged action. The action has already happened, so a
+// failure to record it is logged rather than reported to the caller.
+func (s *Service) audit(ctx context.Context, actor models.AuditActor, action, targetType, targetID string, details bson.M) {
+	err := s.repo.RecordAudit(ctx, &models.AuditEntry{
+		ActorID:    actor.UserID,
+		ActorIP:    actor.IP,
+		Action:     action,
+		TargetType: targetType,
+		TargetID:   targetID,
+		Details:    details,
+		CreatedAt:  time.Now(),
+	})
+	if err != nil {
+		log.Errorf("Error recording audit entry %s by %s on %s: %v", action, actor.UserID.Hex(), targetID, err)
+	}
+}
+
+// ListUsers searches user accounts
+func (s *Service) ListUsers(ctx context.Context, actor models.AuditActor, criteria models.UserSearchCriteria) ([]*models.User, int64, error) {
+	users, total, err := s.repo.SearchUsers(ctx, criteria)
+	if err != nil {
+		return nil, 0, err
+	}
+	for _, user := range users {
+		user.PasswordHash = ""
+	}
+
+	details := bson.M{"query": criteria.Query, "role": criteria.Role}
+	if criteria.Disabled != nil {
+		details["disabled"] = *criteria.Disabled
+	}
+	s.audit(ctx, actor, models.AuditUserList, "", "", details)
+	return users, total, nil
+}
+
+// GetUser returns any user account
+func (s *Service) GetUser(ctx context.Context, actor models.AuditActor, userID bson.ObjectID) (*models.User, error) {
+	user, err := s.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	user.PasswordHash = ""
+
+	s.audit(ctx, actor, models.AuditUserView, "user", userID.Hex(), nil)
+	return user, nil
+}
+
+// SetUserDisabled disables or re-enables an account. Disabling ends every
+// session of the user immediately.
+func (s *Service) SetUserDisabled(ctx context.Context, actor models.AuditActor, userID bson.ObjectID, disabled bool) error {
+	if userID == actor.UserID {
+		return ErrSelfAction
+	}
+	user, err := s.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	user.Disabled = disabled
+	if err := s.repo.UpdateUser(ctx, user); err != nil {
+		return err
+	}
+
+	action := models.AuditUserEnable
+	if disabled {
+		action = models.AuditUserDisable
+		if err := s.revokeSessions(ctx, userID, func(*models.RefreshToken) bool { return true }); err != nil {
+			return err
+		}
+	}
+	s.audit(ctx, actor, action, "user", userID.Hex(), nil)
+	return nil
+}
+
+// ForcePasswordReset blocks password login until the user sets a new password
+// through an emailed reset link, and ends all of their sessions
+func (s *Service) ForcePasswordReset(ctx context.Context, actor models.AuditActor, userID bson.ObjectID) error {
+	user, err := s.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	user.PasswordResetRequired = true
+	if err := s.repo.UpdateUser(ctx, user); err != nil {
+		return err
+	}
+	if err := s.revokeSessions(ctx, userID, func(*models.RefreshToken) bool { return true }); err != nil {
+		return err
+	}
+
+	s.audit(ctx, actor, models.AuditUserForceReset, "user", userID.Hex(), nil)
+	return s.sendPasswordResetEmail(ctx, user)
+}
+
+// SetUserRole changes a user's role. Existing sessions are ended so the new
+// role applies from the next login.
+func (s *Service) SetUserRole(ctx context.Context, actor models.AuditActor, userID bson.ObjectID, role string) error {
+	if userID == actor.UserID {
+		return ErrSelfAction
+	}
+	user, err := s.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	previous := user.EffectiveRole()
+	if previous == role {
+		return nil
+	}
+	user.Role = role
+	if err := s.repo.UpdateUser(ctx, user); err != nil {
+		return err
+	}
+	if err := s.revokeSessions(ctx, userID, func(*models.RefreshToken) bool { return true }); err != nil {
+		return err
+	}
+
+	s.audit(ctx, actor, models.AuditUserRoleChange, "user", userID.Hex(), bson.M{"from": previous, "to": role})
+	return nil
+}
+
+// ResetMFA turns off a user's two-factor authentication, for users who lost
+// both their device and recovery codes. Their sessions are ended.
+func (s *Service) ResetMFA(ctx context.Context, actor models.AuditActor, userID bson.ObjectID) error {
+	if userID == actor.UserID {
+		return ErrSelfAction
+	}
+	user, err := s.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	clearMFA(user)
+	if err := s.repo.UpdateUser(ctx, user); err != nil {
+		return err
+	}
+	if err := s.revokeSessions(ctx, userID, func(*models.RefreshToken) bool { return true }); err != nil {
+		return err
+	}
+
+	s.audit(ctx, actor, models.AuditUserMFAReset, "user", userID.Hex(), nil)
+	return nil
+}
+
+// ListJobs lists background jobs for inspection
+func (s *Service) ListJobs(ctx context.Context, actor models.AuditActor, query models.JobQuery) ([]*models.Job, int64, error) {
+	jobs, total, err := s.repo.ListJobs(ctx, query)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	s.audit(ctx, actor, models.AuditJobList, "", "", bson.M{"states": query.States, "type": query.Type})
+	return jobs, total, nil
+}
+
+// RetryJob requeues a failed or dead job. Food analysis jobs also put their
+// food intake back into the pending state.
+func (s *Service) RetryJob(ctx context.Context, actor models.AuditActor, jobID bson.ObjectID) (*models.Job, error) {
+	job, err := s.jobs.Retry(ctx, jobID)
+	if err != nil {
+		return nil, err
+	}
+
+	if job.Type == models.JobTypeFoodAnalysis {
+		foodIntake, err := s.repo.GetFoodIntakeByID(ctx, job.RefID)
+		if err == nil && !foodIntake.Status {
+			foodIntake.AnalysisStatus = models.FoodAnalysisPending
+			foodIntake.AnalysisError = ""
+			foodIntake.UpdatedAt = time.Now()
+			err = s.repo.UpdateFoodIntake(ctx, foodIntake)
+		}
+		if err != nil && !errors.Is(err, ErrNotFound) {
+			log.Printf("Error resetting food intake %s for retried job %s: %v", job.RefID.Hex(), job.ID.Hex(), err)
+		}
+	}
+
+	s.audit(ctx, actor, models.AuditJobRetry, "job", jobID.Hex(), bson.M{"type": job.Type, "ref_id": job.RefID.Hex()})
+	return job, nil
+}
+
+// ListAudit returns audit log entries, newest first
+func (s *Service) ListAudit(ctx context.Context, actor models.AuditActor, query models.AuditQuery) ([]*models.AuditEntry, int64, error) {
+	entries, total, err := s.repo.ListAudit(ctx, query)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	s.audit(ctx, actor, models.AuditLogView, "", "", bson.M{"action": query.Action, "target_id": query.TargetID})
+	return entries, total, nil
+}
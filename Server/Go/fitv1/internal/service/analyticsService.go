@@ -0,0 +1,330 @@
+package service
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/AyushIIITU/virtualfit/internal/models"
+	"github.com/AyushIIITU/virtualfit/internal/timeutil"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+const (
+	// acuteDays and chronicDays are the windows of the workload ratio
+	acuteDays   = 7
+	chronicDays = 28
+	// maxUntrainedDays bounds how far back untrained muscles are looked for
+	maxUntrainedDays = 365
+)
+
+var (
+	ErrInvalidSecondaryWeight = errors.New("secondary_weight must be between 0 and 1")
+	ErrInvalidUntrainedDays   = errors.New("untrained_days must be between 1 and 365")
+)
+
+// GetTrainingAnalytics reports the sets and tonnage per muscle group of a
+// user's sessions per day or week between the local dates of the query, the
+// acute:chronic workload ratio and the muscles left untrained at the end of
+// the range. Warm-up sets never count.
+func (s *Service) GetTrainingAnalytics(ctx context.Context, query models.TrainingQuery) (*models.TrainingAnalytics, error) {
+	if !timeutil.ValidGranularity(query.Granularity) {
+		return nil, ErrInvalidGranularity
+	}
+	if query.SecondaryWeight < 0 || query.SecondaryWeight > 1 {
+		return nil, ErrInvalidSecondaryWeight
+	}
+	if query.UntrainedDays < 1 || query.UntrainedDays > maxUntrainedDays {
+		return nil, ErrInvalidUntrainedDays
+	}
+	loc := query.Location
+	start := timeutil.StartOfDay(query.From, loc)
+	end := timeutil.StartOfDay(query.To, loc).AddDate(0, 0, 1)
+	if !start.Before(end) {
+		return nil, ErrInvalidRange
+	}
+
+	user, err := s.repo.GetUserByID(ctx, query.UserID)
+	if err != nil {
+		return nil, err
+	}
+	weekStart := user.WeekStart()
+
+	// Lay out every period up front so days without training are still reported
+	var periods []models.TrainingPeriod
+	index := make(map[time.Time]int)
+	for p := timeutil.Truncate(start, query.Granularity, loc, weekStart); p.Before(end); p = timeutil.Next(p, query.Granularity) {
+		if len(periods) == maxSummaryPeriods {
+			return nil, ErrRangeTooLarge
+		}
+		periodStart, periodEnd := p, timeutil.Next(p, query.Granularity)
+		if periodStart.Before(start) {
+			periodStart = start
+		}
+		if periodEnd.After(end) {
+			periodEnd = end
+		}
+		index[p.UTC()] = len(periods)
+		periods = append(periods, models.TrainingPeriod{
+			Start:   periodStart,
+			End:     periodEnd,
+			Days:    daysBetween(periodStart, periodEnd),
+			Muscles: []models.MuscleLoad{},
+		})
+	}
+
+	// Read back to the earliest day a result needs: the chronic window of the
+	// first period, or the untrained window when it goes back further
+	from := start.AddDate(0, 0, -chronicDays)
+	if since := end.AddDate(0, 0, -query.UntrainedDays); since.Before(from) {
+		from = since
+	}
+	sessions, err := s.allWorkoutSessions(ctx, models.WorkoutSessionQuery{UserID: query.UserID, From: from, To: end})
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		catalog     = make(map[bson.ObjectID]*models.Workout)
+		daily       = make(map[time.Time]float64) // tonnage by local day
+		lastTrained = make(map[string]time.Time)
+		muscles     = make([]map[string]*models.MuscleLoad, len(periods))
+		overall     = make(map[string]*models.MuscleLoad)
+	)
+	for i := range muscles {
+		muscles[i] = make(map[string]*models.MuscleLoad)
+	}
+	for _, session := range sessions {
+		day := timeutil.StartOfDay(session.StartedAt, loc)
+		var period *models.TrainingPeriod
+		var periodMuscles map[string]*models.MuscleLoad
+		if !session.StartedAt.Before(start) {
+			i, ok := index[timeutil.Truncate(session.StartedAt, query.Granularity, loc, weekStart).UTC()]
+			if !ok {
+				return nil, fmt.Errorf("session %s at %s falls in no period", session.ID.Hex(), session.StartedAt)
+			}
+			period, periodMuscles = &periods[i], muscles[i]
+			period.Sessions++
+		}
+
+		for _, entry := range session.Entries {
+			workout, err := s.catalogWorkout(ctx, catalog, entry.WorkoutID)
+			if err != nil {
+				return nil, err
+			}
+			for _, set := range entry.Sets {
+				if set.Warmup {
+					continue
+				}
+				reps, tonnage := 0, 0.0
+				if set.Reps != nil {
+					reps = *set.Reps
+					if set.Load != nil {
+						tonnage = *set.Load * float64(reps)
+					}
+				}
+				daily[day] += tonnage
+
+				var targets []muscleShare
+				if workout != nil {
+					targets = muscleShares(workout, query.SecondaryWeight)
+				}
+				for _, target := range targets {
+					lastTrained[target.muscle] = session.StartedAt
+				}
+				if period == nil {
+					continue
+				}
+				period.Sets++
+				period.Reps += reps
+				period.Tonnage += tonnage
+				for _, target := range targets {
+					addMuscleLoad(periodMuscles, target, tonnage)
+					addMuscleLoad(overall, target, tonnage)
+				}
+			}
+		}
+	}
+
+	for i := range periods {
+		period := &periods[i]
+		period.Tonnage = round2(period.Tonnage)
+		period.Muscles = muscleLoads(muscles[i])
+		period.Workload = workloadRatio(daily, period.End)
+	}
+
+	untrained, err := s.untrainedMuscles(ctx, lastTrained, end, loc, query.UntrainedDays)
+	if err != nil {
+		return nil, err
+	}
+
+	analytics := &models.TrainingAnalytics{
+		From:            start,
+		To:              end,
+		Granularity:     query.Granularity,
+		Timezone:        loc.String(),
+		SecondaryWeight: query.SecondaryWeight,
+		Periods:         periods,
+		Muscles:         muscleLoads(overall),
+		Workload:        workloadRatio(daily, end),
+		UntrainedDays:   query.UntrainedDays,
+		Untrained:       untrained,
+	}
+	if query.Granularity == timeutil.Week {
+		analytics.WeekStart = strings.ToLower(weekStart.String())
+	}
+	return analytics, nil
+}
+
+// muscleShare is the share of a set counted for one muscle
+type muscleShare struct {
+	muscle  string
+	share   float64
+	primary bool
+}
+
+// muscleShares lists the muscles a set of workout works. A muscle listed as
+// both primary and secondary counts as primary; secondary muscles are left
+// out at a weight of 0.
+func muscleShares(workout *models.Workout, secondaryWeight float64) []muscleShare {
+	var shares []muscleShare
+	for _, muscle := range workout.PrimaryMuscles {
+		shares = append(shares, muscleShare{muscle: muscle, share: 1, primary: true})
+	}
+	for _, muscle := range workout.SecondaryMuscles {
+		if secondaryWeight > 0 && !slices.Contains(workout.PrimaryMuscles, muscle) {
+			shares = append(shares, muscleShare{muscle: muscle, share: secondaryWeight})
+		}
+	}
+	return shares
+}
+
+// addMuscleLoad counts one set for a muscle
+func addMuscleLoad(loads map[string]*models.MuscleLoad, target muscleShare, tonnage float64) {
+	load, ok := loads[target.muscle]
+	if !ok {
+		load = &models.MuscleLoad{Muscle: target.muscle}
+		loads[target.muscle] = load
+	}
+	load.Sets += target.share
+	load.Tonnage += tonnage * target.share
+	if target.primary {
+		load.PrimarySets++
+	} else {
+		load.SecondarySets++
+	}
+}
+
+// muscleLoads lists muscle loads with the most sets first
+func muscleLoads(loads map[string]*models.MuscleLoad) []models.MuscleLoad {
+	out := []models.MuscleLoad{}
+	for _, load := range loads {
+		load.Sets = round2(load.Sets)
+		load.Tonnage = round2(load.Tonnage)
+		out = append(out, *load)
+	}
+	slices.SortFunc(out, func(a, b models.MuscleLoad) int {
+		if c := cmp.Compare(b.Sets, a.Sets); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Muscle, b.Muscle)
+	})
+	return out
+}
+
+// workloadRatio compares the acute and chronic tonnage of the days before asOf
+func workloadRatio(daily map[time.Time]float64, asOf time.Time) models.WorkloadRatio {
+	acuteFrom, chronicFrom := asOf.AddDate(0, 0, -acuteDays), asOf.AddDate(0, 0, -chronicDays)
+	var acute, chronic float64
+	for day, tonnage := range daily {
+		if !day.Before(asOf) || day.Before(chronicFrom) {
+			continue
+		}
+		chronic += tonnage
+		if !day.Before(acuteFrom) {
+			acute += tonnage
+		}
+	}
+	// The chronic load is a weekly average, comparable with the acute week
+	chronic = chronic * acuteDays / chronicDays
+
+	workload := models.WorkloadRatio{AsOf: asOf, Acute: round2(acute), Chronic: round2(chronic)}
+	if chronic > 0 {
+		ratio := round2(acute / chronic)
+		workload.Ratio = &ratio
+		switch {
+		case ratio < 0.8:
+			workload.Zone = "low"
+		case ratio <= 1.3:
+			workload.Zone = "optimal"
+		case ratio <= 1.5:
+			workload.Zone = "high"
+		default:
+			workload.Zone = "very_high"
+		}
+	}
+	return workload
+}
+
+// untrainedMuscles lists the catalog muscles not trained in the days days
+// before asOf, the longest untrained first
+func (s *Service) untrainedMuscles(ctx context.Context, lastTrained map[string]time.Time, asOf time.Time, loc *time.Location, days int) ([]models.UntrainedMuscle, error) {
+	catalog, err := s.repo.ListMuscles(ctx)
+	if err != nil {
+		return nil, err
+	}
+	since := asOf.AddDate(0, 0, -days)
+
+	untrained := []models.UntrainedMuscle{}
+	for _, muscle := range catalog {
+		last, ok := lastTrained[muscle]
+		if ok && !last.Before(since) {
+			continue
+		}
+		m := models.UntrainedMuscle{Muscle: muscle}
+		if ok {
+			daysSince := daysBetween(timeutil.StartOfDay(last, loc), asOf) - 1
+			m.LastTrained, m.DaysSince = &last, &daysSince
+		}
+		untrained = append(untrained, m)
+	}
+	// Never trained first, in catalog order, then the least recently trained
+	slices.SortStableFunc(untrained, func(a, b models.UntrainedMuscle) int {
+		switch {
+		case a.LastTrained == nil && b.LastTrained == nil:
+			return 0
+		case a.LastTrained == nil:
+			return -1
+		case b.LastTrained == nil:
+			return 1
+		}
+		return a.LastTrained.Compare(*b.LastTrained)
+	})
+	return untrained, nil
+}
+
+// catalogWorkout looks up a catalog workout once per request, or returns nil
+// when it has since been removed from the catalog
+func (s *Service) catalogWorkout(ctx context.Context, cache map[bson.ObjectID]*models.Workout, id bson.ObjectID) (*models.Workout, error) {
+	if workout, ok := cache[id]; ok {
+		return workout, nil
+	}
+	workout, err := s.repo.GetWorkoutByID(ctx, id)
+	if errors.Is(err, ErrNotFound) {
+		workout, err = nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	cache[id] = workout
+	return workout, nil
+}
+
+func round2(v float64) float64 {
+	return math.Round(v*100) / 100
+}
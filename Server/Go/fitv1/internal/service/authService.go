@@ -0,0 +1,220 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/AyushIIITU/virtualfit/internal/auth"
+	"github.com/AyushIIITU/virtualfit/internal/models"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+var (
+	ErrInvalidRefreshToken = errors.New("invalid or expired refresh token")
+	ErrRefreshTokenReused  = errors.New("refresh token was already used; session revoked")
+	ErrTokenRevoked        = errors.New("token has been revoked")
+	ErrAccountDisabled     = errors.New("account is disabled")
+)
+
+// IssueTokens starts a new session for a user who has just authenticated
+func (s *Service) IssueTokens(ctx context.Context, user *models.User) (*models.AuthTokens, error) {
+	return s.issueTokens(ctx, user, bson.NewObjectID())
+}
+
+// issueTokens signs an access token and stores a paired refresh token in the
+// given session family
+func (s *Service) issueTokens(ctx context.Context, user *models.User, familyID bson.ObjectID) (*models.AuthTokens, error) {
+	if err := s.bootstrapAdmin(ctx, user); err != nil {
+		return nil, err
+	}
+
+	claims := &auth.Claims{
+		UserID:        user.ID.Hex(),
+		SessionID:     familyID.Hex(),
+		Role:          user.EffectiveRole(),
+		EmailVerified: user.EmailVerified,
+	}
+	accessToken, err := s.tokens.IssueAccessToken(claims)
+	if err != nil {
+		return nil, err
+	}
+	refreshToken, err := auth.NewOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	err = s.repo.CreateRefreshToken(ctx, &models.RefreshToken{
+		UserID:          user.ID,
+		FamilyID:        familyID,
+		TokenHash:       s.tokens.HashToken(refreshToken),
+		AccessJTI:       claims.ID,
+		AccessExpiresAt: claims.ExpiresAt.Time,
+		ExpiresAt:       now.Add(s.opts.RefreshTokenTTL),
+		CreatedAt:       now,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.AuthTokens{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(s.tokens.AccessTTL().Seconds()),
+	}, nil
+}
+
+// RefreshTokens exchanges a refresh token for a new token pair. Each refresh
+// token can be used once; presenting one again means it leaked, so the whole
+// session is revoked.
+func (s *Service) RefreshTokens(ctx context.Context, refreshToken string) (*models.AuthTokens, error) {
+	stored, err := s.repo.GetRefreshTokenByHash(ctx, s.tokens.HashToken(refreshToken))
+	if errors.Is(err, ErrNotFound) {
+		return nil, ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if stored.RotatedAt != nil {
+		return nil, s.revokeReusedFamily(ctx, stored)
+	}
+	if stored.RevokedAt != nil || !stored.ExpiresAt.After(now) {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	// Lost the race against a concurrent refresh with the same token
+	if err := s.repo.RotateRefreshToken(ctx, stored.ID, now); err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil, s.revokeReusedFamily(ctx, stored)
+		}
+		return nil, err
+	}
+
+	// Claims are rebuilt from the current account, not copied from the old token
+	user, err := s.repo.GetUserByID(ctx, stored.UserID)
+	if errors.Is(err, ErrNotFound) {
+		return nil, ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return nil, err
+	}
+	if user.Disabled {
+		return nil, ErrAccountDisabled
+	}
+	return s.issueTokens(ctx, user, stored.FamilyID)
+}
+
+// bootstrapAdmin promotes accounts listed in the admin emails setting once
+// their address is verified, so a fresh deployment can get its first admin
+func (s *Service) bootstrapAdmin(ctx context.Context, user *models.User) error {
+	if user.Role == models.RoleAdmin || !user.EmailVerified ||
+		!slices.Contains(s.opts.AdminEmails, strings.ToLower(user.Email)) {
+		return nil
+	}
+	user.Role = models.RoleAdmin
+	if err := s.repo.UpdateUser(ctx, user); err != nil {
+		return err
+	}
+	log.Printf("Promoted user %s to admin from the admin email list", user.ID.Hex())
+	return nil
+}
+
+func (s *Service) revokeReusedFamily(ctx context.Context, stored *models.RefreshToken) error {
+	log.Warnf("Refresh token reuse detected for user %s, revoking session %s", stored.UserID.Hex(), stored.FamilyID.Hex())
+	if err := s.revokeSessions(ctx, stored.UserID, func(t *models.RefreshToken) bool {
+		return t.FamilyID == stored.FamilyID
+	}); err != nil {
+		return err
+	}
+	return ErrRefreshTokenReused
+}
+
+// Logout ends the session the access token belongs to
+func (s *Service) Logout(ctx context.Context, claims *auth.Claims) error {
+	userID, err := claims.UserObjectID()
+	if err != nil {
+		return err
+	}
+	if err := s.revokeAccessToken(ctx, userID, claims.ID, claims.ExpiresAt.Time); err != nil {
+		return err
+	}
+
+	sessionID, err := claims.SessionObjectID()
+	if err != nil {
+		return nil
+	}
+	return s.revokeSessions(ctx, userID, func(t *models.RefreshToken) bool {
+		return t.FamilyID == sessionID
+	})
+}
+
+// LogoutAll ends every session of a user
+func (s *Service) LogoutAll(ctx context.Context, claims *auth.Claims) error {
+	userID, err := claims.UserObjectID()
+	if err != nil {
+		return err
+	}
+	if err := s.revokeAccessToken(ctx, userID, claims.ID, claims.ExpiresAt.Time); err != nil {
+		return err
+	}
+	return s.revokeSessions(ctx, userID, func(*models.RefreshToken) bool { return true })
+}
+
+// revokeSessions revokes the matching refresh tokens of a user and denylists
+// the access tokens issued with them that are still valid
+func (s *Service) revokeSessions(ctx context.Context, userID bson.ObjectID, match func(*models.RefreshToken) bool) error {
+	now := time.Now()
+	tokens, err := s.repo.ListRefreshTokens(ctx, userID, now)
+	if err != nil {
+		return err
+	}
+
+	var ids []bson.ObjectID
+	for _, token := range tokens {
+		if !match(token) {
+			continue
+		}
+		ids = append(ids, token.ID)
+		if token.AccessExpiresAt.After(now) {
+			if err := s.revokeAccessToken(ctx, userID, token.AccessJTI, token.AccessExpiresAt); err != nil {
+				return err
+			}
+		}
+	}
+	return s.repo.RevokeRefreshTokens(ctx, ids, now)
+}
+
+func (s *Service) revokeAccessToken(ctx context.Context, userID bson.ObjectID, jti string, expiresAt time.Time) error {
+	return s.repo.RevokeAccessToken(ctx, &models.RevokedToken{
+		JTI:       jti,
+		UserID:    userID,
+		ExpiresAt: expiresAt,
+	})
+}
+
+// JWKS returns the public keys access tokens can be verified with
+func (s *Service) JWKS() auth.JWKS {
+	return s.tokens.JWKS()
+}
+
+// VerifyAccessToken validates an access token and checks it has not been revoked
+func (s *Service) VerifyAccessToken(ctx context.Context, token string) (*auth.Claims, error) {
+	claims, err := s.tokens.ParseAccessToken(token)
+	if err != nil {
+		return nil, err
+	}
+	revoked, err := s.repo.IsAccessTokenRevoked(ctx, claims.ID, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if revoked {
+		return nil, ErrTokenRevoked
+	}
+	return claims, nil
+}
@@ -0,0 +1,12 @@
+package service
+
+import "go.mongodb.org/mongo-driver/v2/bson"
+
+// authorize checks that the caller owns a record. Records of other users are
+// reported as missing so callers cannot probe which IDs exist.
+func authorize(callerID, ownerID bson.ObjectID) error {
+	if callerID.IsZero() || callerID != ownerID {
+		return ErrNotFound
+	}
+	return nil
+}
@@ -9,7 +9,10 @@ import (
 )
 
 // StoreSocketID saves a new socket ID for a user
-func (s *Service) StoreSocketID(ctx context.Context, chat *models.Chat) error {
+func (s *Service) StoreSocketID(ctx context.Context, userID bson.ObjectID, chat *models.Chat) error {
+	if err := authorize(userID, chat.UserID); err != nil {
+		return err
+	}
 	if err := chat.Validate(); err != nil {
 		return err
 	}
@@ -18,7 +21,11 @@ func (s *Service) StoreSocketID(ctx context.Context, chat *models.Chat) error {
 }
 
 // GetAllSocketIDsByUserID retrieves all socket IDs for a specific user
-func (s *Service) GetAllSocketIDsByUserID(ctx context.Context, userID bson.ObjectID) ([]string, error) {
+func (s *Service) GetAllSocketIDsByUserID(ctx context.Context, callerID, userID bson.ObjectID) ([]string, error) {
+	if err := authorize(callerID, userID); err != nil {
+		return nil, err
+	}
+
 	chats, err := s.repo.FindChatsByUserID(ctx, userID)
 	if err != nil {
 		return nil, err
@@ -32,7 +39,7 @@ func (s *Service) GetAllSocketIDsByUserID(ctx context.Context, userID bson.Objec
 	return socketIDs, nil
 }
 
-// DisconnectSocket removes a socket connection
-func (s *Service) DisconnectSocket(ctx context.Context, socketID string) error {
-	return s.repo.DeleteChatBySocketID(ctx, socketID)
+// DisconnectSocket removes one of the user's socket connections
+func (s *Service) DisconnectSocket(ctx context.Context, userID bson.ObjectID, socketID string) error {
+	return s.repo.DeleteChatBySocketID(ctx, userID, socketID)
 }
@@ -1,21 +1,18 @@
 package service
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
-	"mime/multipart"
-	"net/http"
-	"net/textproto"
+	"io/fs"
 	"os"
-	"path/filepath"
-	"strconv"
 	"strings"
 	"time"
 
+	"github.com/AyushIIITU/virtualfit/internal/analyzer"
+	"github.com/AyushIIITU/virtualfit/internal/jobs"
 	"github.com/AyushIIITU/virtualfit/internal/models"
+	"github.com/AyushIIITU/virtualfit/internal/repository"
 	"go.mongodb.org/mongo-driver/v2/bson"
 )
 
@@ -24,6 +21,11 @@ func (s *Service) CreateFoodIntake(ctx context.Context, foodIntake *models.FoodI
 	foodIntake.CreatedAt = time.Now()
 	foodIntake.UpdatedAt = time.Now()
 	foodIntake.Status = false // Set initial status to false
+	foodIntake.AnalysisStatus = models.FoodAnalysisPending
+	foodIntake.Portion = 1
+	if foodIntake.Date.IsZero() {
+		foodIntake.Date = foodIntake.CreatedAt
+	}
 
 	// Save the food intake record
 	createdFoodIntake, err := s.repo.CreateFoodIntake(ctx, foodIntake)
@@ -31,183 +33,275 @@ func (s *Service) CreateFoodIntake(ctx context.Context, foodIntake *models.FoodI
 		return nil, err
 	}
 
-	// Start processing the image in a goroutine
-	go s.ProcessFoodImage(createdFoodIntake)
+	// Queue the image for analysis by the worker pool
+	if _, err := s.jobs.Enqueue(ctx, models.JobTypeFoodAnalysis, createdFoodIntake.ID); err != nil {
+		createdFoodIntake.AnalysisStatus = models.FoodAnalysisFailed
+		createdFoodIntake.AnalysisError = "failed to queue image analysis"
+		if updateErr := s.repo.UpdateFoodIntake(ctx, createdFoodIntake); updateErr != nil {
+			log.Printf("Error recording queue failure for food intake %s: %v", createdFoodIntake.ID.Hex(), updateErr)
+		}
+		return nil, err
+	}
 
 	return createdFoodIntake, nil
 }
 
-// ProcessFoodImage processes the food image asynchronously
-func (s *Service) ProcessFoodImage(foodIntake *models.FoodIntake) {
-	log.Printf("Starting food image processing for food intake ID: %s", foodIntake.ID)
+// CreateManualFoodIntake logs a meal entered by hand with the request's
+// nutrients, already normalized. The entry is complete immediately and never
+// sent to the analyzer, even when it has an image.
+func (s *Service) CreateManualFoodIntake(ctx context.Context, userID bson.ObjectID, req *models.FoodIntakeRequest, nutrients []models.Nutrient, imagePath, imageURL string) (*models.FoodIntake, error) {
+	foodIntake := &models.FoodIntake{
+		UserID:         userID,
+		FoodName:       req.FoodName,
+		Nutrients:      nutrients,
+		Date:           req.Date,
+		Ingredients:    []string{},
+		MealType:       req.MealType,
+		ImageUrl:       imageURL,
+		ImagePath:      imagePath,
+		Status:         true,
+		AnalysisStatus: models.FoodAnalysisManual,
+		Portion:        1,
+		CreatedAt:      time.Now(),
+		UpdatedAt:      time.Now(),
+	}
 
-	// Create a new HTTP client
-	client := &http.Client{}
+	return s.repo.CreateFoodIntake(ctx, foodIntake)
+}
 
-	// Open the image file
-	log.Printf("Opening image file: %s", foodIntake.ImagePath)
-	file, err := os.Open(foodIntake.ImagePath)
+// runFoodAnalysisJob is the job handler for models.JobTypeFoodAnalysis
+func (s *Service) runFoodAnalysisJob(ctx context.Context, job *models.Job) error {
+	foodIntake, err := s.repo.GetFoodIntakeByID(ctx, job.RefID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return jobs.Permanent(err)
+	}
 	if err != nil {
-		log.Printf("Error opening image file: %v", err)
-		return
+		return err
 	}
-	defer file.Close()
 
-	// Create a new multipart form
-	body := &bytes.Buffer{}
-	writer := multipart.NewWriter(body)
-
-	// Create the form file part
-	log.Printf("Creating form file for upload")
-	h := make(textproto.MIMEHeader)
-	h.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%s"; filename="%s"`, "file", filepath.Base(foodIntake.ImagePath)))
-	h.Set("Content-Type", "image/webp")
+	// A previous attempt may have finished after its lease expired
+	if foodIntake.Status {
+		return nil
+	}
 
-	part, err := writer.CreatePart(h)
-	if err != nil {
-		log.Printf("Error creating form part: %v", err)
-		return
+	foodIntake.AnalysisStatus = models.FoodAnalysisProcessing
+	foodIntake.AnalysisAttempts = job.Attempts
+	foodIntake.UpdatedAt = time.Now()
+	if err := s.repo.UpdateFoodIntake(ctx, foodIntake); err != nil {
+		return err
 	}
 
-	// Copy the file content to the form
-	log.Printf("Copying file content to form")
-	_, err = io.Copy(part, file)
+	return s.ProcessFoodImage(ctx, foodIntake)
+}
+
+// recordFoodAnalysisFailure stores a failed attempt on the food intake so
+// clients can see it instead of polling forever
+func (s *Service) recordFoodAnalysisFailure(ctx context.Context, job *models.Job, jobErr error) {
+	foodIntake, err := s.repo.GetFoodIntakeByID(ctx, job.RefID)
 	if err != nil {
-		log.Printf("Error copying file content: %v", err)
+		log.Printf("Error loading food intake %s to record failure: %v", job.RefID.Hex(), err)
 		return
 	}
 
-	// Close the writer
-	err = writer.Close()
-	if err != nil {
-		log.Printf("Error closing writer: %v", err)
-		return
+	foodIntake.AnalysisStatus = models.FoodAnalysisRetrying
+	if job.State == models.JobDead {
+		foodIntake.AnalysisStatus = models.FoodAnalysisFailed
 	}
+	foodIntake.AnalysisError = jobErr.Error()
+	foodIntake.AnalysisAttempts = job.Attempts
+	foodIntake.UpdatedAt = time.Now()
+	if err := s.repo.UpdateFoodIntake(ctx, foodIntake); err != nil {
+		log.Printf("Error recording failure for food intake %s: %v", job.RefID.Hex(), err)
+	}
+}
 
-	// Create the request
-	log.Printf("Creating API request")
-	req, err := http.NewRequest("POST", "http://localhost:8000/analyze-food", body)
+// ProcessFoodImage sends the food image to the analyzer and stores the result
+func (s *Service) ProcessFoodImage(ctx context.Context, foodIntake *models.FoodIntake) error {
+	log.Printf("Starting food image processing for food intake ID: %s", foodIntake.ID.Hex())
+
+	result, err := s.analyzer.Analyze(ctx, foodIntake.ImagePath)
 	if err != nil {
-		log.Printf("Error creating request: %v", err)
-		return
+		return classifyAnalyzerError(err)
 	}
 
-	// Set headers exactly as shown in the API documentation
-	req.Header.Set("Content-Type", writer.FormDataContentType())
-	req.Header.Set("accept", "application/json")
+	log.Printf("Analyzed food %q with ingredients %v", result.FoodName, result.Ingredients)
 
-	// Send the request
-	log.Printf("Sending request to food analysis API with headers: %v", req.Header)
-	resp, err := client.Do(req)
-	if err != nil {
-		log.Printf("Error sending request: %v", err)
-		return
+	// Update the food intake with processed data
+	foodIntake.Original = &models.FoodAnalysisResult{
+		FoodName:    result.FoodName,
+		Ingredients: result.Ingredients,
+		Nutrients:   result.Nutrients,
 	}
-	defer resp.Body.Close()
+	foodIntake.FoodName = result.FoodName
+	foodIntake.Nutrients = result.Nutrients
+	foodIntake.Ingredients = result.Ingredients
+	foodIntake.Status = true
+	foodIntake.AnalysisStatus = models.FoodAnalysisCompleted
+	foodIntake.AnalysisError = ""
+	foodIntake.UpdatedAt = time.Now()
 
-	// Read the response body
-	log.Printf("Reading API response")
-	respBody, err := io.ReadAll(resp.Body)
-	if err != nil {
-		log.Printf("Error reading response: %v", err)
-		return
+	log.Printf("Updating food intake record with processed data")
+	if err := s.repo.UpdateFoodIntake(ctx, foodIntake); err != nil {
+		return fmt.Errorf("updating food intake: %w", err)
 	}
 
-	log.Printf("API Response Status: %d", resp.StatusCode)
-	log.Printf("API Response Body: %s", string(respBody))
+	log.Printf("Successfully completed food image processing for food intake ID: %s", foodIntake.ID.Hex())
+	return nil
+}
 
-	if resp.StatusCode != http.StatusOK {
-		log.Printf("API returned error status: %d", resp.StatusCode)
-		return
+// classifyAnalyzerError marks analyzer failures that a retry cannot fix
+func classifyAnalyzerError(err error) error {
+	var statusErr *analyzer.StatusError
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		// Retrying will not bring a missing upload back
+		return jobs.Permanent(err)
+	case errors.Is(err, analyzer.ErrMalformedResponse):
+		return jobs.Permanent(err)
+	case errors.As(err, &statusErr) && !statusErr.Retryable():
+		return jobs.Permanent(err)
 	}
+	return err
+}
 
-	// Parse the response
-	var result struct {
-		Status    string `json:"status"`
-		FoodName  string `json:"food_name"`
-		Nutrition struct {
-			Name        string `json:"name"`
-			ID          int    `json:"id"`
-			Ingredients string `json:"ingredients"`
-			Nutrition   string `json:"nutrition"`
-		} `json:"nutrition"`
-	}
+func (s *Service) GetFoodIntake(ctx context.Context, userID, id bson.ObjectID) (*models.FoodIntake, error) {
+	return s.getOwnedFoodIntake(ctx, userID, id)
+}
 
-	if err := json.Unmarshal(respBody, &result); err != nil {
-		log.Printf("Error parsing response JSON: %v", err)
-		return
-	}
+// AuthorizeFoodImage checks that one of the user's entries has the image.
+// Images of other users are reported as missing.
+func (s *Service) AuthorizeFoodImage(ctx context.Context, userID bson.ObjectID, imagePath string) error {
+	_, err := s.repo.GetFoodIntakeByImage(ctx, userID, imagePath)
+	return err
+}
 
-	// Parse ingredients - handle single quotes
-	ingredientsStr := result.Nutrition.Ingredients
-	// Remove the single quotes and brackets
-	ingredientsStr = strings.Trim(ingredientsStr, "[]'")
-	// Split by ', ' and clean up each ingredient
-	ingredients := strings.Split(ingredientsStr, "', '")
-	for i := range ingredients {
-		// Remove any remaining single quotes
-		ingredients[i] = strings.Trim(ingredients[i], "'")
-	}
-
-	log.Printf("Parsed ingredients: %v", ingredients)
-
-	// Parse nutrition values - handle single quotes
-	nutritionStr := result.Nutrition.Nutrition
-	// Remove the brackets and any single quotes
-	nutritionStr = strings.Trim(nutritionStr, "[]'")
-	// Split by comma and parse each number
-	nutritionParts := strings.Split(nutritionStr, ", ")
-	var nutritionValues []float64
-	for _, part := range nutritionParts {
-		value, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
-		if err != nil {
-			log.Printf("Error parsing nutrition value %s: %v", part, err)
-			return
-		}
-		nutritionValues = append(nutritionValues, value)
-	}
+func (s *Service) ListUserFoodIntake(ctx context.Context, userID bson.ObjectID) ([]*models.FoodIntake, error) {
+	return s.repo.ListUserFoodIntake(ctx, userID)
+}
 
-	log.Printf("Parsed nutrition values: %v", nutritionValues)
+// ErrFoodIntakeProcessing is returned when correcting an entry the analyzer is still working on
+var ErrFoodIntakeProcessing = errors.New("food intake is still being analyzed")
 
-	// Create nutrients array
-	nutrients := []models.Nutrient{
-		{Name: "Calories", Amount: nutritionValues[0], Unit: "kcal"},
-		{Name: "Protein", Amount: nutritionValues[1], Unit: "g"},
-		{Name: "Fat", Amount: nutritionValues[2], Unit: "g"},
-		{Name: "Saturated Fat", Amount: nutritionValues[3], Unit: "g"},
-		{Name: "Carbohydrates", Amount: nutritionValues[4], Unit: "g"},
-		{Name: "Fiber", Amount: nutritionValues[5], Unit: "g"},
-		{Name: "Sugar", Amount: nutritionValues[6], Unit: "g"},
+// analysisInProgress reports whether the analyzer may still write an entry's values
+func analysisInProgress(foodIntake *models.FoodIntake) bool {
+	switch foodIntake.AnalysisStatus {
+	case models.FoodAnalysisPending, models.FoodAnalysisProcessing, models.FoodAnalysisRetrying:
+		return !foodIntake.Status
 	}
+	return false
+}
 
-	// Update the food intake with processed data
-	ctx := context.Background()
-	updatedFoodIntake := &models.FoodIntake{
-		ID:        foodIntake.ID,
-		UserID:    foodIntake.UserID,
-		ImagePath: foodIntake.ImagePath,
-		FoodName:  result.FoodName,
-		Nutrients: nutrients,
-
-		Ingredients: ingredients,
-		Status:      true,
-		UpdatedAt:   time.Now(),
+// getOwnedFoodIntake loads a food intake, hiding entries of other users
+func (s *Service) getOwnedFoodIntake(ctx context.Context, userID, id bson.ObjectID) (*models.FoodIntake, error) {
+	foodIntake, err := s.repo.GetFoodIntakeByID(ctx, id)
+	if err != nil {
+		return nil, err
 	}
+	if err := authorize(userID, foodIntake.UserID); err != nil {
+		return nil, err
+	}
+	return foodIntake, nil
+}
 
-	log.Printf("Updating food intake record with processed data")
-	err = s.repo.UpdateFoodIntake(ctx, updatedFoodIntake)
+// UpdateFoodIntake applies a user's correction to one of their entries
+func (s *Service) UpdateFoodIntake(ctx context.Context, userID, id bson.ObjectID, update *models.FoodIntakeUpdate) (*models.FoodIntake, error) {
+	foodIntake, err := s.getOwnedFoodIntake(ctx, userID, id)
 	if err != nil {
-		log.Printf("Error updating food intake: %v", err)
-		return
+		return nil, err
+	}
+	if analysisInProgress(foodIntake) {
+		return nil, ErrFoodIntakeProcessing
+	}
+
+	// Entries analysed before originals were recorded keep their current values as the original
+	if foodIntake.Original == nil && foodIntake.AnalysisStatus == models.FoodAnalysisCompleted {
+		foodIntake.Original = &models.FoodAnalysisResult{
+			FoodName:    foodIntake.FoodName,
+			Ingredients: foodIntake.Ingredients,
+			Nutrients:   append([]models.Nutrient(nil), foodIntake.Nutrients...),
+		}
+	}
+
+	if update.FoodName != nil {
+		foodIntake.FoodName = *update.FoodName
+	}
+	if update.MealType != nil {
+		foodIntake.MealType = *update.MealType
+	}
+	if update.Date != nil {
+		foodIntake.Date = *update.Date
+	}
+	if update.Ingredients != nil {
+		foodIntake.Ingredients = *update.Ingredients
+	}
+	if update.Portion != nil {
+		current := foodIntake.Portion
+		if current <= 0 {
+			current = 1
+		}
+		scale := *update.Portion / current
+		for i := range foodIntake.Nutrients {
+			foodIntake.Nutrients[i].Amount *= scale
+		}
+		foodIntake.Portion = *update.Portion
+	}
+	for _, nutrient := range update.Nutrients {
+		foodIntake.Nutrients = setNutrient(foodIntake.Nutrients, nutrient)
+	}
+	// Nutrients entered for an entry the analyzer gave up on complete it by hand
+	if !foodIntake.Status && len(update.Nutrients) > 0 {
+		foodIntake.Status = true
+		foodIntake.AnalysisStatus = models.FoodAnalysisManual
+		foodIntake.AnalysisError = ""
 	}
 
-	log.Printf("Successfully completed food image processing for food intake ID: %s", foodIntake.ID)
+	now := time.Now()
+	foodIntake.CorrectedAt = &now
+	foodIntake.UpdatedAt = now
+	if err := s.repo.UpdateFoodIntake(ctx, foodIntake); err != nil {
+		return nil, err
+	}
+	return foodIntake, nil
 }
 
-func (s *Service) GetFoodIntake(ctx context.Context, id bson.ObjectID) (*models.FoodIntake, error) {
-	return s.repo.GetFoodIntakeByID(ctx, id)
+// setNutrient replaces the nutrient with the same name, or appends it
+func setNutrient(nutrients []models.Nutrient, nutrient models.Nutrient) []models.Nutrient {
+	for i := range nutrients {
+		if strings.EqualFold(nutrients[i].Name, nutrient.Name) {
+			if nutrient.Unit == "" {
+				nutrient.Unit = nutrients[i].Unit
+			}
+			nutrients[i] = nutrient
+			return nutrients
+		}
+	}
+	return append(nutrients, nutrient)
 }
 
-func (s *Service) ListUserFoodIntake(ctx context.Context, userID bson.ObjectID) ([]*models.FoodIntake, error) {
-	return s.repo.ListUserFoodIntake(ctx, userID)
+// DeleteFoodIntake removes one of the user's entries and its image file
+func (s *Service) DeleteFoodIntake(ctx context.Context, userID, id bson.ObjectID) error {
+	foodIntake, err := s.getOwnedFoodIntake(ctx, userID, id)
+	if err != nil {
+		return err
+	}
+	if err := s.repo.DeleteFoodIntake(ctx, id); err != nil {
+		return err
+	}
+
+	if foodIntake.ImagePath == "" {
+		return nil
+	}
+	// Uploads named by the client could share a file before each got its own
+	// name, so keep one another entry still shows
+	switch _, err := s.repo.GetFoodIntakeByImage(ctx, userID, foodIntake.ImagePath); {
+	case err == nil:
+		return nil
+	case !errors.Is(err, ErrNotFound):
+		log.Printf("Error checking other uses of image %s: %v", foodIntake.ImagePath, err)
+		return nil
+	}
+	if err := os.Remove(foodIntake.ImagePath); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		log.Printf("Error removing image %s of deleted food intake: %v", foodIntake.ImagePath, err)
+	}
+	return nil
 }
@@ -0,0 +1,187 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/AyushIIITU/virtualfit/internal/mailer"
+	"github.com/AyushIIITU/virtualfit/internal/models"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+const (
+	maxLoginDelay = 30 * time.Second
+	maxLockout    = 24 * time.Hour
+)
+
+// LockoutPolicy limits failed logins for one kind of key
+type LockoutPolicy struct {
+	MaxFailures int           // failures that trigger a lockout; 0 disables the policy
+	Lockout     time.Duration // first lockout; it doubles each time, up to a day
+}
+
+// LoginThrottledError refuses a login attempt before the password is checked
+type LoginThrottledError struct {
+	RetryAfter time.Duration
+	Locked     bool
+}
+
+func (e *LoginThrottledError) Error() string {
+	if e.Locked {
+		return "too many failed logins; login is temporarily locked"
+	}
+	return "too many failed logins; wait before trying again"
+}
+
+// loginKey is a counter key and the policy that applies to it
+type loginKey struct {
+	key    string
+	policy LockoutPolicy
+}
+
+// loginKeys are the counters an attempt from ip for email is checked against
+func (s *Service) loginKeys(email, ip string) []loginKey {
+	return []loginKey{
+		{accountLoginKey(email), s.opts.AccountLockout},
+		{ipLoginKey(ip), s.opts.IPLockout},
+	}
+}
+
+func accountLoginKey(email string) string {
+	return "account:" + models.NormalizeEmail(email)
+}
+
+func ipLoginKey(ip string) string {
+	return "ip:" + ip
+}
+
+// loginDelay is how long to wait after the last failure before another
+// attempt. Delays start halfway to a lockout and double with each failure.
+func (p LockoutPolicy) loginDelay(failures int) time.Duration {
+	free := p.MaxFailures / 2
+	if p.MaxFailures <= 0 || failures < free {
+		return 0
+	}
+	delay := time.Second
+	for i := free; i < failures && delay < maxLoginDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, maxLoginDelay)
+}
+
+// lockoutDuration doubles the base lockout for each earlier lockout
+func lockoutDuration(base time.Duration, previous int) time.Duration {
+	d := base
+	for i := 0; i < previous && d < maxLockout; i++ {
+		d *= 2
+	}
+	return min(d, maxLockout)
+}
+
+// checkLoginThrottle refuses attempts for a locked account or IP, and attempts
+// that arrive before the progressive delay since the last failure has passed
+func (s *Service) checkLoginThrottle(ctx context.Context, email, ip string) error {
+	now := time.Now()
+	throttled := &LoginThrottledError{}
+	for _, check := range s.loginKeys(email, ip) {
+		failures, err := s.repo.GetLoginFailures(ctx, check.key, now)
+		if errors.Is(err, ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		if failures.LockedUntil != nil && failures.LockedUntil.After(now) {
+			throttled.Locked = true
+			throttled.RetryAfter = max(throttled.RetryAfter, failures.LockedUntil.Sub(now))
+			continue
+		}
+		if wait := failures.LastAt.Add(check.policy.loginDelay(failures.Count)).Sub(now); wait > throttled.RetryAfter {
+			throttled.RetryAfter = wait
+		}
+	}
+
+	if throttled.RetryAfter > 0 {
+		return throttled
+	}
+	return nil
+}
+
+// recordLoginFailure counts a failed password or two-factor code against the
+// account and the client IP, locking either once it reaches its limit. user is
+// nil when the email has no account.
+func (s *Service) recordLoginFailure(ctx context.Context, email, ip string, user *models.User) error {
+	now := time.Now()
+	for _, check := range s.loginKeys(email, ip) {
+		if check.policy.MaxFailures <= 0 {
+			continue
+		}
+		failures, err := s.repo.RecordLoginFailure(ctx, check.key, now, now.Add(s.opts.LoginFailureWindow))
+		if err != nil {
+			return err
+		}
+		if failures.Count < check.policy.MaxFailures {
+			continue
+		}
+
+		until := now.Add(lockoutDuration(check.policy.Lockout, failures.Lockouts))
+		locked, err := s.repo.LockLogin(ctx, check.key, check.policy.MaxFailures, until, until.Add(s.opts.LoginFailureWindow))
+		if err != nil {
+			return err
+		}
+		if !locked {
+			continue
+		}
+
+		log.Warnf("Login locked for %s until %s after %d failed attempts (last from %s)",
+			check.key, until.Format(time.RFC3339), failures.Count, ip)
+		targetType, targetID := "login", check.key
+		if user != nil && check.key == accountLoginKey(email) {
+			targetType, targetID = "user", user.ID.Hex()
+			if err := s.sendUnlockEmail(ctx, user, until); err != nil {
+				log.Printf("Error sending unlock email to user %s: %v", user.ID.Hex(), err)
+			}
+		}
+		s.audit(ctx, models.AuditActor{IP: ip}, models.AuditLoginLockout, targetType, targetID,
+			bson.M{"until": until, "failures": failures.Count})
+	}
+	return nil
+}
+
+// clearAccountLockout forgets an account's failed logins. Failures counted
+// against client IPs are kept.
+func (s *Service) clearAccountLockout(ctx context.Context, email string) error {
+	return s.repo.ClearLoginFailures(ctx, accountLoginKey(email))
+}
+
+// sendUnlockEmail tells the user their account was locked and emails a link
+// that lifts the lock
+func (s *Service) sendUnlockEmail(ctx context.Context, user *models.User, until time.Time) error {
+	ttl := time.Until(until).Round(time.Minute)
+	token, err := s.issueOneTimeToken(ctx, user, models.TokenPurposeAccountUnlock, ttl)
+	if err != nil {
+		return err
+	}
+
+	link := s.opts.PublicURL + "/api/v1/auth/unlock?token=" + url.QueryEscape(token)
+	return s.mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Your account was locked",
+		Body: fmt.Sprintf("Hi %s,\n\nAfter several failed login attempts we locked logins to your account for %s.\n\nIf that was you, unlock it now with this link:\n\n%s\n\nIf it was not you, someone may be guessing your password. Consider resetting it.\n",
+			user.Name, formatTTL(ttl), link),
+	})
+}
+
+// UnlockAccount lifts a login lockout from the emailed unlock link
+func (s *Service) UnlockAccount(ctx context.Context, token string) error {
+	user, err := s.consumeOneTimeToken(ctx, token, models.TokenPurposeAccountUnlock)
+	if err != nil {
+		return err
+	}
+	log.Printf("Login lockout lifted for user %s via unlock link", user.ID.Hex())
+	return s.clearAccountLockout(ctx, user.Email)
+}
@@ -0,0 +1,373 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/AyushIIITU/virtualfit/internal/models"
+	"github.com/AyushIIITU/virtualfit/internal/timeutil"
+	"github.com/AyushIIITU/virtualfit/internal/units"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+const (
+	// MaxTrendWindow is the longest moving average window in days
+	MaxTrendWindow = 90
+	// maxTrendDays bounds the date range of a trend, which is not paginated
+	maxTrendDays = 730
+	// rateWindow is how far back from the latest entry the weekly rate looks
+	rateWindow = 28 * 24 * time.Hour
+	// minRateSpan is the least time the entries of a rate must cover
+	minRateSpan = 72 * time.Hour
+	// maxProjection is the furthest a goal date is projected
+	maxProjection = 5 * 365 * 24 * time.Hour
+	// measurementClockSkew allows entries stamped slightly ahead of the server clock
+	measurementClockSkew = 5 * time.Minute
+	// profileMeasurementNote marks entries recorded from a profile change
+	profileMeasurementNote = "profile update"
+)
+
+var (
+	ErrInvalidMeasurement = errors.New("invalid measurement")
+	ErrEmptyMeasurement   = errors.New("a measurement needs at least one value")
+	ErrFutureMeasurement  = errors.New("measured_at must not be in the future")
+	ErrUnknownMetric      = errors.New("unknown body metric")
+	ErrInvalidWindow      = fmt.Errorf("window must be between 1 and %d days", MaxTrendWindow)
+)
+
+// CreateBodyMeasurement logs a new entry, taken now unless the update says otherwise
+func (s *Service) CreateBodyMeasurement(ctx context.Context, userID bson.ObjectID, update *models.BodyMeasurementUpdate) (*models.BodyMeasurement, error) {
+	if err := update.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidMeasurement, err)
+	}
+
+	now := time.Now()
+	measurement := &models.BodyMeasurement{
+		UserID:     userID,
+		MeasuredAt: now,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+	update.Apply(measurement)
+	if err := checkMeasurement(measurement, now); err != nil {
+		return nil, err
+	}
+
+	measurement, err := s.repo.CreateBodyMeasurement(ctx, measurement)
+	if err != nil {
+		return nil, err
+	}
+	if affectsBodyStats(measurement) {
+		if err := s.syncBodyStats(ctx, userID); err != nil {
+			return nil, err
+		}
+	}
+	return measurement, nil
+}
+
+func (s *Service) GetBodyMeasurement(ctx context.Context, userID, id bson.ObjectID) (*models.BodyMeasurement, error) {
+	return s.getOwnedBodyMeasurement(ctx, userID, id)
+}
+
+// ListBodyMeasurements returns a page of the caller's entries, newest first
+func (s *Service) ListBodyMeasurements(ctx context.Context, query models.MeasurementQuery) ([]*models.BodyMeasurement, int64, error) {
+	if query.Metric != "" {
+		if _, ok := models.MetricQuantities[query.Metric]; !ok {
+			return nil, 0, ErrUnknownMetric
+		}
+	}
+	return s.repo.ListBodyMeasurements(ctx, query)
+}
+
+// getOwnedBodyMeasurement loads an entry, hiding entries of other users
+func (s *Service) getOwnedBodyMeasurement(ctx context.Context, userID, id bson.ObjectID) (*models.BodyMeasurement, error) {
+	measurement, err := s.repo.GetBodyMeasurementByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := authorize(userID, measurement.UserID); err != nil {
+		return nil, err
+	}
+	return measurement, nil
+}
+
+// UpdateBodyMeasurement changes the time or values of one of the user's entries
+func (s *Service) UpdateBodyMeasurement(ctx context.Context, userID, id bson.ObjectID, update *models.BodyMeasurementUpdate) (*models.BodyMeasurement, error) {
+	if err := update.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidMeasurement, err)
+	}
+
+	measurement, err := s.getOwnedBodyMeasurement(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	affected := affectsBodyStats(measurement)
+
+	now := time.Now()
+	update.Apply(measurement)
+	if err := checkMeasurement(measurement, now); err != nil {
+		return nil, err
+	}
+	measurement.UpdatedAt = now
+	if err := s.repo.UpdateBodyMeasurement(ctx, measurement); err != nil {
+		return nil, err
+	}
+	if affected || affectsBodyStats(measurement) {
+		if err := s.syncBodyStats(ctx, userID); err != nil {
+			return nil, err
+		}
+	}
+	return measurement, nil
+}
+
+// DeleteBodyMeasurement removes one of the user's entries. The profile keeps
+// its weight when the last weighed entry is deleted.
+func (s *Service) DeleteBodyMeasurement(ctx context.Context, userID, id bson.ObjectID) error {
+	measurement, err := s.getOwnedBodyMeasurement(ctx, userID, id)
+	if err != nil {
+		return err
+	}
+	if err := s.repo.DeleteBodyMeasurement(ctx, id); err != nil {
+		return err
+	}
+	if affectsBodyStats(measurement) {
+		return s.syncBodyStats(ctx, userID)
+	}
+	return nil
+}
+
+// checkMeasurement rejects entries without values or from the future
+func checkMeasurement(measurement *models.BodyMeasurement, now time.Time) error {
+	empty := true
+	for metric := range models.MetricQuantities {
+		if measurement.Value(metric) != nil {
+			empty = false
+		}
+	}
+	if empty {
+		return ErrEmptyMeasurement
+	}
+	if measurement.MeasuredAt.After(now.Add(measurementClockSkew)) {
+		return ErrFutureMeasurement
+	}
+	return nil
+}
+
+// affectsBodyStats reports whether an entry holds a metric copied onto the profile
+func affectsBodyStats(measurement *models.BodyMeasurement) bool {
+	return measurement.Weight != nil || measurement.BodyFatPercent != nil
+}
+
+// syncBodyStats copies the latest logged weight and body fat onto the user,
+// recalculating their targets when the calculator manages them
+func (s *Service) syncBodyStats(ctx context.Context, userID bson.ObjectID) error {
+	user, err := s.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	changed := false
+	for metric, field := range map[string]*float64{
+		models.MetricWeight:         &user.Weight,
+		models.MetricBodyFatPercent: &user.BodyFatPercent,
+	} {
+		latest, _, err := s.repo.ListBodyMeasurements(ctx, models.MeasurementQuery{UserID: userID, Metric: metric, Limit: 1})
+		if err != nil {
+			return err
+		}
+		if len(latest) == 0 {
+			continue
+		}
+		if value := *latest[0].Value(metric); *field != value {
+			*field = value
+			changed = true
+		}
+	}
+	if !changed {
+		return nil
+	}
+
+	if user.AutoTargets {
+		if err := applyTargets(user); err != nil {
+			return err
+		}
+	}
+	return s.repo.UpdateUser(ctx, user)
+}
+
+// recordProfileMeasurement logs the weight and body fat set through the
+// profile, so the history also covers values that were never logged directly.
+// before is nil for a new account.
+func (s *Service) recordProfileMeasurement(ctx context.Context, before, after *models.User) {
+	now := time.Now()
+	measurement := &models.BodyMeasurement{
+		UserID:     after.ID,
+		MeasuredAt: now,
+		Note:       profileMeasurementNote,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+	if before == nil || before.Weight != after.Weight {
+		weight := after.Weight
+		measurement.Weight = &weight
+	}
+	if after.BodyFatPercent > 0 && (before == nil || before.BodyFatPercent != after.BodyFatPercent) {
+		bodyFat := after.BodyFatPercent
+		measurement.BodyFatPercent = &bodyFat
+	}
+	if !affectsBodyStats(measurement) {
+		return
+	}
+
+	if _, err := s.repo.CreateBodyMeasurement(ctx, measurement); err != nil {
+		log.Printf("Error recording profile measurement of user %s: %v", after.ID.Hex(), err)
+	}
+}
+
+// GetMeasurementTrend reports one metric between the dates from and to (both
+// inclusive) in loc with a moving average over window days, the weekly rate of
+// change, and when the goal will be reached at that rate. A nil goal falls
+// back to the goal weight on the profile.
+func (s *Service) GetMeasurementTrend(ctx context.Context, userID bson.ObjectID, metric string, from, to time.Time, loc *time.Location, window int, goal *float64) (*models.MeasurementTrend, error) {
+	quantity, ok := models.MetricQuantities[metric]
+	if !ok {
+		return nil, ErrUnknownMetric
+	}
+	if window < 1 || window > MaxTrendWindow {
+		return nil, ErrInvalidWindow
+	}
+	start := timeutil.StartOfDay(from, loc)
+	end := timeutil.StartOfDay(to, loc).AddDate(0, 0, 1)
+	if !start.Before(end) {
+		return nil, ErrInvalidRange
+	}
+	if daysBetween(start, end) > maxTrendDays {
+		return nil, ErrRangeTooLarge
+	}
+	windowSize := time.Duration(window) * 24 * time.Hour
+
+	if goal == nil && metric == models.MetricWeight {
+		user, err := s.repo.GetUserByID(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+		if user.GoalWeight > 0 {
+			goal = &user.GoalWeight
+		}
+	}
+
+	// Entries from before the range complete the first moving averages
+	entries, _, err := s.repo.ListBodyMeasurements(ctx, models.MeasurementQuery{
+		UserID: userID,
+		Metric: metric,
+		From:   start.Add(-windowSize),
+		To:     end,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	trend := &models.MeasurementTrend{
+		Metric:     metric,
+		Unit:       units.UnitOf(quantity, units.Metric),
+		From:       start,
+		To:         end,
+		WindowDays: window,
+		Points:     []models.TrendPoint{},
+		Goal:       goal,
+	}
+
+	// Entries come newest first; walk them oldest first keeping a running window
+	var sum float64
+	oldest := len(entries) - 1
+	for i := len(entries) - 1; i >= 0; i-- {
+		entry := entries[i]
+		sum += *entry.Value(metric)
+		for !entries[oldest].MeasuredAt.After(entry.MeasuredAt.Add(-windowSize)) {
+			sum -= *entries[oldest].Value(metric)
+			oldest--
+		}
+		if entry.MeasuredAt.Before(start) {
+			continue
+		}
+		trend.Points = append(trend.Points, models.TrendPoint{
+			MeasuredAt:    entry.MeasuredAt,
+			Value:         *entry.Value(metric),
+			MovingAverage: sum / float64(oldest-i+1),
+		})
+	}
+	if len(trend.Points) == 0 {
+		return trend, nil
+	}
+
+	latest := trend.Points[len(trend.Points)-1]
+	current := latest.MovingAverage
+	trend.Current = &current
+	trend.WeeklyRate = weeklyRate(trend.Points, latest.MeasuredAt.Add(-rateWindow))
+	if goal != nil {
+		projectGoal(trend, latest.MeasuredAt, loc)
+	}
+	return trend, nil
+}
+
+// weeklyRate fits a least-squares line through the points taken since the
+// given time and returns its slope per week, or nil with too little data
+func weeklyRate(points []models.TrendPoint, since time.Time) *float64 {
+	var recent []models.TrendPoint
+	for _, p := range points {
+		if !p.MeasuredAt.Before(since) {
+			recent = append(recent, p)
+		}
+	}
+	if len(recent) < 2 || recent[len(recent)-1].MeasuredAt.Sub(recent[0].MeasuredAt) < minRateSpan {
+		return nil
+	}
+
+	var meanX, meanY float64
+	for _, p := range recent {
+		meanX += p.MeasuredAt.Sub(recent[0].MeasuredAt).Hours() / 24
+		meanY += p.Value
+	}
+	meanX /= float64(len(recent))
+	meanY /= float64(len(recent))
+
+	var cov, variance float64
+	for _, p := range recent {
+		dx := p.MeasuredAt.Sub(recent[0].MeasuredAt).Hours()/24 - meanX
+		cov += dx * (p.Value - meanY)
+		variance += dx * dx
+	}
+	rate := cov / variance * 7
+	return &rate
+}
+
+// projectGoal sets the goal status and, when the trend is heading towards the
+// goal, the date it will be reached counting from the latest entry
+func projectGoal(trend *models.MeasurementTrend, latest time.Time, loc *time.Location) {
+	remaining := *trend.Goal - *trend.Current
+	// Within half a percent of the goal counts as reached, as daily readings fluctuate more
+	if math.Abs(remaining) <= math.Abs(*trend.Goal)*0.005 {
+		trend.GoalStatus = models.GoalReached
+		return
+	}
+	if trend.WeeklyRate == nil {
+		return
+	}
+	rate := *trend.WeeklyRate
+	if rate == 0 || math.Signbit(rate) != math.Signbit(remaining) {
+		trend.GoalStatus = models.GoalOffTrack
+		return
+	}
+
+	eta := time.Duration(remaining / rate * 7 * 24 * float64(time.Hour))
+	if eta > maxProjection {
+		trend.GoalStatus = models.GoalOffTrack
+		return
+	}
+	trend.GoalStatus = models.GoalOnTrack
+	date := timeutil.StartOfDay(latest.Add(eta), loc)
+	trend.ProjectedGoalDate = &date
+}
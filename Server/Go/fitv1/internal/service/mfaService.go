@@ -0,0 +1,295 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/AyushIIITU/virtualfit/internal/auth"
+	"github.com/AyushIIITU/virtualfit/internal/models"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// recoveryCodeCount is how many recovery codes a user gets at a time
+const recoveryCodeCount = 10
+
+var (
+	ErrMFAAlreadyEnabled = errors.New("two-factor authentication is already enabled")
+	ErrMFANotEnabled     = errors.New("two-factor authentication is not enabled")
+	ErrMFANotEnrolling   = errors.New("start two-factor enrollment first")
+	ErrInvalidMFACode    = errors.New("invalid two-factor code")
+	ErrInvalidMFAToken   = errors.New("invalid or expired MFA token; log in again")
+	ErrInvalidPassword   = errors.New("invalid password")
+)
+
+var recoveryEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// BeginMFAEnrollment creates a TOTP secret for the user to add to an
+// authenticator app. It only takes effect once confirmed with a valid code.
+func (s *Service) BeginMFAEnrollment(ctx context.Context, userID bson.ObjectID) (*models.MFAEnrollment, error) {
+	user, err := s.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.MFAEnabled {
+		return nil, ErrMFAAlreadyEnabled
+	}
+
+	secret, err := auth.NewTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+	user.MFAPendingSecret, err = s.tokens.Seal(secret)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.UpdateUser(ctx, user); err != nil {
+		return nil, err
+	}
+
+	return &models.MFAEnrollment{
+		Secret:          secret,
+		ProvisioningURI: auth.TOTPProvisioningURI(s.opts.MFAIssuer, user.Email, secret),
+	}, nil
+}
+
+// ConfirmMFAEnrollment enables TOTP once the user proves their app produces
+// valid codes, and returns the recovery codes. They are only shown this once.
+func (s *Service) ConfirmMFAEnrollment(ctx context.Context, userID bson.ObjectID, code string) ([]string, error) {
+	user, err := s.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.MFAEnabled {
+		return nil, ErrMFAAlreadyEnabled
+	}
+	if user.MFAPendingSecret == "" {
+		return nil, ErrMFANotEnrolling
+	}
+
+	secret, err := s.tokens.Open(user.MFAPendingSecret)
+	if err != nil {
+		return nil, err
+	}
+	step, ok := auth.ValidateTOTP(secret, code, time.Now())
+	if !ok {
+		return nil, ErrInvalidMFACode
+	}
+
+	codes, hashes, err := s.newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	user.MFAEnabled = true
+	user.MFASecret = user.MFAPendingSecret
+	user.MFAPendingSecret = ""
+	user.MFALastStep = step
+	user.MFARecoveryCodes = hashes
+	if err := s.repo.UpdateUser(ctx, user); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// DisableMFA turns TOTP off after checking the password and a current code.
+// Wrong passwords and codes count as failed logins from the client at ip.
+func (s *Service) DisableMFA(ctx context.Context, userID bson.ObjectID, password, code, ip string) error {
+	user, err := s.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !user.MFAEnabled {
+		return ErrMFANotEnabled
+	}
+	if err := s.checkLoginThrottle(ctx, user.Email, ip); err != nil {
+		return err
+	}
+	if bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)) != nil {
+		return s.secondFactorFailed(ctx, user, ip, ErrInvalidPassword)
+	}
+	if err := s.checkSecondFactor(ctx, user, code); err != nil {
+		return s.secondFactorFailed(ctx, user, ip, err)
+	}
+	if err := s.clearAccountLockout(ctx, user.Email); err != nil {
+		return err
+	}
+
+	clearMFA(user)
+	return s.repo.UpdateUser(ctx, user)
+}
+
+// RegenerateRecoveryCodes replaces the user's recovery codes. Wrong codes
+// count as failed logins from the client at ip.
+func (s *Service) RegenerateRecoveryCodes(ctx context.Context, userID bson.ObjectID, code, ip string) ([]string, error) {
+	user, err := s.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !user.MFAEnabled {
+		return nil, ErrMFANotEnabled
+	}
+	if err := s.checkLoginThrottle(ctx, user.Email, ip); err != nil {
+		return nil, err
+	}
+	if err := s.checkSecondFactor(ctx, user, code); err != nil {
+		return nil, s.secondFactorFailed(ctx, user, ip, err)
+	}
+	if err := s.clearAccountLockout(ctx, user.Email); err != nil {
+		return nil, err
+	}
+
+	codes, hashes, err := s.newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	user.MFARecoveryCodes = hashes
+	if err := s.repo.UpdateUser(ctx, user); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// CreateMFAChallenge is the result of the password step for users with TOTP
+func (s *Service) CreateMFAChallenge(ctx context.Context, user *models.User) (*models.MFAChallenge, error) {
+	token, _, err := s.tokens.IssueMFAToken(user.ID, s.opts.MFAChallengeTTL)
+	if err != nil {
+		return nil, err
+	}
+	return &models.MFAChallenge{
+		MFARequired: true,
+		MFAToken:    token,
+		ExpiresIn:   int(s.opts.MFAChallengeTTL.Seconds()),
+	}, nil
+}
+
+// VerifyMFA completes a two-factor login from the client at ip. A challenge
+// can be answered once; after a wrong code the user has to log in again.
+// Wrong codes count as failed logins.
+func (s *Service) VerifyMFA(ctx context.Context, mfaToken, code, ip string) (*models.User, *models.AuthTokens, error) {
+	claims, err := s.tokens.ParseMFAToken(mfaToken)
+	if err != nil {
+		return nil, nil, ErrInvalidMFAToken
+	}
+	revoked, err := s.repo.IsAccessTokenRevoked(ctx, claims.ID, time.Now())
+	if err != nil {
+		return nil, nil, err
+	}
+	if revoked {
+		return nil, nil, ErrInvalidMFAToken
+	}
+
+	userID, err := claims.UserObjectID()
+	if err != nil {
+		return nil, nil, ErrInvalidMFAToken
+	}
+	if err := s.revokeAccessToken(ctx, userID, claims.ID, claims.ExpiresAt.Time); err != nil {
+		return nil, nil, err
+	}
+
+	user, err := s.repo.GetUserByID(ctx, userID)
+	if errors.Is(err, ErrNotFound) {
+		return nil, nil, ErrInvalidMFAToken
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	if user.Disabled {
+		return nil, nil, ErrAccountDisabled
+	}
+	if !user.MFAEnabled {
+		return nil, nil, ErrInvalidMFAToken
+	}
+	if err := s.checkLoginThrottle(ctx, user.Email, ip); err != nil {
+		return nil, nil, err
+	}
+	if err := s.checkSecondFactor(ctx, user, code); err != nil {
+		return nil, nil, s.secondFactorFailed(ctx, user, ip, err)
+	}
+	if err := s.clearAccountLockout(ctx, user.Email); err != nil {
+		return nil, nil, err
+	}
+
+	tokens, err := s.IssueTokens(ctx, user)
+	if err != nil {
+		return nil, nil, err
+	}
+	return user, tokens, nil
+}
+
+// checkSecondFactor accepts a TOTP code not used before or an unused recovery
+// code, and stores that it was used. The store only lets one of two requests
+// with the same code through. user is updated to match.
+func (s *Service) checkSecondFactor(ctx context.Context, user *models.User, code string) error {
+	secret, err := s.tokens.Open(user.MFASecret)
+	if err != nil {
+		return err
+	}
+	if step, ok := auth.ValidateTOTP(secret, code, time.Now()); ok {
+		if err := s.repo.ConsumeMFAStep(ctx, user.ID, step); err != nil {
+			return usedSecondFactor(err)
+		}
+		user.MFALastStep = step
+		return nil
+	}
+
+	hash := s.tokens.HashToken(normalizeRecoveryCode(code))
+	if err := s.repo.ConsumeRecoveryCode(ctx, user.ID, hash); err != nil {
+		return usedSecondFactor(err)
+	}
+	user.MFARecoveryCodes = slices.DeleteFunc(user.MFARecoveryCodes, func(stored string) bool { return stored == hash })
+	return nil
+}
+
+// usedSecondFactor reports a code the store did not match as invalid
+func usedSecondFactor(err error) error {
+	if errors.Is(err, ErrNotFound) {
+		return ErrInvalidMFACode
+	}
+	return err
+}
+
+// secondFactorFailed counts a wrong password or code as a failed login and
+// returns err
+func (s *Service) secondFactorFailed(ctx context.Context, user *models.User, ip string, err error) error {
+	if !errors.Is(err, ErrInvalidMFACode) && !errors.Is(err, ErrInvalidPassword) {
+		return err
+	}
+	if recordErr := s.recordLoginFailure(ctx, user.Email, ip, user); recordErr != nil {
+		return recordErr
+	}
+	return err
+}
+
+// newRecoveryCodes returns fresh recovery codes and the hashes to store
+func (s *Service) newRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		b := make([]byte, 7)
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, err
+		}
+		raw := strings.ToLower(recoveryEncoding.EncodeToString(b))[:10]
+		codes[i] = raw[:5] + "-" + raw[5:]
+		hashes[i] = s.tokens.HashToken(raw)
+	}
+	return codes, hashes, nil
+}
+
+// normalizeRecoveryCode ignores case, dashes and spaces
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+}
+
+func clearMFA(user *models.User) {
+	user.MFAEnabled = false
+	user.MFASecret = ""
+	user.MFAPendingSecret = ""
+	user.MFARecoveryCodes = nil
+	user.MFALastStep = 0
+}
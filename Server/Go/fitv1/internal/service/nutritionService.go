@@ -0,0 +1,158 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/AyushIIITU/virtualfit/internal/models"
+	"github.com/AyushIIITU/virtualfit/internal/timeutil"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// maxSummaryPeriods bounds the size of a nutrition summary response
+const maxSummaryPeriods = 400
+
+// unspecifiedMealType groups entries logged without a meal type
+const unspecifiedMealType = "unspecified"
+
+var (
+	ErrInvalidRange       = errors.New("from must not be after to")
+	ErrRangeTooLarge      = errors.New("date range is too large for the requested granularity")
+	ErrInvalidGranularity = errors.New("granularity must be day or week")
+)
+
+// GetNutritionSummary aggregates a user's food intake per day or week between
+// the local dates from and to (both inclusive) and compares it with their
+// targets. Weeks start on the user's first day of the week.
+func (s *Service) GetNutritionSummary(ctx context.Context, userID bson.ObjectID, from, to time.Time, granularity string, loc *time.Location) (*models.NutritionSummary, error) {
+	if !timeutil.ValidGranularity(granularity) {
+		return nil, ErrInvalidGranularity
+	}
+	start := timeutil.StartOfDay(from, loc)
+	end := timeutil.StartOfDay(to, loc).AddDate(0, 0, 1)
+	if !start.Before(end) {
+		return nil, ErrInvalidRange
+	}
+
+	user, err := s.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	weekStart := user.WeekStart()
+
+	// Lay out every period up front so days without entries are still reported
+	var periods []models.NutritionPeriod
+	index := make(map[time.Time]int)
+	for p := timeutil.Truncate(start, granularity, loc, weekStart); p.Before(end); p = timeutil.Next(p, granularity) {
+		if len(periods) == maxSummaryPeriods {
+			return nil, ErrRangeTooLarge
+		}
+		periodStart, periodEnd := p, timeutil.Next(p, granularity)
+		if periodStart.Before(start) {
+			periodStart = start
+		}
+		if periodEnd.After(end) {
+			periodEnd = end
+		}
+		index[p.UTC()] = len(periods)
+		periods = append(periods, models.NutritionPeriod{
+			Start:  periodStart,
+			End:    periodEnd,
+			Days:   daysBetween(periodStart, periodEnd),
+			Totals: make(map[string]models.NutrientTotal),
+			Meals:  make(map[string]models.MealBreakdown),
+		})
+	}
+
+	buckets, err := s.repo.AggregateNutrition(ctx, models.NutritionQuery{
+		UserID:      userID,
+		From:        start,
+		To:          end,
+		Granularity: granularity,
+		Timezone:    loc.String(),
+		WeekStart:   weekStart,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, bucket := range buckets {
+		i, ok := index[bucket.Period.UTC()]
+		if !ok {
+			continue
+		}
+		period := &periods[i]
+
+		total := period.Totals[bucket.Nutrient]
+		total.Amount += bucket.Amount
+		total.Unit = bucket.Unit
+		period.Totals[bucket.Nutrient] = total
+
+		mealType := bucket.MealType
+		if mealType == "" {
+			mealType = unspecifiedMealType
+		}
+		meal, ok := period.Meals[mealType]
+		if !ok {
+			meal.Nutrients = make(map[string]models.NutrientTotal)
+		}
+		meal.Entries = max(meal.Entries, bucket.Entries)
+		meal.Nutrients[bucket.Nutrient] = models.NutrientTotal{Amount: bucket.Amount, Unit: bucket.Unit}
+		period.Meals[mealType] = meal
+	}
+
+	for i := range periods {
+		period := &periods[i]
+		period.Targets = []models.TargetProgress{
+			targetProgress(models.NutrientCalories, "kcal", float64(user.DailyCalorieIntake*period.Days), period.Totals),
+			targetProgress(models.NutrientProtein, "g", float64(user.DailyProteinIntake*period.Days), period.Totals),
+		}
+		// Carb and fat targets are optional; zero means the user has none
+		if user.DailyCarbIntake > 0 {
+			period.Targets = append(period.Targets, targetProgress(models.NutrientCarbohydrates, "g", float64(user.DailyCarbIntake*period.Days), period.Totals))
+		}
+		if user.DailyFatIntake > 0 {
+			period.Targets = append(period.Targets, targetProgress(models.NutrientFat, "g", float64(user.DailyFatIntake*period.Days), period.Totals))
+		}
+	}
+
+	summary := &models.NutritionSummary{
+		From:        start,
+		To:          end,
+		Granularity: granularity,
+		Timezone:    loc.String(),
+		Periods:     periods,
+	}
+	if granularity == timeutil.Week {
+		summary.WeekStart = strings.ToLower(weekStart.String())
+	}
+	return summary, nil
+}
+
+// targetProgress compares the consumed amount of a nutrient with its target
+func targetProgress(nutrient, unit string, target float64, totals map[string]models.NutrientTotal) models.TargetProgress {
+	consumed := totals[nutrient].Amount
+	progress := models.TargetProgress{
+		Nutrient:  nutrient,
+		Unit:      unit,
+		Target:    target,
+		Consumed:  round1(consumed),
+		Remaining: round1(target - consumed),
+	}
+	if target > 0 {
+		progress.Percent = round1(consumed / target * 100)
+	}
+	return progress
+}
+
+// daysBetween counts calendar days, ignoring DST shifts in the wall clock
+func daysBetween(start, end time.Time) int {
+	return int(math.Round(end.Sub(start).Hours() / 24))
+}
+
+func round1(v float64) float64 {
+	return math.Round(v*10) / 10
+}
@@ -0,0 +1,227 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/AyushIIITU/virtualfit/internal/models"
+	"github.com/AyushIIITU/virtualfit/internal/program"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// defaultProgramWeeks is how long a program runs unless the request says otherwise
+const defaultProgramWeeks = 4
+
+var (
+	ErrInvalidProgram  = errors.New("invalid program")
+	ErrProgramExercise = errors.New("a program exercise needs a rep range or a duration, with reps_min not above reps_max")
+	ErrNoProgram       = errors.New("no catalog exercise fits the profile")
+)
+
+// GenerateProgram builds a program from the user's profile and the workout
+// catalog and saves it
+func (s *Service) GenerateProgram(ctx context.Context, userID bson.ObjectID, req *models.ProgramGenerateRequest) (*models.Program, error) {
+	if err := req.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidProgram, err)
+	}
+	user, err := s.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	workouts, err := s.repo.GetWorkout(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+
+	profile := program.Profile{
+		DaysPerWeek:          user.DaysPerWeek,
+		FitnessLevel:         user.CurrentFitnessLevel,
+		Goals:                user.Goals,
+		InterestedActivities: user.InterestedActivities,
+		// Medical conditions rule out exercises just as health considerations do
+		HealthConsiderations: append(append([]string{}, user.HealthConsiderations...), user.MedicalConditions...),
+		Equipment:            user.Equipment,
+	}
+	if req.DaysPerWeek > 0 {
+		profile.DaysPerWeek = req.DaysPerWeek
+	}
+	if req.Equipment != nil {
+		profile.Equipment = *req.Equipment
+	}
+
+	catalog := make([]program.Exercise, 0, len(workouts))
+	for _, w := range workouts {
+		catalog = append(catalog, program.Exercise{
+			ID:        w.ID.Hex(),
+			Name:      w.Name,
+			Level:     w.Level,
+			Mechanic:  w.Mechanic,
+			Equipment: w.Equipment,
+			Category:  w.Category,
+			Primary:   w.PrimaryMuscles,
+		})
+	}
+	plan := program.Generate(profile, catalog, req.Goal)
+
+	now := time.Now()
+	p := &models.Program{
+		UserID:      userID,
+		Name:        req.Name,
+		Goal:        plan.Goal,
+		Split:       plan.Split,
+		Weeks:       req.Weeks,
+		Notes:       plan.Notes,
+		Generated:   true,
+		Progression: defaultProgression(plan.Goal, user.UnitSystem()),
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	if p.Name == "" {
+		p.Name = fmt.Sprintf("%d-day %s", len(plan.Days), splitNames[plan.Split])
+	}
+	if p.Weeks == 0 {
+		p.Weeks = defaultProgramWeeks
+	}
+	empty := true
+	for _, day := range plan.Days {
+		pd := models.ProgramDay{ID: bson.NewObjectID(), Name: day.Name, Focus: day.Focus, Exercises: []models.ProgramExercise{}}
+		for _, pr := range day.Exercises {
+			workoutID, _ := bson.ObjectIDFromHex(pr.ExerciseID)
+			pe := models.ProgramExercise{ID: bson.NewObjectID(), WorkoutID: workoutID, Sets: pr.Sets}
+			if pr.RepsMax > 0 {
+				pe.RepsMin, pe.RepsMax = &pr.RepsMin, &pr.RepsMax
+			}
+			if pr.Duration > 0 {
+				pe.Duration = &pr.Duration
+			}
+			if pr.Rest > 0 {
+				pe.Rest = &pr.Rest
+			}
+			pd.Exercises = append(pd.Exercises, pe)
+			empty = false
+		}
+		p.Days = append(p.Days, pd)
+	}
+	if empty {
+		return nil, ErrNoProgram
+	}
+	return s.repo.CreateProgram(ctx, p)
+}
+
+// splitNames are how generated program names describe each split
+var splitNames = map[string]string{
+	program.FullBody:     "full body",
+	program.UpperLower:   "upper/lower",
+	program.PushPullLegs: "push/pull/legs",
+}
+
+// CreateProgram saves a program written by hand
+func (s *Service) CreateProgram(ctx context.Context, userID bson.ObjectID, input *models.ProgramInput) (*models.Program, error) {
+	if err := input.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidProgram, err)
+	}
+	user, err := s.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	p := &models.Program{
+		UserID:      userID,
+		Name:        input.Name,
+		Goal:        input.Goal,
+		Weeks:       input.Weeks,
+		Progression: defaultProgression(input.Goal, user.UnitSystem()),
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	if p.Weeks == 0 {
+		p.Weeks = defaultProgramWeeks
+	}
+	if input.Progression != nil {
+		input.Progression.Apply(p.Progression)
+	}
+	for i := range input.Days {
+		p.Days = append(p.Days, input.Days[i].Day())
+	}
+	if err := s.checkProgram(ctx, p); err != nil {
+		return nil, err
+	}
+	return s.repo.CreateProgram(ctx, p)
+}
+
+// GetProgram returns one of the user's programs
+func (s *Service) GetProgram(ctx context.Context, userID, id bson.ObjectID) (*models.Program, error) {
+	p, err := s.repo.GetProgramByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := authorize(userID, p.UserID); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// ListPrograms pages through a user's programs, newest first
+func (s *Service) ListPrograms(ctx context.Context, query models.ProgramQuery) ([]*models.Program, int64, error) {
+	return s.repo.ListPrograms(ctx, query)
+}
+
+// UpdateProgram changes a program's name, goal, length, days or progression
+func (s *Service) UpdateProgram(ctx context.Context, userID, id bson.ObjectID, update *models.ProgramUpdate) (*models.Program, error) {
+	if err := update.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidProgram, err)
+	}
+	p, err := s.GetProgram(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	// A program saved without a progression gets the defaults it ran on
+	if p.Progression, err = s.programProgression(ctx, p); err != nil {
+		return nil, err
+	}
+	update.Apply(p)
+	if err := s.checkProgram(ctx, p); err != nil {
+		return nil, err
+	}
+	p.UpdatedAt = time.Now()
+	if err := s.repo.UpdateProgram(ctx, p); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// DeleteProgram removes one of the user's programs
+func (s *Service) DeleteProgram(ctx context.Context, userID, id bson.ObjectID) error {
+	if _, err := s.GetProgram(ctx, userID, id); err != nil {
+		return err
+	}
+	return s.repo.DeleteProgram(ctx, id)
+}
+
+// checkProgram rejects exercises without a dose and exercises that are not
+// in the catalog
+func (s *Service) checkProgram(ctx context.Context, p *models.Program) error {
+	known := make(map[bson.ObjectID]bool)
+	for _, day := range p.Days {
+		for _, e := range day.Exercises {
+			reps := e.RepsMin != nil && e.RepsMax != nil && *e.RepsMin <= *e.RepsMax
+			if !reps && (e.Duration == nil || e.RepsMin != nil || e.RepsMax != nil) {
+				return ErrProgramExercise
+			}
+			if known[e.WorkoutID] {
+				continue
+			}
+			if _, err := s.repo.GetWorkoutByID(ctx, e.WorkoutID); err != nil {
+				if errors.Is(err, ErrNotFound) {
+					return ErrUnknownWorkout
+				}
+				return err
+			}
+			known[e.WorkoutID] = true
+		}
+	}
+	return nil
+}
@@ -0,0 +1,204 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"slices"
+
+	"github.com/AyushIIITU/virtualfit/internal/models"
+	"github.com/AyushIIITU/virtualfit/internal/progression"
+	"github.com/AyushIIITU/virtualfit/internal/units"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+var ErrUnknownProgramDay = errors.New("day is not in the program")
+
+// defaultProgression is how a program progresses unless it says otherwise:
+// linearly for strength and by double progression for every other goal, in
+// steps of 2.5 kg, or 5 lb for users of imperial units
+func defaultProgression(goal, system string) *models.Progression {
+	p := &models.Progression{
+		Strategy:      progression.Double,
+		Increment:     2.5,
+		DeloadAfter:   progression.DefaultDeloadAfter,
+		DeloadPercent: progression.DefaultDeloadPercent,
+		TargetRPE:     progression.DefaultTargetRPE,
+	}
+	if goal == "strength" {
+		p.Strategy = progression.Linear
+	}
+	if system == units.Imperial {
+		p.Increment, _ = units.Convert(5, units.Pound, units.Kilogram)
+	}
+	return p
+}
+
+// programProgression returns a program's progression, or the defaults for
+// programs saved without one
+func (s *Service) programProgression(ctx context.Context, p *models.Program) (*models.Progression, error) {
+	if p.Progression != nil {
+		return p.Progression, nil
+	}
+	user, err := s.repo.GetUserByID(ctx, p.UserID)
+	if err != nil {
+		return nil, err
+	}
+	return defaultProgression(p.Goal, user.UnitSystem()), nil
+}
+
+// GetNextSession recommends the load and reps of each exercise of a program
+// day from the user's history of the exercise. The program defaults to the
+// user's newest and the day to the one after the day last trained.
+func (s *Service) GetNextSession(ctx context.Context, query models.NextSessionQuery) (*models.NextSession, error) {
+	p, err := s.currentProgram(ctx, query.UserID, query.ProgramID)
+	if err != nil {
+		return nil, err
+	}
+	settings, err := s.programProgression(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+	day, err := s.nextProgramDay(ctx, p, query.DayID)
+	if err != nil {
+		return nil, err
+	}
+
+	next := &models.NextSession{
+		ProgramID:   p.ID,
+		ProgramName: p.Name,
+		DayID:       day.ID,
+		DayName:     day.Name,
+		Progression: *settings,
+		Exercises:   []models.NextExercise{},
+	}
+	for _, e := range day.Exercises {
+		name, err := s.workoutName(ctx, e.WorkoutID)
+		if err != nil {
+			return nil, err
+		}
+		ne := models.NextExercise{ExerciseID: e.ID, WorkoutID: e.WorkoutID, Name: name, Sets: e.Sets}
+		if e.RepsMin == nil || e.RepsMax == nil {
+			ne.Action = progression.Repeat
+			ne.Duration = e.Duration
+			ne.Reason = "timed exercises keep their duration"
+			next.Exercises = append(next.Exercises, ne)
+			continue
+		}
+
+		sessions, err := s.allWorkoutSessions(ctx, models.WorkoutSessionQuery{UserID: p.UserID, WorkoutID: e.WorkoutID})
+		if err != nil {
+			return nil, err
+		}
+		history := make([]progression.Session, 0, len(sessions))
+		for _, session := range sessions {
+			history = append(history, progressionSession(session, e.WorkoutID))
+		}
+		rec := progression.Recommend(progression.Settings{
+			Strategy:      settings.Strategy,
+			Increment:     settings.Increment,
+			DeloadAfter:   settings.DeloadAfter,
+			DeloadPercent: settings.DeloadPercent,
+			TargetRPE:     settings.TargetRPE,
+		}, progression.Target{Sets: e.Sets, RepsMin: *e.RepsMin, RepsMax: *e.RepsMax}, history)
+
+		ne.Action, ne.Stalls, ne.Reason = rec.Action, rec.Stalls, rec.Reason
+		ne.Sets, ne.Reps = rec.Sets, &rec.Reps
+		if rec.Action != progression.Start {
+			ne.Load = &rec.Load
+		}
+		if rec.TargetRPE > 0 {
+			ne.TargetRPE = &rec.TargetRPE
+		}
+		if last := rec.Last; last != nil {
+			ne.Last = &models.LastPerformance{StartedAt: last.At, Load: last.Load, Sets: last.Sets, Reps: last.Reps}
+			if last.RPE > 0 {
+				ne.Last.RPE = &last.RPE
+			}
+		}
+		next.Exercises = append(next.Exercises, ne)
+	}
+	return next, nil
+}
+
+// currentProgram returns one of the user's programs, or their newest when id
+// is zero
+func (s *Service) currentProgram(ctx context.Context, userID, id bson.ObjectID) (*models.Program, error) {
+	if !id.IsZero() {
+		return s.GetProgram(ctx, userID, id)
+	}
+	programs, _, err := s.repo.ListPrograms(ctx, models.ProgramQuery{UserID: userID, Limit: 1})
+	if err != nil {
+		return nil, err
+	}
+	if len(programs) == 0 {
+		return nil, ErrNotFound
+	}
+	return programs[0], nil
+}
+
+// nextProgramDay returns the day with the given ID or, when it is zero, the
+// first day with exercises after the one the user last trained. The day last
+// trained is the one sharing the most exercises with the user's latest
+// session since the program was created; without one the week starts over.
+func (s *Service) nextProgramDay(ctx context.Context, p *models.Program, dayID bson.ObjectID) (*models.ProgramDay, error) {
+	if !dayID.IsZero() {
+		for i := range p.Days {
+			if p.Days[i].ID == dayID {
+				return &p.Days[i], nil
+			}
+		}
+		return nil, ErrUnknownProgramDay
+	}
+
+	last := -1
+	sessions, _, err := s.repo.ListWorkoutSessions(ctx, models.WorkoutSessionQuery{UserID: p.UserID, From: p.CreatedAt, Limit: 1})
+	if err != nil {
+		return nil, err
+	}
+	if len(sessions) > 0 {
+		trained := sessionWorkouts(sessions[0])
+		most := 0
+		for i, day := range p.Days {
+			shared := 0
+			for _, e := range day.Exercises {
+				if slices.Contains(trained, e.WorkoutID) {
+					shared++
+				}
+			}
+			if shared > most {
+				last, most = i, shared
+			}
+		}
+	}
+	for k := 1; k <= len(p.Days); k++ {
+		day := &p.Days[(last+k)%len(p.Days)]
+		if len(day.Exercises) > 0 {
+			return day, nil
+		}
+	}
+	return &p.Days[0], nil
+}
+
+// progressionSession collects the sets of one exercise in a session, in order
+func progressionSession(session *models.WorkoutSession, workoutID bson.ObjectID) progression.Session {
+	ps := progression.Session{At: session.StartedAt}
+	for _, entry := range session.Entries {
+		if entry.WorkoutID != workoutID {
+			continue
+		}
+		for _, set := range entry.Sets {
+			x := progression.Set{Warmup: set.Warmup}
+			if set.Load != nil {
+				x.Load = *set.Load
+			}
+			if set.Reps != nil {
+				x.Reps = *set.Reps
+			}
+			if set.RPE != nil {
+				x.RPE = *set.RPE
+			}
+			ps.Sets = append(ps.Sets, x)
+		}
+	}
+	return ps
+}
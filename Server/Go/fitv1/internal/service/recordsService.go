@@ -0,0 +1,269 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/AyushIIITU/virtualfit/internal/models"
+	"github.com/AyushIIITU/virtualfit/internal/records"
+	"github.com/AyushIIITU/virtualfit/internal/units"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// GetPersonalRecords returns the user's records for every exercise they have
+// logged, by the one-rep max formula given or else the one they prefer
+func (s *Service) GetPersonalRecords(ctx context.Context, userID bson.ObjectID, formula string) (*models.PersonalRecords, error) {
+	formula, err := s.recordFormula(ctx, userID, formula)
+	if err != nil {
+		return nil, err
+	}
+	sessions, err := s.allWorkoutSessions(ctx, models.WorkoutSessionQuery{UserID: userID})
+	if err != nil {
+		return nil, err
+	}
+
+	trackers := make(map[bson.ObjectID]*records.Tracker)
+	var order []bson.ObjectID
+	for _, session := range sessions {
+		for _, workoutID := range sessionWorkouts(session) {
+			tracker, ok := trackers[workoutID]
+			if !ok {
+				tracker = records.NewTracker(formula)
+				trackers[workoutID] = tracker
+				order = append(order, workoutID)
+			}
+			tracker.Add(recordSession(session, workoutID))
+		}
+	}
+
+	result := &models.PersonalRecords{Formula: formula, Exercises: []models.ExerciseRecords{}}
+	for _, workoutID := range order {
+		name, err := s.workoutName(ctx, workoutID)
+		if err != nil {
+			return nil, err
+		}
+		result.Exercises = append(result.Exercises, models.ExerciseRecords{
+			WorkoutID: workoutID,
+			Name:      name,
+			Records:   personalRecords(workoutID, trackers[workoutID].Best()),
+		})
+	}
+	sort.SliceStable(result.Exercises, func(i, j int) bool {
+		return strings.ToLower(result.Exercises[i].Name) < strings.ToLower(result.Exercises[j].Name)
+	})
+	return result, nil
+}
+
+// GetRecordHistory reports each session of one exercise between from and to
+// (from inclusive, to exclusive, either may be zero), oldest first, with the
+// records it set. Records are counted from the start of the range.
+func (s *Service) GetRecordHistory(ctx context.Context, userID, workoutID bson.ObjectID, formula string, from, to time.Time) (*models.RecordHistory, error) {
+	formula, err := s.recordFormula(ctx, userID, formula)
+	if err != nil {
+		return nil, err
+	}
+	name, err := s.workoutName(ctx, workoutID)
+	if err != nil {
+		return nil, err
+	}
+	sessions, err := s.allWorkoutSessions(ctx, models.WorkoutSessionQuery{
+		UserID:    userID,
+		WorkoutID: workoutID,
+		From:      from,
+		To:        to,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	history := &models.RecordHistory{
+		WorkoutID: workoutID,
+		Name:      name,
+		Formula:   formula,
+		Sessions:  []models.RecordSession{},
+	}
+	tracker := records.NewTracker(formula)
+	for _, session := range sessions {
+		rs := recordSession(session, workoutID)
+		summary := records.Summarize(rs, formula)
+		point := models.RecordSession{
+			SessionID:    session.ID,
+			StartedAt:    session.StartedAt,
+			Sets:         summary.Sets,
+			Reps:         summary.Reps,
+			TopLoad:      summary.TopLoad,
+			Estimated1RM: units.Round(summary.Estimated1RM),
+			Volume:       units.Round(summary.Volume),
+			Records:      []string{},
+		}
+		for _, r := range tracker.Add(rs) {
+			if !slices.Contains(point.Records, r.Kind) {
+				point.Records = append(point.Records, r.Kind)
+			}
+		}
+		history.Sessions = append(history.Sessions, point)
+	}
+	history.Records = personalRecords(workoutID, tracker.Best())
+	return history, nil
+}
+
+// flagNewRecords sets the records the given sets of a saved session set, by
+// the user's preferred formula. Only earlier sets are compared, so a set
+// logged into an old session is judged against what came before it.
+func (s *Service) flagNewRecords(ctx context.Context, session *models.WorkoutSession, setIDs []bson.ObjectID) {
+	if len(setIDs) == 0 {
+		return
+	}
+	formula, err := s.recordFormula(ctx, session.UserID, "")
+	if err != nil {
+		log.Printf("Error loading the record formula of user %s: %v", session.UserID.Hex(), err)
+		return
+	}
+
+	// Exercises of the session that the sets belong to
+	var workouts []bson.ObjectID
+	for _, entry := range session.Entries {
+		for _, set := range entry.Sets {
+			if slices.Contains(setIDs, set.ID) && !slices.Contains(workouts, entry.WorkoutID) {
+				workouts = append(workouts, entry.WorkoutID)
+			}
+		}
+	}
+
+	newSet := make(map[string]bool)
+	for _, id := range setIDs {
+		newSet[id.Hex()] = true
+	}
+	for _, workoutID := range workouts {
+		sessions, err := s.allWorkoutSessions(ctx, models.WorkoutSessionQuery{
+			UserID:    session.UserID,
+			WorkoutID: workoutID,
+			To:        session.StartedAt.Add(time.Nanosecond),
+		})
+		if err != nil {
+			log.Printf("Error loading the history of session %s: %v", session.ID.Hex(), err)
+			return
+		}
+
+		tracker := records.NewTracker(formula)
+		for _, earlier := range sessions {
+			if earlier.ID == session.ID {
+				continue
+			}
+			tracker.Add(recordSession(earlier, workoutID))
+		}
+		var flagged []records.Record
+		for _, r := range tracker.Add(recordSession(session, workoutID)) {
+			// Volume records count when a new set is part of the session
+			if newSet[r.SetID] || r.Kind == records.SessionVolume {
+				flagged = append(flagged, r)
+			}
+		}
+		session.NewRecords = append(session.NewRecords, personalRecords(workoutID, flagged)...)
+	}
+}
+
+// recordFormula returns formula if set, else the user's preferred formula
+func (s *Service) recordFormula(ctx context.Context, userID bson.ObjectID, formula string) (string, error) {
+	if formula != "" {
+		if !records.ValidFormula(formula) {
+			return "", records.ErrUnknownFormula
+		}
+		return formula, nil
+	}
+	user, err := s.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		return "", err
+	}
+	if records.ValidFormula(user.OneRepMaxFormula) {
+		return user.OneRepMaxFormula, nil
+	}
+	return records.Epley, nil
+}
+
+// allWorkoutSessions returns every session matching query, oldest first
+func (s *Service) allWorkoutSessions(ctx context.Context, query models.WorkoutSessionQuery) ([]*models.WorkoutSession, error) {
+	query.Limit, query.Offset = 0, 0
+	sessions, _, err := s.repo.ListWorkoutSessions(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	slices.Reverse(sessions)
+	return sessions, nil
+}
+
+// workoutName returns the catalog name of an exercise, or "" when it has
+// since been removed from the catalog
+func (s *Service) workoutName(ctx context.Context, workoutID bson.ObjectID) (string, error) {
+	workout, err := s.repo.GetWorkoutByID(ctx, workoutID)
+	if errors.Is(err, ErrNotFound) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return workout.Name, nil
+}
+
+// sessionWorkouts returns the distinct exercises of a session, in order
+func sessionWorkouts(session *models.WorkoutSession) []bson.ObjectID {
+	var workouts []bson.ObjectID
+	for _, entry := range session.Entries {
+		if !slices.Contains(workouts, entry.WorkoutID) {
+			workouts = append(workouts, entry.WorkoutID)
+		}
+	}
+	return workouts
+}
+
+// recordSession collects the sets of one exercise in a session, in order
+func recordSession(session *models.WorkoutSession, workoutID bson.ObjectID) records.Session {
+	rs := records.Session{ID: session.ID.Hex(), At: session.StartedAt}
+	for _, entry := range session.Entries {
+		if entry.WorkoutID != workoutID {
+			continue
+		}
+		for _, set := range entry.Sets {
+			x := records.Set{ID: set.ID.Hex(), Warmup: set.Warmup}
+			if set.Load != nil {
+				x.Load = *set.Load
+			}
+			if set.Reps != nil {
+				x.Reps = *set.Reps
+			}
+			rs.Sets = append(rs.Sets, x)
+		}
+	}
+	return rs
+}
+
+// personalRecords converts records of an exercise for a response
+func personalRecords(workoutID bson.ObjectID, found []records.Record) []models.PersonalRecord {
+	out := []models.PersonalRecord{}
+	for _, r := range found {
+		pr := models.PersonalRecord{Kind: r.Kind, WorkoutID: workoutID, AchievedAt: r.At}
+		pr.SessionID, _ = bson.ObjectIDFromHex(r.SessionID)
+		if setID, err := bson.ObjectIDFromHex(r.SetID); err == nil {
+			pr.SetID = &setID
+		}
+
+		// Estimates and volumes are derived, so trim them like converted values
+		value := units.Round(r.Value)
+		switch r.Kind {
+		case records.SessionVolume:
+			pr.Volume = &value
+		case records.Estimated1RM:
+			pr.Estimated1RM = &value
+			fallthrough
+		default:
+			load, reps := r.Load, r.Reps
+			pr.Load, pr.Reps = &load, &reps
+		}
+		out = append(out, pr)
+	}
+	return out
+}
@@ -4,6 +4,10 @@ import (
 	"context"
 	"time"
 
+	"github.com/AyushIIITU/virtualfit/internal/analyzer"
+	"github.com/AyushIIITU/virtualfit/internal/auth"
+	"github.com/AyushIIITU/virtualfit/internal/jobs"
+	"github.com/AyushIIITU/virtualfit/internal/mailer"
 	"github.com/AyushIIITU/virtualfit/internal/models"
 	"github.com/AyushIIITU/virtualfit/internal/repository"
 	"go.mongodb.org/mongo-driver/v2/bson"
@@ -16,12 +20,43 @@ import (
 
 var log = logrus.New()
 
+// ErrNotFound is returned when a record does not exist or belongs to another user
+var ErrNotFound = repository.ErrNotFound
+
+// ErrEmailTaken is returned when another account already uses an email address
+var ErrEmailTaken = repository.ErrEmailTaken
+
+// ErrConflict is returned when a record kept changing under an edit
+var ErrConflict = repository.ErrConflict
+
+// Options holds the service settings that come from configuration
+type Options struct {
+	RefreshTokenTTL      time.Duration
+	EmailVerificationTTL time.Duration
+	PasswordResetTTL     time.Duration
+	PublicURL            string   // base URL of this API, used in emailed links
+	PasswordResetURL     string   // client page that completes a password reset
+	AdminEmails          []string // lower-case addresses promoted to admin once verified
+	MFAIssuer            string   // name authenticator apps show for our codes
+	MFAChallengeTTL      time.Duration
+	AccountLockout       LockoutPolicy
+	IPLockout            LockoutPolicy
+	LoginFailureWindow   time.Duration // failures are forgotten after this long without another
+}
+
 type Service struct {
-	repo *repository.MongoDB
+	repo     repository.Store
+	jobs     *jobs.Queue
+	analyzer analyzer.FoodAnalyzer
+	tokens   *auth.TokenService
+	mailer   mailer.Mailer
+	opts     Options
 }
 
-func NewService(repo *repository.MongoDB) *Service {
-	return &Service{repo: repo}
+func NewService(repo repository.Store, queue *jobs.Queue, foodAnalyzer analyzer.FoodAnalyzer, tokens *auth.TokenService, mail mailer.Mailer, opts Options) *Service {
+	s := &Service{repo: repo, jobs: queue, analyzer: foodAnalyzer, tokens: tokens, mailer: mail, opts: opts}
+	queue.Register(models.JobTypeFoodAnalysis, s.runFoodAnalysisJob, s.recordFoodAnalysisFailure)
+	return s
 }
 
 // Exercise Service
@@ -37,10 +72,11 @@ func (s *Service) GetDietPlanData(ctx context.Context, userID bson.ObjectID) (*m
 		ID:                     user.ID,
 		Name:                   user.Name,
 		Email:                  user.Email,
-		Age:                    int(time.Now().Sub(user.DOB).Hours() / 24 / 365.25),
+		Age:                    user.Age(time.Now().In(user.Location())),
 		Gender:                 user.Gender,
 		Height:                 user.Height,
 		Weight:                 user.Weight,
+		Units:                  user.UnitSystem(),
 		Goals:                  user.Goals,
 		DietaryRestrictions:    user.DietaryRestrictions,
 		DailyCalorieIntake:     user.DailyCalorieIntake,
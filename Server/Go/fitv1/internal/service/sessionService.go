@@ -0,0 +1,341 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/AyushIIITU/virtualfit/internal/models"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+const (
+	// maxSessionEntries and maxEntrySets keep a session a reasonable document
+	maxSessionEntries = 50
+	maxEntrySets      = 50
+	// sessionClockSkew allows sessions stamped slightly ahead of the server clock
+	sessionClockSkew = 5 * time.Minute
+	// sessionEditAttempts is how often an edit is redone on a session that
+	// another request changed in the meantime
+	sessionEditAttempts = 5
+)
+
+var (
+	ErrInvalidSession  = errors.New("invalid workout session")
+	ErrFutureSession   = errors.New("started_at must not be in the future")
+	ErrSessionEnd      = errors.New("ended_at must not be before started_at")
+	ErrEmptySet        = errors.New("a set needs reps, a duration or a distance")
+	ErrUnknownWorkout  = errors.New("workout not found in the catalog")
+	ErrEntryNotFound   = errors.New("exercise entry not found")
+	ErrSetNotFound     = errors.New("set not found")
+	ErrSessionTooLarge = fmt.Errorf("a session holds at most %d exercises of %d sets each", maxSessionEntries, maxEntrySets)
+	ErrInvalidPosition = errors.New("position is past the end of the session")
+)
+
+// CreateWorkoutSession starts a session, now unless the input says otherwise
+func (s *Service) CreateWorkoutSession(ctx context.Context, userID bson.ObjectID, input *models.WorkoutSessionInput) (*models.WorkoutSession, error) {
+	if err := input.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSession, err)
+	}
+
+	now := time.Now()
+	session := &models.WorkoutSession{
+		UserID:    userID,
+		Name:      input.Name,
+		StartedAt: now,
+		EndedAt:   input.EndedAt,
+		Notes:     input.Notes,
+		Entries:   []models.SessionEntry{},
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if input.StartedAt != nil {
+		session.StartedAt = *input.StartedAt
+	}
+	var added []bson.ObjectID
+	for i := range input.Entries {
+		setIDs, err := s.addEntry(ctx, session, &input.Entries[i])
+		if err != nil {
+			return nil, err
+		}
+		added = append(added, setIDs...)
+	}
+	if err := checkSession(session, now); err != nil {
+		return nil, err
+	}
+
+	session, err := s.repo.CreateWorkoutSession(ctx, session)
+	if err != nil {
+		return nil, err
+	}
+	s.flagNewRecords(ctx, session, added)
+	return session, nil
+}
+
+// GetWorkoutSession returns one of the user's sessions
+func (s *Service) GetWorkoutSession(ctx context.Context, userID, id bson.ObjectID) (*models.WorkoutSession, error) {
+	session, err := s.repo.GetWorkoutSessionByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := authorize(userID, session.UserID); err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+// ListWorkoutSessions pages through a user's sessions, newest first
+func (s *Service) ListWorkoutSessions(ctx context.Context, query models.WorkoutSessionQuery) ([]*models.WorkoutSession, int64, error) {
+	return s.repo.ListWorkoutSessions(ctx, query)
+}
+
+// UpdateWorkoutSession changes the name, notes or times of a session
+func (s *Service) UpdateWorkoutSession(ctx context.Context, userID, id bson.ObjectID, update *models.WorkoutSessionUpdate) (*models.WorkoutSession, error) {
+	if err := update.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSession, err)
+	}
+	return s.editWorkoutSession(ctx, userID, id, func(session *models.WorkoutSession) error {
+		update.Apply(session)
+		return nil
+	})
+}
+
+// DeleteWorkoutSession removes one of the user's sessions
+func (s *Service) DeleteWorkoutSession(ctx context.Context, userID, id bson.ObjectID) error {
+	if _, err := s.GetWorkoutSession(ctx, userID, id); err != nil {
+		return err
+	}
+	return s.repo.DeleteWorkoutSession(ctx, id)
+}
+
+// AddSessionEntry adds a catalog exercise, with any sets already done, to a session
+func (s *Service) AddSessionEntry(ctx context.Context, userID, sessionID bson.ObjectID, input *models.SessionEntryInput) (*models.WorkoutSession, error) {
+	if err := input.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSession, err)
+	}
+	var added []bson.ObjectID
+	session, err := s.editWorkoutSession(ctx, userID, sessionID, func(session *models.WorkoutSession) error {
+		var err error
+		added, err = s.addEntry(ctx, session, input)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.flagNewRecords(ctx, session, added)
+	return session, nil
+}
+
+// UpdateSessionEntry changes an entry's notes or moves it within its session
+func (s *Service) UpdateSessionEntry(ctx context.Context, userID, sessionID, entryID bson.ObjectID, update *models.SessionEntryUpdate) (*models.WorkoutSession, error) {
+	if err := update.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSession, err)
+	}
+	return s.editWorkoutSession(ctx, userID, sessionID, func(session *models.WorkoutSession) error {
+		i := entryIndex(session, entryID)
+		if i < 0 {
+			return ErrEntryNotFound
+		}
+		if update.Notes != nil {
+			session.Entries[i].Notes = *update.Notes
+		}
+		if update.Position != nil {
+			if *update.Position >= len(session.Entries) {
+				return ErrInvalidPosition
+			}
+			entry := session.Entries[i]
+			session.Entries = slices.Insert(slices.Delete(session.Entries, i, i+1), *update.Position, entry)
+		}
+		return nil
+	})
+}
+
+// DeleteSessionEntry removes an exercise and its sets from a session
+func (s *Service) DeleteSessionEntry(ctx context.Context, userID, sessionID, entryID bson.ObjectID) (*models.WorkoutSession, error) {
+	return s.editWorkoutSession(ctx, userID, sessionID, func(session *models.WorkoutSession) error {
+		i := entryIndex(session, entryID)
+		if i < 0 {
+			return ErrEntryNotFound
+		}
+		session.Entries = slices.Delete(session.Entries, i, i+1)
+		return nil
+	})
+}
+
+// AddWorkoutSet logs a set of an entry and flags the personal records it sets
+func (s *Service) AddWorkoutSet(ctx context.Context, userID, sessionID, entryID bson.ObjectID, update *models.WorkoutSetUpdate) (*models.WorkoutSession, error) {
+	if err := update.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSession, err)
+	}
+	var setID bson.ObjectID
+	session, err := s.editWorkoutSession(ctx, userID, sessionID, func(session *models.WorkoutSession) error {
+		entry := session.Entry(entryID)
+		if entry == nil {
+			return ErrEntryNotFound
+		}
+		var err error
+		setID, err = addSet(entry, update)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.flagNewRecords(ctx, session, []bson.ObjectID{setID})
+	return session, nil
+}
+
+// UpdateWorkoutSet corrects the values of a set and flags the personal
+// records it now sets
+func (s *Service) UpdateWorkoutSet(ctx context.Context, userID, sessionID, entryID, setID bson.ObjectID, update *models.WorkoutSetUpdate) (*models.WorkoutSession, error) {
+	if err := update.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSession, err)
+	}
+	session, err := s.editWorkoutSession(ctx, userID, sessionID, func(session *models.WorkoutSession) error {
+		entry := session.Entry(entryID)
+		if entry == nil {
+			return ErrEntryNotFound
+		}
+		set := entry.Set(setID)
+		if set == nil {
+			return ErrSetNotFound
+		}
+		update.Apply(set)
+		return checkSet(set)
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.flagNewRecords(ctx, session, []bson.ObjectID{setID})
+	return session, nil
+}
+
+// DeleteWorkoutSet removes a set from an entry
+func (s *Service) DeleteWorkoutSet(ctx context.Context, userID, sessionID, entryID, setID bson.ObjectID) (*models.WorkoutSession, error) {
+	return s.editWorkoutSession(ctx, userID, sessionID, func(session *models.WorkoutSession) error {
+		entry := session.Entry(entryID)
+		if entry == nil {
+			return ErrEntryNotFound
+		}
+		i := slices.IndexFunc(entry.Sets, func(set models.WorkoutSet) bool { return set.ID == setID })
+		if i < 0 {
+			return ErrSetNotFound
+		}
+		entry.Sets = slices.Delete(entry.Sets, i, i+1)
+		return nil
+	})
+}
+
+// editWorkoutSession loads one of the user's sessions, applies edit and saves
+// it. The save only applies to the session as it was loaded; when another
+// request changed it first, the edit is redone on the new version, so
+// concurrent edits such as logging two sets are all kept.
+func (s *Service) editWorkoutSession(ctx context.Context, userID, id bson.ObjectID, edit func(*models.WorkoutSession) error) (*models.WorkoutSession, error) {
+	for attempt := 1; ; attempt++ {
+		session, err := s.GetWorkoutSession(ctx, userID, id)
+		if err != nil {
+			return nil, err
+		}
+		if err := edit(session); err != nil {
+			return nil, err
+		}
+		now := time.Now()
+		if err := checkSession(session, now); err != nil {
+			return nil, err
+		}
+
+		// Stored times keep milliseconds, so each save must move updated_at
+		// by at least one for the next save to tell the versions apart
+		read := session.UpdatedAt
+		session.UpdatedAt = now.Truncate(time.Millisecond)
+		if !session.UpdatedAt.After(read) {
+			session.UpdatedAt = read.Truncate(time.Millisecond).Add(time.Millisecond)
+		}
+		err = s.repo.UpdateWorkoutSession(ctx, session, read)
+		if errors.Is(err, ErrConflict) && attempt < sessionEditAttempts {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		return session, nil
+	}
+}
+
+// addEntry appends or inserts an entry after checking its exercise is in the
+// catalog, and returns the IDs of its sets
+func (s *Service) addEntry(ctx context.Context, session *models.WorkoutSession, input *models.SessionEntryInput) ([]bson.ObjectID, error) {
+	if len(session.Entries) >= maxSessionEntries {
+		return nil, ErrSessionTooLarge
+	}
+	if _, err := s.repo.GetWorkoutByID(ctx, input.WorkoutID); err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil, ErrUnknownWorkout
+		}
+		return nil, err
+	}
+
+	entry := models.SessionEntry{
+		ID:        bson.NewObjectID(),
+		WorkoutID: input.WorkoutID,
+		Notes:     input.Notes,
+		Sets:      []models.WorkoutSet{},
+	}
+	var setIDs []bson.ObjectID
+	for i := range input.Sets {
+		setID, err := addSet(&entry, &input.Sets[i])
+		if err != nil {
+			return nil, err
+		}
+		setIDs = append(setIDs, setID)
+	}
+
+	position := len(session.Entries)
+	if input.Position != nil {
+		if *input.Position > position {
+			return nil, ErrInvalidPosition
+		}
+		position = *input.Position
+	}
+	session.Entries = slices.Insert(session.Entries, position, entry)
+	return setIDs, nil
+}
+
+// addSet appends a new set to an entry and returns its ID
+func addSet(entry *models.SessionEntry, update *models.WorkoutSetUpdate) (bson.ObjectID, error) {
+	if len(entry.Sets) >= maxEntrySets {
+		return bson.ObjectID{}, ErrSessionTooLarge
+	}
+	set := models.WorkoutSet{ID: bson.NewObjectID()}
+	update.Apply(&set)
+	if err := checkSet(&set); err != nil {
+		return bson.ObjectID{}, err
+	}
+	entry.Sets = append(entry.Sets, set)
+	return set.ID, nil
+}
+
+// checkSet rejects sets that record nothing done
+func checkSet(set *models.WorkoutSet) error {
+	if set.Reps == nil && set.Duration == nil && set.Distance == nil {
+		return ErrEmptySet
+	}
+	return nil
+}
+
+// checkSession validates the times of a session
+func checkSession(session *models.WorkoutSession, now time.Time) error {
+	if session.StartedAt.After(now.Add(sessionClockSkew)) {
+		return ErrFutureSession
+	}
+	if session.EndedAt != nil && session.EndedAt.Before(session.StartedAt) {
+		return ErrSessionEnd
+	}
+	return nil
+}
+
+// entryIndex returns the position of an entry in a session, or -1
+func entryIndex(session *models.WorkoutSession, entryID bson.ObjectID) int {
+	return slices.IndexFunc(session.Entries, func(e models.SessionEntry) bool { return e.ID == entryID })
+}
@@ -0,0 +1,73 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/AyushIIITU/virtualfit/internal/models"
+	"github.com/AyushIIITU/virtualfit/internal/targets"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// ErrAutoTargets is returned for a profile update that sets daily targets by
+// hand while the calculator manages them
+var ErrAutoTargets = errors.New("turn off auto_targets to set daily targets by hand")
+
+// Bounds of the daily targets a profile accepts
+const (
+	minDailyCalories = 1000
+	maxDailyCalories = 5000
+	minDailyProtein  = 30
+	maxDailyProtein  = 500
+)
+
+// targetsProfile is the calculator input for a user
+func targetsProfile(user *models.User, now time.Time) targets.Profile {
+	return targets.Profile{
+		Gender:         user.Gender,
+		Age:            user.Age(now),
+		HeightCM:       user.Height,
+		WeightKG:       user.Weight,
+		BodyFatPercent: user.BodyFatPercent,
+		FitnessLevel:   user.CurrentFitnessLevel,
+		DaysPerWeek:    user.DaysPerWeek,
+		Goals:          user.Goals,
+	}
+}
+
+// recommendTargets runs the calculator and keeps its targets within the
+// bounds a profile accepts, so extreme body metrics cannot store targets no
+// request could set
+func recommendTargets(user *models.User, formula string) (*targets.Recommendation, error) {
+	rec, err := targets.Recommend(targetsProfile(user, time.Now()), formula)
+	if err != nil {
+		return nil, err
+	}
+	bounded := rec.Within(minDailyCalories, maxDailyCalories, minDailyProtein, maxDailyProtein)
+	return &bounded, nil
+}
+
+// RecommendTargets computes daily calorie and macro targets for a user. An
+// empty formula picks the most accurate one the profile allows.
+func (s *Service) RecommendTargets(ctx context.Context, userID bson.ObjectID, formula string) (*targets.Recommendation, error) {
+	user, err := s.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return recommendTargets(user, formula)
+}
+
+// applyTargets sets the user's daily targets to the calculator's recommendation
+func applyTargets(user *models.User) error {
+	rec, err := recommendTargets(user, "")
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidProfile, err)
+	}
+	user.DailyCalorieIntake = rec.Calories
+	user.DailyProteinIntake = rec.ProteinG
+	user.DailyCarbIntake = rec.CarbsG
+	user.DailyFatIntake = rec.FatG
+	return nil
+}
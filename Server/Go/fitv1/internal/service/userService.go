@@ -3,18 +3,26 @@ package service
 import (
 	"context"
 	"errors"
+	"fmt"
 	"time"
 
 	"github.com/AyushIIITU/virtualfit/internal/models"
+	"go.mongodb.org/mongo-driver/v2/bson"
 	"golang.org/x/crypto/bcrypt"
 )
 
+var (
+	ErrInvalidCredentials = errors.New("invalid credentials")
+	ErrInvalidProfile     = errors.New("invalid profile")
+	// ErrPasswordResetRequired is returned on login after an admin forced a reset
+	ErrPasswordResetRequired = errors.New("password reset required; check your email for a reset link")
+)
+
 // User Service
 func (s *Service) RegisterUser(ctx context.Context, userReg *models.UserRegister) (*models.User, error) {
-	// Check if user already exists
-	existingUser, err := s.repo.GetUserByEmail(ctx, userReg.Email)
-	if err == nil && existingUser != nil {
-		return nil, errors.New("user already exists")
+	userReg.Email = models.NormalizeEmail(userReg.Email)
+	if err := userReg.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidProfile, err)
 	}
 
 	// Hash password
@@ -33,15 +41,21 @@ func (s *Service) RegisterUser(ctx context.Context, userReg *models.UserRegister
 		Name:                   userReg.Name,
 		Email:                  userReg.Email,
 		PasswordHash:           string(hashedPassword),
+		Role:                   models.RoleUser,
 		DOB:                    userReg.DateOfBirth,
 		Gender:                 userReg.Gender,
 		Height:                 userReg.Height,
 		Weight:                 userReg.Weight,
+		BodyFatPercent:         userReg.BodyFatPercent,
+		Units:                  userReg.Units,
 		Region:                 userReg.Region,
+		Timezone:               userReg.Timezone,
+		Locale:                 userReg.Locale,
 		Goals:                  userReg.Goals,
 		DietaryRestrictions:    userReg.DietaryRestrictions,
 		DailyCalorieIntake:     userReg.DailyCalorieIntake,
 		DailyProteinIntake:     userReg.DailyProteinIntake,
+		AutoTargets:            userReg.AutoTargets,
 		Exercises:              userReg.Exercises,
 		PreferredMealFrequency: userReg.PreferredMealFrequency,
 		FoodsToAvoid:           userReg.FoodsToAvoid,
@@ -50,30 +64,110 @@ func (s *Service) RegisterUser(ctx context.Context, userReg *models.UserRegister
 		MedicalConditions:      userReg.MedicalConditions,
 		FoodAllergies:          userReg.FoodAllergies,
 		InterestedActivities:   userReg.InterestedActivities,
+		Equipment:              userReg.Equipment,
 		DaysPerWeek:            userReg.DaysPerWeek,
 
 		CreatedAt: time.Now(),
 		UpdatedAt: time.Now(),
 	}
 
-	return s.repo.CreateUser(ctx, user)
+	if user.AutoTargets {
+		if err := applyTargets(user); err != nil {
+			return nil, err
+		}
+	}
+
+	user, err = s.repo.CreateUser(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+	s.recordProfileMeasurement(ctx, nil, user)
+
+	// The account works without the email; the user can ask for a new link
+	if err := s.sendVerificationEmail(ctx, user); err != nil {
+		log.Printf("Error sending verification email to user %s: %v", user.ID.Hex(), err)
+	}
+	return user, nil
 }
 
-func (s *Service) LoginUser(ctx context.Context, login *models.UserLogin) (*models.User, error) {
+// LoginUser checks a password login from the client at ip. Failed attempts
+// are counted per account and per IP, and throttled or locked out.
+func (s *Service) LoginUser(ctx context.Context, login *models.UserLogin, ip string) (*models.User, error) {
+	login.Email = models.NormalizeEmail(login.Email)
+	if err := s.checkLoginThrottle(ctx, login.Email, ip); err != nil {
+		return nil, err
+	}
+
 	user, err := s.repo.GetUserByEmail(ctx, login.Email)
+	if errors.Is(err, ErrNotFound) {
+		if err := s.recordLoginFailure(ctx, login.Email, ip, nil); err != nil {
+			return nil, err
+		}
+		return nil, ErrInvalidCredentials
+	}
 	if err != nil {
-		return nil, errors.New("invalid credentials")
+		return nil, err
 	}
 
 	err = bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(login.Password))
 	if err != nil {
-		return nil, errors.New("invalid credentials")
+		if err := s.recordLoginFailure(ctx, login.Email, ip, user); err != nil {
+			return nil, err
+		}
+		return nil, ErrInvalidCredentials
+	}
+
+	// Only reveal the account state to someone who knows the password
+	if user.Disabled {
+		return nil, ErrAccountDisabled
+	}
+	if user.PasswordResetRequired {
+		return nil, ErrPasswordResetRequired
 	}
 
+	// With two-factor authentication the failures are cleared once the code is
+	// verified, so guessing codes cannot hide behind repeated password logins
+	if !user.MFAEnabled {
+		if err := s.clearAccountLockout(ctx, user.Email); err != nil {
+			return nil, err
+		}
+	}
 	return user, nil
 }
 
-// User Profile Update Service
-func (s *Service) UpdateUserProfile(ctx context.Context, user *models.User) error {
-	return s.repo.UpdateUser(ctx, user)
+// Preferences returns the units, timezone and week start a user prefers
+func (s *Service) Preferences(ctx context.Context, userID bson.ObjectID) (models.Preferences, error) {
+	user, err := s.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		return models.Preferences{}, err
+	}
+	return user.Preferences(), nil
+}
+
+// UpdateUserProfile merges a profile patch into the user and returns the
+// updated user. Credentials, email and account state have their own flows.
+func (s *Service) UpdateUserProfile(ctx context.Context, userID bson.ObjectID, patch *models.ProfileUpdate) (*models.User, error) {
+	if err := patch.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidProfile, err)
+	}
+
+	user, err := s.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	before := *user
+	patch.Apply(user)
+	if user.AutoTargets {
+		if patch.SetsTargets() {
+			return nil, ErrAutoTargets
+		}
+		if err := applyTargets(user); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.repo.UpdateUser(ctx, user); err != nil {
+		return nil, err
+	}
+	s.recordProfileMeasurement(ctx, &before, user)
+	return user, nil
 }
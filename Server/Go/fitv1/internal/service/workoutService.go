@@ -2,7 +2,10 @@ package service
 
 import (
 	"context"
+	"errors"
 	"path/filepath"
+	"regexp"
+	"strings"
 
 	"github.com/AyushIIITU/virtualfit/internal/models"
 	"go.mongodb.org/mongo-driver/v2/bson"
@@ -30,3 +33,74 @@ func (s *Service) GetWorkoutImagePath(workoutId, imageName string) string {
 	baseImagesPath := "../uploads/workout_images"
 	return filepath.Join(baseImagesPath, workoutId, imageName)
 }
+
+// ErrInvalidWorkoutSlug is returned for workout IDs that are unsafe as an image directory name
+var ErrInvalidWorkoutSlug = errors.New("workout id may only contain letters, digits, '-' and '_'")
+
+// workoutSlug matches the string IDs of the catalog, e.g. "Barbell_Squat"
+var workoutSlug = regexp.MustCompile(`^[A-Za-z0-9_-]{1,100}$`)
+
+// workoutFromInput builds a catalog entry. The slug also names the directory
+// the workout's images are served from.
+func workoutFromInput(input *models.WorkoutInput) (*models.Workout, error) {
+	slug := input.Slug
+	if slug == "" {
+		slug = strings.Join(strings.Fields(input.Name), "_")
+	}
+	if !workoutSlug.MatchString(slug) {
+		return nil, ErrInvalidWorkoutSlug
+	}
+	return &models.Workout{
+		ID_Default:       slug,
+		Name:             input.Name,
+		Force:            input.Force,
+		Level:            input.Level,
+		Mechanic:         input.Mechanic,
+		Equipment:        input.Equipment,
+		PrimaryMuscles:   input.PrimaryMuscles,
+		SecondaryMuscles: input.SecondaryMuscles,
+		Instructions:     input.Instructions,
+		Category:         input.Category,
+		Images:           input.Images,
+	}, nil
+}
+
+// CreateWorkout adds a workout to the catalog
+func (s *Service) CreateWorkout(ctx context.Context, actor models.AuditActor, input *models.WorkoutInput) (*models.Workout, error) {
+	workout, err := workoutFromInput(input)
+	if err != nil {
+		return nil, err
+	}
+	workout, err = s.repo.CreateWorkout(ctx, workout)
+	if err != nil {
+		return nil, err
+	}
+
+	s.audit(ctx, actor, models.AuditWorkoutCreate, "workout", workout.ID.Hex(), bson.M{"name": workout.Name})
+	return workout, nil
+}
+
+// UpdateWorkout replaces a catalog workout
+func (s *Service) UpdateWorkout(ctx context.Context, actor models.AuditActor, id bson.ObjectID, input *models.WorkoutInput) (*models.Workout, error) {
+	workout, err := workoutFromInput(input)
+	if err != nil {
+		return nil, err
+	}
+	workout.ID = id
+	if err := s.repo.UpdateWorkout(ctx, workout); err != nil {
+		return nil, err
+	}
+
+	s.audit(ctx, actor, models.AuditWorkoutUpdate, "workout", id.Hex(), bson.M{"name": workout.Name})
+	return workout, nil
+}
+
+// DeleteWorkout removes a workout from the catalog
+func (s *Service) DeleteWorkout(ctx context.Context, actor models.AuditActor, id bson.ObjectID) error {
+	if err := s.repo.DeleteWorkout(ctx, id); err != nil {
+		return err
+	}
+
+	s.audit(ctx, actor, models.AuditWorkoutDelete, "workout", id.Hex(), nil)
+	return nil
+}
@@ -0,0 +1,241 @@
+// Package targets estimates energy expenditure and recommends daily calorie
+// and macronutrient targets from a user's body metrics and goals.
+package targets
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/AyushIIITU/virtualfit/internal/goals"
+)
+
+// BMR formulas
+const (
+	MifflinStJeor  = "mifflin-st-jeor"
+	HarrisBenedict = "harris-benedict" // revised by Roza and Shizgal, 1984
+	KatchMcArdle   = "katch-mcardle"   // needs body fat
+)
+
+// Goals a recommendation is tuned for
+const (
+	GoalLose          = "lose"
+	GoalMaintain      = "maintain"
+	GoalGain          = "gain"
+	GoalRecomposition = "recomposition" // lose fat while building muscle
+)
+
+// Energy per gram of each macronutrient, in kcal
+const (
+	kcalPerGramProtein = 4
+	kcalPerGramCarbs   = 4
+	kcalPerGramFat     = 9
+)
+
+var (
+	ErrUnknownFormula  = errors.New("unknown BMR formula")
+	ErrBodyFatRequired = errors.New("katch-mcardle needs body_fat_percent in the profile")
+	ErrIncomplete      = errors.New("profile needs date of birth, height and weight")
+)
+
+// Profile is the input to the calculator. Height is in cm and weight in kg.
+type Profile struct {
+	Gender         string // Male, Female or Other
+	Age            int
+	HeightCM       float64
+	WeightKG       float64
+	BodyFatPercent float64 // 0 when unknown
+	FitnessLevel   string  // Beginner, Intermediate or Advanced
+	DaysPerWeek    int     // training days
+	Goals          []string
+}
+
+// Recommendation is a set of daily targets and how they were derived
+type Recommendation struct {
+	Formula        string  `json:"formula"`
+	BMR            float64 `json:"bmr"`
+	ActivityFactor float64 `json:"activity_factor"`
+	TDEE           float64 `json:"tdee"`
+	Goal           string  `json:"goal"`
+	Calories       int     `json:"calories"`
+	ProteinG       int     `json:"protein_g"`
+	CarbsG         int     `json:"carbs_g"`
+	FatG           int     `json:"fat_g"`
+}
+
+// goalPlan is the calorie adjustment and protein intake for a goal
+type goalPlan struct {
+	calorieFactor float64 // multiplier applied to TDEE
+	proteinPerKG  float64 // grams per kg of body weight
+}
+
+var goalPlans = map[string]goalPlan{
+	GoalLose:          {calorieFactor: 0.80, proteinPerKG: 2.0},
+	GoalMaintain:      {calorieFactor: 1.00, proteinPerKG: 1.6},
+	GoalGain:          {calorieFactor: 1.10, proteinPerKG: 1.8},
+	GoalRecomposition: {calorieFactor: 0.90, proteinPerKG: 2.2},
+}
+
+const (
+	fatShare        = 0.25 // of calories
+	minFatPerKG     = 0.6  // grams per kg of body weight
+	calorieRounding = 10
+)
+
+// DefaultFormula picks Katch-McArdle when body fat is known, since lean mass
+// predicts BMR best, and Mifflin-St Jeor otherwise
+func DefaultFormula(p Profile) string {
+	if p.BodyFatPercent > 0 {
+		return KatchMcArdle
+	}
+	return MifflinStJeor
+}
+
+// BMR returns the basal metabolic rate in kcal/day. For genders other than
+// Male and Female the sex-specific formulas use the average of both.
+func BMR(formula string, p Profile) (float64, error) {
+	age := float64(p.Age)
+	complete := p.Age > 0 && p.HeightCM > 0 && p.WeightKG > 0
+
+	switch formula {
+	case MifflinStJeor:
+		if !complete {
+			return 0, ErrIncomplete
+		}
+		base := 10*p.WeightKG + 6.25*p.HeightCM - 5*age
+		return bySex(p.Gender, base+5, base-161), nil
+	case HarrisBenedict:
+		if !complete {
+			return 0, ErrIncomplete
+		}
+		male := 88.362 + 13.397*p.WeightKG + 4.799*p.HeightCM - 5.677*age
+		female := 447.593 + 9.247*p.WeightKG + 3.098*p.HeightCM - 4.330*age
+		return bySex(p.Gender, male, female), nil
+	case KatchMcArdle:
+		if p.BodyFatPercent <= 0 {
+			return 0, ErrBodyFatRequired
+		}
+		if p.WeightKG <= 0 {
+			return 0, ErrIncomplete
+		}
+		leanMass := p.WeightKG * (1 - p.BodyFatPercent/100)
+		return 370 + 21.6*leanMass, nil
+	default:
+		return 0, fmt.Errorf("%w %q", ErrUnknownFormula, formula)
+	}
+}
+
+func bySex(gender string, male, female float64) float64 {
+	switch gender {
+	case "Male":
+		return male
+	case "Female":
+		return female
+	}
+	return (male + female) / 2
+}
+
+// ActivityFactor maps training days per week to the usual TDEE multipliers
+// (1.2 sedentary to 1.9 very active), nudged by fitness level since
+// experienced lifters train harder on the same number of days
+func ActivityFactor(daysPerWeek int, fitnessLevel string) float64 {
+	var factor float64
+	switch {
+	case daysPerWeek <= 0:
+		factor = 1.2
+	case daysPerWeek <= 2:
+		factor = 1.375
+	case daysPerWeek <= 4:
+		factor = 1.55
+	case daysPerWeek <= 6:
+		factor = 1.725
+	default:
+		factor = 1.9
+	}
+
+	switch fitnessLevel {
+	case "Beginner":
+		factor -= 0.05
+	case "Advanced":
+		factor += 0.05
+	}
+	factor = math.Max(1.2, math.Min(1.9, factor))
+	return math.Round(factor*1000) / 1000
+}
+
+// GoalOf reads free-text goals such as "lose weight" or "build muscle".
+// Wanting both fat loss and muscle gain, in one goal or across several, means
+// recomposition. Strength counts as a gain.
+func GoalOf(list []string) string {
+	intent := goals.Parse(list)
+	gain := intent.Gain || intent.Strength
+	switch {
+	case intent.Lose && gain:
+		return GoalRecomposition
+	case intent.Lose:
+		return GoalLose
+	case gain:
+		return GoalGain
+	}
+	return GoalMaintain
+}
+
+// Recommend computes daily targets with the given formula, or the default
+// formula when it is empty. Calories never go below BMR. Protein scales with
+// body weight, fat takes a quarter of the calories and carbs the rest.
+func Recommend(p Profile, formula string) (*Recommendation, error) {
+	if formula == "" {
+		formula = DefaultFormula(p)
+	}
+	bmr, err := BMR(formula, p)
+	if err != nil {
+		return nil, err
+	}
+
+	factor := ActivityFactor(p.DaysPerWeek, p.FitnessLevel)
+	tdee := bmr * factor
+	goal := GoalOf(p.Goals)
+	plan := goalPlans[goal]
+
+	calories := math.Max(bmr, tdee*plan.calorieFactor)
+	calories = math.Round(calories/calorieRounding) * calorieRounding
+	protein := math.Round(plan.proteinPerKG * p.WeightKG)
+	fat := math.Round(math.Max(calories*fatShare/kcalPerGramFat, minFatPerKG*p.WeightKG))
+	carbs := math.Max(0, math.Round((calories-protein*kcalPerGramProtein-fat*kcalPerGramFat)/kcalPerGramCarbs))
+
+	return &Recommendation{
+		Formula:        formula,
+		BMR:            math.Round(bmr),
+		ActivityFactor: factor,
+		TDEE:           math.Round(tdee),
+		Goal:           goal,
+		Calories:       int(calories),
+		ProteinG:       int(protein),
+		CarbsG:         int(carbs),
+		FatG:           int(fat),
+	}, nil
+}
+
+// Within keeps calories and protein between the given bounds. When that
+// changes the calories, fat and carbs share what protein leaves in the same
+// ratio as before.
+func (r Recommendation) Within(minCalories, maxCalories, minProtein, maxProtein int) Recommendation {
+	calories := min(max(r.Calories, minCalories), maxCalories)
+	protein := min(max(r.ProteinG, minProtein), maxProtein)
+	if calories == r.Calories && protein == r.ProteinG {
+		return r
+	}
+
+	fatKcal := float64(r.FatG * kcalPerGramFat)
+	carbKcal := float64(r.CarbsG * kcalPerGramCarbs)
+	rest := math.Max(0, float64(calories-protein*kcalPerGramProtein))
+	share := fatShare
+	if fatKcal+carbKcal > 0 {
+		share = fatKcal / (fatKcal + carbKcal)
+	}
+	r.Calories = calories
+	r.ProteinG = protein
+	r.FatG = int(math.Round(rest * share / kcalPerGramFat))
+	r.CarbsG = int(math.Round(rest * (1 - share) / kcalPerGramCarbs))
+	return r
+}
@@ -0,0 +1,209 @@
+package targets
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+// athlete is 30 years old, 180 cm and 80 kg
+var athlete = Profile{Gender: "Male", Age: 30, HeightCM: 180, WeightKG: 80, FitnessLevel: "Intermediate", DaysPerWeek: 4}
+
+func TestBMR(t *testing.T) {
+	female, other, lean := athlete, athlete, athlete
+	female.Gender = "Female"
+	other.Gender = "Other"
+	lean.BodyFatPercent = 20
+
+	tests := []struct {
+		name    string
+		formula string
+		profile Profile
+		want    float64
+	}{
+		{"mifflin male", MifflinStJeor, athlete, 1780},
+		{"mifflin female", MifflinStJeor, female, 1614},
+		{"mifflin other averages both", MifflinStJeor, other, 1697},
+		{"harris-benedict male", HarrisBenedict, athlete, 1853.632},
+		{"katch-mcardle", KatchMcArdle, lean, 1752.4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := BMR(tt.formula, tt.profile)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if math.Abs(got-tt.want) > 1e-6 {
+				t.Errorf("BMR = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBMRErrors(t *testing.T) {
+	noHeight := athlete
+	noHeight.HeightCM = 0
+	tests := []struct {
+		name    string
+		formula string
+		profile Profile
+		want    error
+	}{
+		{"unknown formula", "guess", athlete, ErrUnknownFormula},
+		{"incomplete mifflin", MifflinStJeor, noHeight, ErrIncomplete},
+		{"incomplete harris-benedict", HarrisBenedict, Profile{WeightKG: 80}, ErrIncomplete},
+		{"katch-mcardle without body fat", KatchMcArdle, athlete, ErrBodyFatRequired},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := BMR(tt.formula, tt.profile); !errors.Is(err, tt.want) {
+				t.Errorf("err = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestDefaultFormula(t *testing.T) {
+	if got := DefaultFormula(athlete); got != MifflinStJeor {
+		t.Errorf("without body fat: %s", got)
+	}
+	lean := athlete
+	lean.BodyFatPercent = 15
+	if got := DefaultFormula(lean); got != KatchMcArdle {
+		t.Errorf("with body fat: %s", got)
+	}
+}
+
+func TestActivityFactor(t *testing.T) {
+	tests := []struct {
+		days  int
+		level string
+		want  float64
+	}{
+		{0, "Intermediate", 1.2},
+		{0, "Beginner", 1.2}, // never below sedentary
+		{2, "Intermediate", 1.375},
+		{3, "Beginner", 1.5},
+		{4, "Advanced", 1.6},
+		{6, "Intermediate", 1.725},
+		{7, "Intermediate", 1.9},
+		{7, "Advanced", 1.9}, // never above very active
+	}
+	for _, tt := range tests {
+		if got := ActivityFactor(tt.days, tt.level); got != tt.want {
+			t.Errorf("ActivityFactor(%d, %s) = %v, want %v", tt.days, tt.level, got, tt.want)
+		}
+	}
+}
+
+func TestGoalOf(t *testing.T) {
+	tests := []struct {
+		goals []string
+		want  string
+	}{
+		{nil, GoalMaintain},
+		{[]string{"stay healthy"}, GoalMaintain},
+		{[]string{"Lose weight"}, GoalLose},
+		{[]string{"cut"}, GoalLose},
+		{[]string{"Build Muscle"}, GoalGain},
+		{[]string{"bulk"}, GoalGain},
+		{[]string{"lose fat", "build muscle"}, GoalRecomposition},
+		{[]string{"lose fat and build muscle"}, GoalRecomposition},
+		{[]string{"lean muscle"}, GoalRecomposition},
+		{[]string{"body recomposition"}, GoalRecomposition},
+	}
+	for _, tt := range tests {
+		if got := GoalOf(tt.goals); got != tt.want {
+			t.Errorf("GoalOf(%q) = %s, want %s", tt.goals, got, tt.want)
+		}
+	}
+}
+
+// energy is the calories of a recommendation's macros
+func energy(r Recommendation) int {
+	return r.ProteinG*kcalPerGramProtein + r.CarbsG*kcalPerGramCarbs + r.FatG*kcalPerGramFat
+}
+
+func TestRecommend(t *testing.T) {
+	calories := make(map[string]int)
+	for _, goal := range []string{"maintain", "lose weight", "build muscle", "recomposition"} {
+		t.Run(goal, func(t *testing.T) {
+			p := athlete
+			p.Goals = []string{goal}
+			rec, err := Recommend(p, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			calories[rec.Goal] = rec.Calories
+
+			if rec.Formula != MifflinStJeor || rec.BMR != 1780 || rec.ActivityFactor != 1.55 {
+				t.Errorf("formula %s, BMR %v, factor %v", rec.Formula, rec.BMR, rec.ActivityFactor)
+			}
+			if rec.Calories%calorieRounding != 0 {
+				t.Errorf("calories %d are not rounded to %d", rec.Calories, calorieRounding)
+			}
+			if float64(rec.Calories) < rec.BMR-calorieRounding/2 {
+				t.Errorf("calories %d below BMR %v", rec.Calories, rec.BMR)
+			}
+			if want := int(math.Round(goalPlans[rec.Goal].proteinPerKG * p.WeightKG)); rec.ProteinG != want {
+				t.Errorf("protein = %d g, want %d", rec.ProteinG, want)
+			}
+			if float64(rec.FatG) < minFatPerKG*p.WeightKG {
+				t.Errorf("fat %d g is below the minimum", rec.FatG)
+			}
+			// Rounding each macro to a gram is off by a few kcal at most
+			if diff := energy(*rec) - rec.Calories; diff < -10 || diff > 10 {
+				t.Errorf("macros add up to %d kcal, want %d", energy(*rec), rec.Calories)
+			}
+		})
+	}
+	if !(calories[GoalLose] < calories[GoalRecomposition] && calories[GoalRecomposition] < calories[GoalMaintain] && calories[GoalMaintain] < calories[GoalGain]) {
+		t.Errorf("calories by goal %v should rise from lose to gain", calories)
+	}
+}
+
+func TestRecommendFloorsAtBMR(t *testing.T) {
+	// A large deficit on a sedentary profile would undercut BMR
+	p := Profile{Gender: "Female", Age: 60, HeightCM: 150, WeightKG: 45, DaysPerWeek: 0, FitnessLevel: "Beginner", Goals: []string{"lose weight"}}
+	rec, err := Recommend(p, MifflinStJeor)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if float64(rec.Calories) < rec.BMR-calorieRounding/2 {
+		t.Errorf("calories %d below BMR %v", rec.Calories, rec.BMR)
+	}
+}
+
+func TestWithin(t *testing.T) {
+	rec := Recommendation{Calories: 2500, ProteinG: 160, CarbsG: 290, FatG: 69}
+	if got := rec.Within(1000, 5000, 30, 500); got != rec {
+		t.Errorf("targets within bounds changed to %+v", got)
+	}
+
+	tests := []struct {
+		name              string
+		rec               Recommendation
+		calories, protein int
+	}{
+		{"too many calories", Recommendation{Calories: 6200, ProteinG: 400, CarbsG: 800, FatG: 172}, 5000, 400},
+		{"too few calories", Recommendation{Calories: 900, ProteinG: 60, CarbsG: 100, FatG: 25}, 1000, 60},
+		{"too much protein", Recommendation{Calories: 4800, ProteinG: 560, CarbsG: 395, FatG: 133}, 4800, 500},
+		{"too little protein", Recommendation{Calories: 1200, ProteinG: 20, CarbsG: 170, FatG: 35}, 1200, 30},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.rec.Within(1000, 5000, 30, 500)
+			if got.Calories != tt.calories || got.ProteinG != tt.protein {
+				t.Errorf("calories %d and protein %d, want %d and %d", got.Calories, got.ProteinG, tt.calories, tt.protein)
+			}
+			if diff := energy(got) - got.Calories; diff < -10 || diff > 10 {
+				t.Errorf("macros add up to %d kcal, want %d", energy(got), got.Calories)
+			}
+			before := float64(tt.rec.FatG*kcalPerGramFat) / float64(energy(tt.rec)-tt.rec.ProteinG*kcalPerGramProtein)
+			after := float64(got.FatG*kcalPerGramFat) / float64(energy(got)-got.ProteinG*kcalPerGramProtein)
+			if math.Abs(before-after) > 0.01 {
+				t.Errorf("fat share of the rest went from %.3f to %.3f", before, after)
+			}
+		})
+	}
+}
@@ -0,0 +1,150 @@
+package timeutil
+
+import (
+	"strings"
+	"time"
+
+	"golang.org/x/text/language"
+)
+
+// regionZones maps ISO 3166 country codes to the zone most of the country
+// lives in, for users who have not picked a timezone
+var regionZones = map[string]string{
+	"AE": "Asia/Dubai",
+	"AR": "America/Argentina/Buenos_Aires",
+	"AU": "Australia/Sydney",
+	"BD": "Asia/Dhaka",
+	"BR": "America/Sao_Paulo",
+	"CA": "America/Toronto",
+	"CN": "Asia/Shanghai",
+	"DE": "Europe/Berlin",
+	"EG": "Africa/Cairo",
+	"ES": "Europe/Madrid",
+	"FR": "Europe/Paris",
+	"GB": "Europe/London",
+	"ID": "Asia/Jakarta",
+	"IE": "Europe/Dublin",
+	"IN": "Asia/Kolkata",
+	"IT": "Europe/Rome",
+	"JP": "Asia/Tokyo",
+	"KE": "Africa/Nairobi",
+	"KR": "Asia/Seoul",
+	"LK": "Asia/Colombo",
+	"MX": "America/Mexico_City",
+	"MY": "Asia/Kuala_Lumpur",
+	"NG": "Africa/Lagos",
+	"NL": "Europe/Amsterdam",
+	"NP": "Asia/Kathmandu",
+	"NZ": "Pacific/Auckland",
+	"PH": "Asia/Manila",
+	"PK": "Asia/Karachi",
+	"RU": "Europe/Moscow",
+	"SA": "Asia/Riyadh",
+	"SG": "Asia/Singapore",
+	"TH": "Asia/Bangkok",
+	"US": "America/New_York",
+	"ZA": "Africa/Johannesburg",
+}
+
+// regionNames maps common spellings of country names to their codes
+var regionNames = map[string]string{
+	"india":                "IN",
+	"united states":        "US",
+	"usa":                  "US",
+	"united kingdom":       "GB",
+	"uk":                   "GB",
+	"england":              "GB",
+	"canada":               "CA",
+	"australia":            "AU",
+	"germany":              "DE",
+	"france":               "FR",
+	"japan":                "JP",
+	"china":                "CN",
+	"singapore":            "SG",
+	"uae":                  "AE",
+	"united arab emirates": "AE",
+	"pakistan":             "PK",
+	"bangladesh":           "BD",
+	"nepal":                "NP",
+	"sri lanka":            "LK",
+	"brazil":               "BR",
+	"mexico":               "MX",
+	"south africa":         "ZA",
+	"nigeria":              "NG",
+	"new zealand":          "NZ",
+}
+
+// Regions whose calendars start the week on Sunday or Saturday, after CLDR.
+// Everywhere else weeks start on Monday.
+var (
+	sundayFirst = []string{
+		"AG", "AS", "BD", "BR", "BS", "BT", "BW", "BZ", "CA", "CO", "DM", "DO", "ET",
+		"GT", "GU", "HK", "HN", "ID", "IL", "IN", "JM", "JP", "KE", "KH", "KR", "LA",
+		"MH", "MM", "MO", "MT", "MX", "MZ", "NI", "NP", "PA", "PE", "PH", "PK", "PR",
+		"PT", "PY", "SA", "SG", "SV", "TH", "TT", "TW", "UM", "US", "VE", "VI", "WS",
+		"YE", "ZA", "ZW",
+	}
+	saturdayFirst = []string{
+		"AE", "AF", "BH", "DJ", "DZ", "EG", "IQ", "IR", "JO", "KW", "LY", "OM", "QA",
+		"SD", "SY",
+	}
+)
+
+// RegionCode returns the ISO 3166 code for a free-form region such as "IN",
+// "IND" or "India", or "" when it is not recognised
+func RegionCode(region string) string {
+	region = strings.TrimSpace(region)
+	if code, ok := regionNames[strings.ToLower(region)]; ok {
+		return code
+	}
+	r, err := language.ParseRegion(region)
+	if err != nil || !r.IsCountry() {
+		return ""
+	}
+	return r.String()
+}
+
+// LocaleRegion returns the country of a BCP 47 locale such as "en-IN", or ""
+// when the locale names none and none can be guessed from its language
+func LocaleRegion(locale string) string {
+	if locale == "" {
+		return ""
+	}
+	tag, err := language.Parse(locale)
+	if err != nil {
+		return ""
+	}
+	r, confidence := tag.Region()
+	if confidence == language.No || !r.IsCountry() {
+		return ""
+	}
+	return r.String()
+}
+
+// RegionLocation returns the usual zone of a region, and false for unknown regions
+func RegionLocation(region string) (*time.Location, bool) {
+	name, ok := regionZones[RegionCode(region)]
+	if !ok {
+		return nil, false
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, false
+	}
+	return loc, true
+}
+
+// FirstWeekday returns the day weeks start on in a country
+func FirstWeekday(code string) time.Weekday {
+	for _, c := range sundayFirst {
+		if c == code {
+			return time.Sunday
+		}
+	}
+	for _, c := range saturdayFirst {
+		if c == code {
+			return time.Saturday
+		}
+	}
+	return time.Monday
+}
@@ -0,0 +1,47 @@
+package timeutil
+
+import "time"
+
+// Granularities for grouping records by calendar period
+const (
+	Day  = "day"
+	Week = "week"
+)
+
+// DateLayout is the format of date-only query parameters
+const DateLayout = "2006-01-02"
+
+// StartOfDay returns local midnight of t's day in loc
+func StartOfDay(t time.Time, loc *time.Location) time.Time {
+	y, m, d := t.In(loc).Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, loc)
+}
+
+// StartOfWeek returns local midnight of the first day of t's week in loc,
+// for weeks starting on first
+func StartOfWeek(t time.Time, loc *time.Location, first time.Weekday) time.Time {
+	day := StartOfDay(t, loc)
+	offset := (int(day.Weekday()) - int(first) + 7) % 7 // days since the week started
+	return day.AddDate(0, 0, -offset)
+}
+
+// Truncate returns the start of the day or week containing t
+func Truncate(t time.Time, granularity string, loc *time.Location, first time.Weekday) time.Time {
+	if granularity == Week {
+		return StartOfWeek(t, loc, first)
+	}
+	return StartOfDay(t, loc)
+}
+
+// Next returns the start of the period following the one starting at start
+func Next(start time.Time, granularity string) time.Time {
+	if granularity == Week {
+		return start.AddDate(0, 0, 7)
+	}
+	return start.AddDate(0, 0, 1)
+}
+
+// ValidGranularity reports whether g is a supported granularity
+func ValidGranularity(g string) bool {
+	return g == Day || g == Week
+}
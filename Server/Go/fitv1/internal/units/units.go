@@ -0,0 +1,231 @@
+// Package units converts body, training and nutrition quantities between the
+// metric units they are stored in (kg, cm, m, g, kcal) and the units clients use.
+//
+// Struct fields holding a quantity are tagged with its kind, for example
+// `unit:"body_weight"`, and converted in place by ToCanonical and FromCanonical.
+package units
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+)
+
+// Unit systems a user can prefer
+const (
+	Metric   = "metric"
+	Imperial = "imperial"
+)
+
+// Units
+const (
+	Kilogram    = "kg"
+	Gram        = "g"
+	Milligram   = "mg"
+	Pound       = "lb"
+	Ounce       = "oz"
+	Centimeter  = "cm"
+	Inch        = "in"
+	Meter       = "m"
+	Kilometer   = "km"
+	Mile        = "mi"
+	Kilocalorie = "kcal"
+	Kilojoule   = "kJ"
+	Percent     = "%"
+)
+
+// Quantities, as used in `unit` struct tags
+const (
+	BodyWeight = "body_weight" // kg, or lb in imperial
+	Load       = "load"        // weight lifted: kg, or lb in imperial
+	Length     = "length"      // cm, or in in imperial
+	Distance   = "distance"    // m, or mi in imperial
+	Ratio      = "ratio"       // % in both systems
+)
+
+var (
+	ErrUnknownSystem     = errors.New("units must be metric or imperial")
+	ErrUnknownUnit       = errors.New("unknown unit")
+	ErrIncompatibleUnits = errors.New("incompatible units")
+)
+
+type dimension int
+
+const (
+	mass dimension = iota
+	length
+	energy
+	ratio
+)
+
+// scale gives each unit's dimension and its size in the dimension's base
+// unit: g, cm, kcal or %
+var scale = map[string]struct {
+	dim  dimension
+	size float64
+}{
+	Kilogram:    {mass, 1000},
+	Gram:        {mass, 1},
+	Milligram:   {mass, 0.001},
+	Pound:       {mass, 453.59237},
+	Ounce:       {mass, 28.349523125},
+	Centimeter:  {length, 1},
+	Inch:        {length, 2.54},
+	Meter:       {length, 100},
+	Kilometer:   {length, 100000},
+	Mile:        {length, 160934.4},
+	Kilocalorie: {energy, 1},
+	Kilojoule:   {energy, 1 / 4.184},
+	Percent:     {ratio, 1},
+}
+
+// quantityUnits gives the unit of each quantity in each system
+var quantityUnits = map[string]map[string]string{
+	BodyWeight: {Metric: Kilogram, Imperial: Pound},
+	Load:       {Metric: Kilogram, Imperial: Pound},
+	Length:     {Metric: Centimeter, Imperial: Inch},
+	Distance:   {Metric: Meter, Imperial: Mile},
+	Ratio:      {Metric: Percent, Imperial: Percent},
+}
+
+// ValidSystem reports whether system is a supported unit system
+func ValidSystem(system string) bool {
+	return system == Metric || system == Imperial
+}
+
+// UnitOf returns the unit a quantity is expressed in under a system. Metric
+// units are the stored ones.
+func UnitOf(quantity, system string) string {
+	units, ok := quantityUnits[quantity]
+	if !ok {
+		panic(fmt.Sprintf("units: unknown quantity %q", quantity))
+	}
+	if system == Imperial {
+		return units[Imperial]
+	}
+	return units[Metric]
+}
+
+// Convert expresses a value in another unit of the same dimension
+func Convert(value float64, from, to string) (float64, error) {
+	f, ok := scale[from]
+	if !ok {
+		return 0, fmt.Errorf("%w %q", ErrUnknownUnit, from)
+	}
+	t, ok := scale[to]
+	if !ok {
+		return 0, fmt.Errorf("%w %q", ErrUnknownUnit, to)
+	}
+	if f.dim != t.dim {
+		return 0, fmt.Errorf("%w: %s and %s", ErrIncompatibleUnits, from, to)
+	}
+	if from == to {
+		return value, nil
+	}
+	// Drop the float noise of the conversion, far below any measured precision
+	return math.Round(value*f.size/t.size*1e9) / 1e9, nil
+}
+
+// SameDimension reports whether two known units measure the same thing
+func SameDimension(a, b string) bool {
+	sa, okA := scale[a]
+	sb, okB := scale[b]
+	return okA && okB && sa.dim == sb.dim
+}
+
+// displayDecimals is how precisely values are shown in units coarse enough
+// that two decimals would lose what was stored: 0.01 mi is 16 m
+var displayDecimals = map[string]int{
+	Inch: 3,
+	Mile: 5,
+}
+
+// Round trims float noise from a value for display, to two decimals
+func Round(v float64) float64 {
+	return math.Round(v*100) / 100
+}
+
+// RoundIn trims float noise from a value in unit for display, to the
+// precision the unit is shown with
+func RoundIn(v float64, unit string) float64 {
+	decimals, ok := displayDecimals[unit]
+	if !ok {
+		return Round(v)
+	}
+	p := math.Pow10(decimals)
+	return math.Round(v*p) / p
+}
+
+// ToCanonical converts the tagged fields of v, a pointer to a struct, from
+// the units of system to the stored metric units
+func ToCanonical(v any, system string) error {
+	if !ValidSystem(system) {
+		return ErrUnknownSystem
+	}
+	if system != Metric {
+		convertFields(reflect.ValueOf(v), system, true)
+	}
+	return nil
+}
+
+// FromCanonical converts the tagged fields of v, a pointer to a struct or a
+// slice of them, from the stored metric units to the units of system.
+// Values are rounded to the display precision of their unit.
+func FromCanonical(v any, system string) {
+	if system == Imperial {
+		convertFields(reflect.ValueOf(v), system, false)
+	}
+}
+
+// convertFields walks v converting every float64 or *float64 field with a
+// `unit` tag, and descends into untagged structs, pointers and slices
+func convertFields(v reflect.Value, system string, toCanonical bool) {
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if !v.IsNil() {
+			convertFields(v.Elem(), system, toCanonical)
+		}
+	case reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			convertFields(v.Index(i), system, toCanonical)
+		}
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			quantity := field.Tag.Get("unit")
+			if quantity == "" {
+				convertFields(v.Field(i), system, toCanonical)
+				continue
+			}
+
+			value := v.Field(i)
+			if value.Kind() == reflect.Pointer {
+				if value.IsNil() {
+					continue
+				}
+				value = value.Elem()
+			}
+			if value.Kind() != reflect.Float64 || !value.CanSet() {
+				panic(fmt.Sprintf("units: field %s.%s must be a settable float64", t.Name(), field.Name))
+			}
+
+			from, to := UnitOf(quantity, system), UnitOf(quantity, Metric)
+			if !toCanonical {
+				from, to = to, from
+			}
+			converted, err := Convert(value.Float(), from, to)
+			if err != nil {
+				panic(err) // the units of a quantity always share a dimension
+			}
+			if !toCanonical {
+				converted = RoundIn(converted, to)
+			}
+			value.SetFloat(converted)
+		}
+	}
+}
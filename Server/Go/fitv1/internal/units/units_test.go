@@ -0,0 +1,50 @@
+package units
+
+import (
+	"math"
+	"testing"
+)
+
+type sample struct {
+	Weight   float64  `unit:"body_weight"`
+	Waist    *float64 `unit:"length"`
+	Distance float64  `unit:"distance"`
+	Load     float64  `unit:"load"`
+}
+
+func TestFromCanonicalPrecision(t *testing.T) {
+	waist := 81.3
+	s := sample{Weight: 80, Waist: &waist, Distance: 400, Load: 102.5}
+	FromCanonical(&s, Imperial)
+
+	tests := []struct {
+		name      string
+		got, want float64
+	}{
+		{"pounds to two decimals", s.Weight, 176.37},
+		{"inches to three", *s.Waist, 32.008},
+		{"miles to five", s.Distance, 0.24855},
+		{"load to two", s.Load, 225.97},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s: got %v, want %v", tt.name, tt.got, tt.want)
+		}
+	}
+
+	// Shown values convert back to what was stored, to its own precision
+	if err := ToCanonical(&s, Imperial); err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(s.Distance-400) > 0.05 || math.Abs(*s.Waist-81.3) > 0.005 || math.Abs(s.Weight-80) > 0.005 {
+		t.Errorf("round trip gave %v m, %v cm and %v kg", s.Distance, *s.Waist, s.Weight)
+	}
+}
+
+func TestFromCanonicalMetric(t *testing.T) {
+	s := sample{Weight: 80.123456, Distance: 400.5}
+	FromCanonical(&s, Metric)
+	if s.Weight != 80.123456 || s.Distance != 400.5 {
+		t.Errorf("metric values changed to %+v", s)
+	}
+}